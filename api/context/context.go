@@ -225,6 +225,25 @@ func (ctx *Context) GetOutputs() map[string]any {
 	return outputs
 }
 
+// Clone returns a copy of the context with its own Environment, Outputs and template
+// cache maps, so that it can be handed to a check that runs concurrently with other checks.
+func (ctx *Context) Clone() *Context {
+	environment := make(map[string]interface{}, len(ctx.Environment))
+	maps.Copy(environment, ctx.Environment)
+	outputs := make(map[string]*pkg.CheckResult, len(ctx.Outputs))
+	maps.Copy(outputs, ctx.Outputs)
+
+	return &Context{
+		Context:      ctx.Context,
+		Namespace:    ctx.Namespace,
+		Canary:       ctx.Canary,
+		Environment:  environment,
+		cache:        maps.Clone(ctx.cache),
+		Outputs:      outputs,
+		HARCollector: ctx.HARCollector,
+	}
+}
+
 func (ctx *Context) IsDebug() bool {
 	return ctx.Context.IsDebug() || ctx.Canary.IsDebug()
 }
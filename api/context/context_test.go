@@ -1,6 +1,7 @@
 package context
 
 import (
+	"sync"
 	"testing"
 
 	v1 "github.com/flanksource/canary-checker/api/v1"
@@ -107,3 +108,28 @@ func TestWithCheckResultDataTakesPrecedence(t *testing.T) {
 		}
 	}
 }
+
+func TestCloneTemplateCacheIsNotShared(t *testing.T) {
+	ctx := New(dutyCtx.New(), v1.Canary{})
+	ctx.cache = map[string]any{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		check := ctx.Clone()
+		check.Environment["check"] = map[string]any{"id": ""}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lastResult := check.GetContextualFunctions()["last_result"].(func() any)
+			for j := 0; j < 100; j++ {
+				lastResult()
+				delete(check.cache, "last_result")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ctx.cache) != 0 {
+		t.Errorf("parent cache = %v, want it untouched by the cloned checks", ctx.cache)
+	}
+}
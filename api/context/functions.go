@@ -34,6 +34,7 @@ func (ctx *Context) GetContextualFunctions() map[string]any {
 			}
 
 			if checkID == "" {
+				ctx.cache["last_result"] = status
 				return status
 			}
 
@@ -49,15 +49,35 @@ type CanarySpec struct {
 	// Retries configures generic retry behavior for all checks in this canary.
 	Retries *CheckRetries `yaml:"retries,omitempty" json:"retries,omitempty"`
 
+	// Concurrency is the maximum number of checks in this canary that are run in parallel.
+	// Checks using dependsOn only start once all of their dependencies have completed.
+	// Defaults to the check.concurrency property, or 1 (sequential) if that is not set.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// Proxy routes the outbound connections of the checks in this canary through an HTTP(S) or
+	// SOCKS5 proxy. Checks can override it, and it defaults to the proxy.url property. SQL checks
+	// cannot connect through a proxy and are invalid unless their host is in noProxy.
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+
 	Env                map[string]VarSource      `yaml:"env,omitempty" json:"env,omitempty"`
 	HTTP               []HTTPCheck               `yaml:"http,omitempty" json:"http,omitempty"`
+	HAR                []HARCheck                `yaml:"har,omitempty" json:"har,omitempty"`
 	DNS                []DNSCheck                `yaml:"dns,omitempty" json:"dns,omitempty"`
 	DockerPull         []DockerPullCheck         `yaml:"docker,omitempty" json:"docker,omitempty"`
 	DockerPush         []DockerPushCheck         `yaml:"dockerPush,omitempty" json:"dockerPush,omitempty"`
 	ContainerdPull     []ContainerdPullCheck     `yaml:"containerd,omitempty" json:"containerd,omitempty"`
 	ContainerdPush     []ContainerdPushCheck     `yaml:"containerdPush,omitempty" json:"containerdPush,omitempty"`
+	Registry           []RegistryCheck           `yaml:"registry,omitempty" json:"registry,omitempty"`
 	S3                 []S3Check                 `yaml:"s3,omitempty" json:"s3,omitempty"`
 	TCP                []TCPCheck                `yaml:"tcp,omitempty" json:"tcp,omitempty"`
+	GRPC               []GRPCCheck               `yaml:"grpc,omitempty" json:"grpc,omitempty"`
+	TLS                []TLSCheck                `yaml:"tls,omitempty" json:"tls,omitempty"`
+	SSH                []SSHCheck                `yaml:"ssh,omitempty" json:"ssh,omitempty"`
+	SMTP               []SMTPCheck               `yaml:"smtp,omitempty" json:"smtp,omitempty"`
+	Kafka              []KafkaCheck              `yaml:"kafka,omitempty" json:"kafka,omitempty"`
+	MQTT               []MQTTCheck               `yaml:"mqtt,omitempty" json:"mqtt,omitempty"`
+	Websocket          []WebsocketCheck          `yaml:"websocket,omitempty" json:"websocket,omitempty"`
+	NTP                []NTPCheck                `yaml:"ntp,omitempty" json:"ntp,omitempty"`
 	Pod                []PodCheck                `yaml:"pod,omitempty" json:"pod,omitempty"`
 	LDAP               []LDAPCheck               `yaml:"ldap,omitempty" json:"ldap,omitempty"`
 	ICMP               []ICMPCheck               `yaml:"icmp,omitempty" json:"icmp,omitempty"`
@@ -76,6 +96,7 @@ type CanarySpec struct {
 	PubSub             []PubSubCheck             `yaml:"pubsub,omitempty" json:"pubsub,omitempty"`
 	GitHub             []GitHubCheck             `yaml:"github,omitempty" json:"github,omitempty"`
 	GitProtocol        []GitProtocolCheck        `yaml:"gitProtocol,omitempty" json:"gitProtocol,omitempty"`
+	Git                []GitCheck                `yaml:"git,omitempty" json:"git,omitempty"`
 	Kubernetes         []KubernetesCheck         `yaml:"kubernetes,omitempty" json:"kubernetes,omitempty"`
 	KubernetesResource []KubernetesResourceCheck `yaml:"kubernetesResource,omitempty" json:"kubernetesResource,omitempty"`
 	Folder             []FolderCheck             `yaml:"folder,omitempty" json:"folder,omitempty"`
@@ -112,6 +133,9 @@ func (spec CanarySpec) GetAllChecks() []external.Check {
 	for _, check := range spec.HTTP {
 		checks = append(checks, check)
 	}
+	for _, check := range spec.HAR {
+		checks = append(checks, check)
+	}
 	for _, check := range spec.DNS {
 		checks = append(checks, check)
 	}
@@ -127,12 +151,39 @@ func (spec CanarySpec) GetAllChecks() []external.Check {
 	for _, check := range spec.ContainerdPush {
 		checks = append(checks, check)
 	}
+	for _, check := range spec.Registry {
+		checks = append(checks, check)
+	}
 	for _, check := range spec.S3 {
 		checks = append(checks, check)
 	}
 	for _, check := range spec.TCP {
 		checks = append(checks, check)
 	}
+	for _, check := range spec.GRPC {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.TLS {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.SSH {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.SMTP {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.Kafka {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.MQTT {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.Websocket {
+		checks = append(checks, check)
+	}
+	for _, check := range spec.NTP {
+		checks = append(checks, check)
+	}
 	for _, check := range spec.Pod {
 		checks = append(checks, check)
 	}
@@ -187,6 +238,9 @@ func (spec CanarySpec) GetAllChecks() []external.Check {
 	for _, check := range spec.GitProtocol {
 		checks = append(checks, check)
 	}
+	for _, check := range spec.Git {
+		checks = append(checks, check)
+	}
 	for _, check := range spec.Kubernetes {
 		checks = append(checks, check)
 	}
@@ -235,6 +289,9 @@ func (spec CanarySpec) KeepOnly(names ...string) CanarySpec {
 	spec.HTTP = lo.Filter(spec.HTTP, func(c HTTPCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
+	spec.HAR = lo.Filter(spec.HAR, func(c HARCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
 	spec.DNS = lo.Filter(spec.DNS, func(c DNSCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
@@ -250,12 +307,39 @@ func (spec CanarySpec) KeepOnly(names ...string) CanarySpec {
 	spec.ContainerdPush = lo.Filter(spec.ContainerdPush, func(c ContainerdPushCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
+	spec.Registry = lo.Filter(spec.Registry, func(c RegistryCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
 	spec.S3 = lo.Filter(spec.S3, func(c S3Check, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
 	spec.TCP = lo.Filter(spec.TCP, func(c TCPCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
+	spec.GRPC = lo.Filter(spec.GRPC, func(c GRPCCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.TLS = lo.Filter(spec.TLS, func(c TLSCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.SSH = lo.Filter(spec.SSH, func(c SSHCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.SMTP = lo.Filter(spec.SMTP, func(c SMTPCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.Kafka = lo.Filter(spec.Kafka, func(c KafkaCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.MQTT = lo.Filter(spec.MQTT, func(c MQTTCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.Websocket = lo.Filter(spec.Websocket, func(c WebsocketCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
+	spec.NTP = lo.Filter(spec.NTP, func(c NTPCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
 	spec.Pod = lo.Filter(spec.Pod, func(c PodCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
@@ -310,6 +394,9 @@ func (spec CanarySpec) KeepOnly(names ...string) CanarySpec {
 	spec.GitProtocol = lo.Filter(spec.GitProtocol, func(c GitProtocolCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
+	spec.Git = lo.Filter(spec.Git, func(c GitCheck, _ int) bool {
+		return lo.Contains(names, c.GetName())
+	})
 	spec.Kubernetes = lo.Filter(spec.Kubernetes, func(c KubernetesCheck, _ int) bool {
 		return lo.Contains(names, c.GetName())
 	})
@@ -85,6 +85,20 @@ type Oauth2Config struct {
 	Params   map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
 }
 
+// Proxy routes the outbound connections of a check through an HTTP(S) or SOCKS5 proxy
+type Proxy struct {
+	// URL of the proxy, e.g. http://proxy.corp:3128 or socks5://proxy.corp:1080
+	URL string `json:"url" yaml:"url"`
+	// Username to authenticate to the proxy with
+	Username types.EnvVar `json:"username,omitempty" yaml:"username,omitempty"`
+	// Password to authenticate to the proxy with
+	Password types.EnvVar `json:"password,omitempty" yaml:"password,omitempty"`
+	// NoProxy lists the hosts connected to directly, in the same format as the NO_PROXY
+	// environment variable: hostnames, domains (.example.com), ip addresses and CIDRs, with an optional port.
+	// Connections to localhost and loopback addresses are never proxied.
+	NoProxy []string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+}
+
 type TLSConfig struct {
 	// InsecureSkipVerify controls whether a client verifies the server's
 	// certificate chain and host name
@@ -147,6 +161,90 @@ func (t TLSConfig) ToTLSConfig(ctx checkContext, namespace string) (*tls.Config,
 	return cfg, nil
 }
 
+// HTTPContract is the contract an HTTP response is validated against
+type HTTPContract struct {
+	// OpenAPI v3 document in json or yaml, either inline, from a configmap/secret or an http(s) url to load it from.
+	// Urls are fetched with the proxy and tls config of the check, and documents are cached for an hour
+	OpenAPI *types.EnvVar `yaml:"openapi,omitempty" json:"openapi,omitempty"`
+	// OperationID of the operation in the OpenAPI document to validate the response against,
+	// if empty the operation is looked up using the method and path of the check, ignoring the servers of the document
+	OperationID string `yaml:"operationId,omitempty" json:"operationId,omitempty"`
+	// Schema is a JSON Schema in json or yaml the response body is validated against
+	Schema *types.EnvVar `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+// HTTPStep is a single request of a multi-step HTTP check. The url, body and header values are
+// go templates with access to the extracted variables as .vars and the responses of the
+// previous steps as .steps.<name>, e.g. {{ .vars.token }} or {{ .steps.login.code }}
+type HTTPStep struct {
+	// Name of the step, used to reference its response in later steps
+	Name string `yaml:"name" json:"name"`
+	// URL of the request, relative urls are resolved against the url of the check
+	URL string `yaml:"url" json:"url"`
+	// Method to use - defaults to GET
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+	// Header fields added to the headers of the check
+	Headers []types.EnvVar `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Request Body Contents
+	Body string `yaml:"body,omitempty" json:"body,omitempty"`
+	// Expected response codes, defaults to 200..299
+	ResponseCodes []int `yaml:"responseCodes,omitempty" json:"responseCodes,omitempty"`
+	// Extract values from the response into variables for the following steps
+	Extract []HTTPStepExtract `yaml:"extract,omitempty" json:"extract,omitempty"`
+	// Test is a CEL expression evaluated against the response (code, headers, content, json) that must return true
+	Test string `yaml:"test,omitempty" json:"test,omitempty"`
+}
+
+func (s HTTPStep) GetMethod() string {
+	if s.Method != "" {
+		return s.Method
+	}
+	return "GET"
+}
+
+// HTTPStepExtract extracts a variable from the response of a step using one of jsonPath, expr or regex
+type HTTPStepExtract struct {
+	// Name of the variable
+	Name string `yaml:"name" json:"name"`
+	// JSONPath into the json response e.g. $.data.token
+	JSONPath string `yaml:"jsonPath,omitempty" json:"jsonPath,omitempty"`
+	// Expr is a CEL expression evaluated against the response (code, headers, content, json)
+	Expr string `yaml:"expr,omitempty" json:"expr,omitempty"`
+	// Regex matched against the response body, the first capture group is used if there is one
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+}
+
+// HTTPPhaseThresholds are the maximum durations in milliseconds of the phases of an HTTP request
+type HTTPPhaseThresholds struct {
+	// DNS lookup of the host
+	DNS int `yaml:"dns,omitempty" json:"dns,omitempty"`
+	// Connect is the time to establish the TCP connection
+	Connect int `yaml:"connect,omitempty" json:"connect,omitempty"`
+	// TLS handshake
+	TLS int `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// FirstByte is the time from sending the request to receiving the first byte of the response
+	FirstByte int `yaml:"firstByte,omitempty" json:"firstByte,omitempty"`
+	// Transfer is the time to read the response body
+	Transfer int `yaml:"transfer,omitempty" json:"transfer,omitempty"`
+}
+
+// GetThresholds returns the configured thresholds keyed by phase
+func (t HTTPPhaseThresholds) GetThresholds() map[string]int {
+	thresholds := map[string]int{
+		"dns":       t.DNS,
+		"connect":   t.Connect,
+		"tls":       t.TLS,
+		"firstByte": t.FirstByte,
+		"transfer":  t.Transfer,
+	}
+	for phase, threshold := range thresholds {
+		if threshold <= 0 {
+			delete(thresholds, phase)
+		}
+	}
+	return thresholds
+}
+
 type Crawl struct {
 	// Filters is a list of regex filters to apply to the crawled links.
 	Filters []string `yaml:"filters,omitempty" json:"filters,omitempty"`
@@ -173,6 +271,8 @@ type HTTPCheck struct {
 	Endpoint string `yaml:"endpoint" json:"endpoint,omitempty" template:"true"`
 	// Maximum duration in milliseconds for the HTTP request. It will fail the check if it takes longer.
 	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Maximum duration in milliseconds for each phase of the HTTP request. It will fail the check if a phase takes longer.
+	PhaseThresholdMillis *HTTPPhaseThresholds `yaml:"phaseThresholdMillis,omitempty" json:"phaseThresholdMillis,omitempty"`
 	// Expected response codes for the HTTP Request.
 	ResponseCodes []int `yaml:"responseCodes,omitempty" json:"responseCodes,omitempty"`
 	// Maximum redirects to follow (Defaults 10).
@@ -208,6 +308,19 @@ type HTTPCheck struct {
 	TLSConfig *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
 	// Crawl site and verify links
 	Crawl *Crawl `yaml:"crawl,omitempty" json:"crawl,omitempty"`
+	// Steps run a sequence of requests sharing a cookie jar, with values extracted from
+	// earlier responses available to later steps. The check fails on the first failing step.
+	Steps []HTTPStep `yaml:"steps,omitempty" json:"steps,omitempty"`
+	// Contract validates the status code, headers and body of the response against an OpenAPI document or a JSON Schema
+	Contract *HTTPContract `yaml:"contract,omitempty" json:"contract,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Resolve pins hostnames to ip addresses, keeping the hostname for the Host header and SNI.
+	// A host pinned to several addresses reports one result per address
+	Resolve map[string][]string `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+	// IPFamily restricts the connection to an address of the family, one of ipv4, ipv6 or both.
+	// both connects over each family and reports one result per family
+	IPFamily string `yaml:"ipFamily,omitempty" json:"ipFamily,omitempty"`
 }
 
 func (c HTTPCheck) GetType() string {
@@ -227,6 +340,40 @@ type TCPCheck struct {
 	Relatable       `yaml:",inline" json:",inline"`
 	Endpoint        string `yaml:"endpoint" json:"endpoint,omitempty"`
 	ThresholdMillis int64  `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Resolve pins the host of the endpoint to ip addresses, reporting one result per address
+	Resolve map[string][]string `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+	// IPFamily restricts the connection to an address of the family, one of ipv4, ipv6 or both.
+	// both connects over each family and reports one result per family
+	IPFamily string `yaml:"ipFamily,omitempty" json:"ipFamily,omitempty"`
+	// Protocol is tcp or udp, defaults to tcp. udp checks require messages as there is no connection to establish
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	// TLSConfig wraps the tcp connection in TLS, the connection is in plaintext if not set.
+	// Any non-empty field (enable, insecureSkipVerify, ca, cert) implies TLS is enabled.
+	TLSConfig *SwitchableTLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Messages are sent and their responses awaited in order, the check only connects without them
+	Messages []TCPMessage `yaml:"messages,omitempty" json:"messages,omitempty"`
+	// ResponseTimeout for each expected response, defaults to 10s
+	ResponseTimeout Duration `yaml:"responseTimeout,omitempty" json:"responseTimeout,omitempty"`
+}
+
+type TCPMessage struct {
+	// Text is sent as is, use double quotes in YAML for escape sequences such as "PING\r\n"
+	Text string `yaml:"text,omitempty" json:"text,omitempty" template:"true"`
+	// Hex is sent after it is decoded, whitespace is ignored e.g. "0a 0b"
+	Hex string `yaml:"hex,omitempty" json:"hex,omitempty"`
+	// Expect waits for a response after sending the message. Over tcp the response is everything
+	// received until it matches, over udp every datagram is matched and those that do not match are skipped
+	Expect *TCPExpect `yaml:"expect,omitempty" json:"expect,omitempty"`
+}
+
+type TCPExpect struct {
+	// Regex the response must match
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// Expr is a CEL expression that must return true, with the response as response, its hex
+	// encoding as hex and, when it is JSON, its parsed value as json
+	Expr string `yaml:"expr,omitempty" json:"expr,omitempty"`
 }
 
 func (t TCPCheck) GetEndpoint() string {
@@ -237,13 +384,424 @@ func (t TCPCheck) GetType() string {
 	return "tcp"
 }
 
+type GRPCCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Endpoint of the gRPC server in the form of host:port
+	Endpoint string `yaml:"endpoint" json:"endpoint" template:"true"`
+	// Service to check the health of, defaults to the overall health of the server
+	Service string `yaml:"service,omitempty" json:"service,omitempty" template:"true"`
+	// Headers are sent as metadata with every call
+	Headers []types.EnvVar `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Reflection lists the services exposed by the server using server reflection
+	Reflection bool `yaml:"reflection,omitempty" json:"reflection,omitempty"`
+	// TLSConfig configures TLS for the connection, the connection is in plaintext if not set.
+	// Any non-empty field (enable, insecureSkipVerify, ca, cert) implies TLS is enabled.
+	TLSConfig *SwitchableTLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Maximum duration in milliseconds for the health check. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Resolve pins the host of the endpoint to ip addresses, keeping the hostname for SNI.
+	// A host pinned to several addresses reports one result per address
+	Resolve map[string][]string `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+}
+
+func (c GRPCCheck) GetEndpoint() string {
+	return c.Endpoint
+}
+
+func (c GRPCCheck) GetType() string {
+	return "grpc"
+}
+
+type TLSCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Endpoint to connect to in the form of host:port
+	Endpoint string `yaml:"endpoint" json:"endpoint" template:"true"`
+	// ServerName used for SNI and hostname verification, defaults to the host of the endpoint
+	ServerName string `yaml:"serverName,omitempty" json:"serverName,omitempty" template:"true"`
+	// StartTLS upgrades a plaintext connection before the handshake, one of smtp, imap, postgres or ldap
+	// +kubebuilder:validation:Enum=smtp;imap;postgres;ldap
+	StartTLS string `yaml:"startTLS,omitempty" json:"startTLS,omitempty"`
+	// ExpiryDays fails the check if any certificate in the chain expires in less than this number of days
+	ExpiryDays int `yaml:"expiryDays,omitempty" json:"expiryDays,omitempty"`
+	// MinVersion fails the check if a lower TLS version is negotiated, one of 1.0, 1.1, 1.2 or 1.3
+	// +kubebuilder:validation:Enum="1.0";"1.1";"1.2";"1.3"
+	MinVersion string `yaml:"minVersion,omitempty" json:"minVersion,omitempty"`
+	// TLSConfig provides the CA bundle the chain is verified against (defaults to the system roots),
+	// an optional client certificate, and insecureSkipVerify to disable chain and hostname verification
+	TLSConfig TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Maximum duration in milliseconds for the handshake. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Resolve pins the host of the endpoint to ip addresses, keeping the hostname for SNI.
+	// A host pinned to several addresses reports one result per address
+	Resolve map[string][]string `yaml:"resolve,omitempty" json:"resolve,omitempty"`
+}
+
+func (c TLSCheck) GetEndpoint() string {
+	return c.Endpoint
+}
+
+func (c TLSCheck) GetType() string {
+	return "tls"
+}
+
+type HARCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// File is the path to a .har recording
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// HAR is the recording itself, either inline or from a configmap/secret
+	HAR *types.EnvVar `yaml:"har,omitempty" json:"har,omitempty"`
+	// Hosts replaces the recorded hosts, e.g. www.example.com: staging.example.com or
+	// www.example.com: http://localhost:8080. The replacements are go templates.
+	Hosts map[string]string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	// Headers replace or add to the recorded request headers, e.g. to provide credentials
+	// that are redacted in the recording. Header values are go templates.
+	Headers []types.EnvVar `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Exclude requests with urls matching any of these regular expressions, e.g. static assets
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	// StatusMatch is how response codes are compared with the recording, exact (default)
+	// or class, where e.g. a recorded 201 matches any 2xx
+	// +kubebuilder:validation:Enum=exact;class
+	StatusMatch string `yaml:"statusMatch,omitempty" json:"statusMatch,omitempty"`
+	// BodyMatch is how response bodies are compared with the recording, none (default), exact,
+	// json where the keys and value types must match but values may differ, or size where the
+	// body size may differ by at most sizeTolerance percent
+	// +kubebuilder:validation:Enum=none;exact;json;size
+	BodyMatch string `yaml:"bodyMatch,omitempty" json:"bodyMatch,omitempty"`
+	// SizeTolerance is the percentage the body size may differ by when bodyMatch is size, defaults to 10
+	SizeTolerance int `yaml:"sizeTolerance,omitempty" json:"sizeTolerance,omitempty"`
+	// Maximum duration in milliseconds for the replay. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+func (c HARCheck) GetEndpoint() string {
+	if c.File != "" {
+		return c.File
+	}
+	return c.Description.Description
+}
+
+func (c HARCheck) GetType() string {
+	return "har"
+}
+
+type RegistryCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	// Connection provides the registry credentials, either from a connection or an inline username and password.
+	// The url overrides the registry of the image, e.g. http://localhost:5000 for a registry without TLS.
+	Connection `yaml:",inline" json:",inline"`
+	Relatable  `yaml:",inline" json:",inline"`
+	// Image to pull, e.g. ghcr.io/flanksource/canary-checker:latest or alpine@sha256:...
+	Image string `yaml:"image" json:"image" template:"true"`
+	// DockerConfig is a .dockerconfigjson, e.g. from a kubernetes.io/dockerconfigjson secret,
+	// the credentials of the registry of the image are used when no connection credentials are given
+	DockerConfig *types.EnvVar `yaml:"dockerConfig,omitempty" json:"dockerConfig,omitempty"`
+	// Platform to pull from a multi-platform image, defaults to linux/amd64
+	Platform string `yaml:"platform,omitempty" json:"platform,omitempty"`
+	// ExpectedDigest fails the check if the image resolves to a different digest
+	ExpectedDigest string `yaml:"expectedDigest,omitempty" json:"expectedDigest,omitempty"`
+	// SkipLayers only pulls the manifest and config of the image
+	SkipLayers bool `yaml:"skipLayers,omitempty" json:"skipLayers,omitempty"`
+	// Push a small generated image after pulling, and delete it afterwards
+	Push *RegistryPush `yaml:"push,omitempty" json:"push,omitempty"`
+	// TLSConfig provides the CA bundle and client certificate for the registry
+	TLSConfig *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Maximum duration in milliseconds for all the phases. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+// RegistryPush is the image pushed by a registry check
+type RegistryPush struct {
+	// Image to push to, defaults to the repository of the pulled image with a canary-checker tag
+	Image string `yaml:"image,omitempty" json:"image,omitempty" template:"true"`
+	// SkipDelete leaves the pushed image in the registry, e.g. for registries that do not support deletes
+	SkipDelete bool `yaml:"skipDelete,omitempty" json:"skipDelete,omitempty"`
+}
+
+func (c RegistryCheck) GetEndpoint() string {
+	return c.Image
+}
+
+func (c RegistryCheck) GetType() string {
+	return "registry"
+}
+
+type SSHCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Connection provides the host and credentials, the url is either host[:port] or ssh://[user@]host[:port].
+	// The password is the passphrase of the private key if the key is encrypted.
+	Connection `yaml:",inline" json:",inline"`
+	// PrivateKey in PEM or OpenSSH format, defaults to the certificate of the connection
+	PrivateKey types.EnvVar `yaml:"privateKey,omitempty" json:"privateKey,omitempty"`
+	// HostKey pins the host key, either its fingerprint e.g. SHA256:... or MD5:..., or the public key
+	// in the authorized_keys format
+	HostKey string `yaml:"hostKey,omitempty" json:"hostKey,omitempty"`
+	// KnownHosts in the known_hosts format to verify the host key with
+	KnownHosts *types.EnvVar `yaml:"knownHosts,omitempty" json:"knownHosts,omitempty"`
+	// InsecureIgnoreHostKey skips the verification of the host key, one of hostKey, knownHosts or
+	// insecureIgnoreHostKey is required
+	InsecureIgnoreHostKey bool `yaml:"insecureIgnoreHostKey,omitempty" json:"insecureIgnoreHostKey,omitempty"`
+	// Command to run after connecting, its stdout, stderr and exit code are available to the test
+	// as results.stdout, results.stderr and results.exitCode
+	Command string `yaml:"command,omitempty" json:"command,omitempty" template:"true"`
+	// SessionTimeout for connecting and running the command, defaults to 1m
+	SessionTimeout Duration `yaml:"sessionTimeout,omitempty" json:"sessionTimeout,omitempty"`
+	// Maximum duration in milliseconds for connecting and running the command. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+func (c SSHCheck) GetEndpoint() string {
+	return c.URL
+}
+
+func (c SSHCheck) GetType() string {
+	return "ssh"
+}
+
+func (c SSHCheck) GetTestFunction() Template {
+	if c.Test.Expression == "" && c.Command != "" {
+		c.Test.Expression = "results.exitCode == 0"
+	}
+	return c.Test
+}
+
+type SMTPCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Connection provides the server and the credentials to authenticate with, the url is either
+	// smtp://host[:port] which upgrades with STARTTLS, or smtps://host[:port] for implicit TLS
+	Connection `yaml:",inline" json:",inline"`
+	// Hello is the domain sent with EHLO, defaults to localhost
+	Hello string `yaml:"hello,omitempty" json:"hello,omitempty"`
+	// SkipStartTLS allows an smtp:// server that does not support STARTTLS, credentials are never sent in plaintext
+	SkipStartTLS bool       `yaml:"skipStartTLS,omitempty" json:"skipStartTLS,omitempty"`
+	TLSConfig    *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Mail is the test message to send, the check only connects and authenticates without it
+	Mail *SMTPMail `yaml:"mail,omitempty" json:"mail,omitempty"`
+	// RoundTrip waits for the test message to arrive in an IMAP mailbox and deletes it
+	RoundTrip *SMTPRoundTrip `yaml:"roundTrip,omitempty" json:"roundTrip,omitempty"`
+	// SessionTimeout for the SMTP session, defaults to 1m
+	SessionTimeout Duration `yaml:"sessionTimeout,omitempty" json:"sessionTimeout,omitempty"`
+	// Maximum duration in milliseconds for sending the message and its delivery. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+type SMTPMail struct {
+	From string   `yaml:"from" json:"from"`
+	To   []string `yaml:"to" json:"to"`
+	// Subject defaults to the name of the check
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty" template:"true"`
+	Body    string `yaml:"body,omitempty" json:"body,omitempty" template:"true"`
+}
+
+type SMTPRoundTrip struct {
+	// Connection to the IMAP server, the url is either imap://host[:port] which upgrades with
+	// STARTTLS, or imaps://host[:port] for implicit TLS
+	Connection `yaml:",inline" json:",inline"`
+	TLSConfig  *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Mailbox to search for the message, defaults to INBOX
+	Mailbox string `yaml:"mailbox,omitempty" json:"mailbox,omitempty"`
+	// Timeout for the message to arrive, defaults to 5m
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Interval between searches of the mailbox, defaults to 5s
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+func (c SMTPCheck) GetEndpoint() string {
+	return c.URL
+}
+
+func (c SMTPCheck) GetType() string {
+	return "smtp"
+}
+
+type KafkaCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Connection provides the SASL credentials, and the brokers as a comma separated url e.g.
+	// kafka-0:9092,kafka-1:9092 when brokers is empty
+	Connection `yaml:",inline" json:",inline"`
+	// Brokers to bootstrap the connection with
+	Brokers []string `yaml:"brokers,omitempty" json:"brokers,omitempty" template:"true"`
+	// SASLMechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, defaults to PLAIN when there is a username
+	SASLMechanism string               `yaml:"saslMechanism,omitempty" json:"saslMechanism,omitempty"`
+	TLSConfig     *SwitchableTLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Topics to check the partitions of, defaults to all topics except internal ones
+	Topics []string `yaml:"topics,omitempty" json:"topics,omitempty"`
+	// ConsumerGroups to compute the lag of, for every partition they have committed an offset to
+	ConsumerGroups []string `yaml:"consumerGroups,omitempty" json:"consumerGroups,omitempty"`
+	// MaxTotalLag fails the check when the lag of a consumer group across all its partitions exceeds it
+	MaxTotalLag int64 `yaml:"maxTotalLag,omitempty" json:"maxTotalLag,omitempty"`
+	// MaxPartitionLag fails the check when the lag of a consumer group on any partition exceeds it
+	MaxPartitionLag int64 `yaml:"maxPartitionLag,omitempty" json:"maxPartitionLag,omitempty"`
+	// RequestTimeout for connecting and each request, defaults to 30s
+	RequestTimeout Duration `yaml:"requestTimeout,omitempty" json:"requestTimeout,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+func (c KafkaCheck) GetEndpoint() string {
+	if len(c.Brokers) > 0 {
+		return strings.Join(c.Brokers, ",")
+	}
+	return c.URL
+}
+
+func (c KafkaCheck) GetType() string {
+	return "kafka"
+}
+
+type MQTTCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Connection provides the broker and the credentials, the url is one of tcp://host[:port],
+	// ssl://host[:port], ws://host[:port]/path or wss://host[:port]/path
+	Connection `yaml:",inline" json:",inline"`
+	TLSConfig  *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// ClientID defaults to canary-checker- followed by a random suffix
+	ClientID string `yaml:"clientId,omitempty" json:"clientId,omitempty"`
+	// Topic the probe message is published to and received from
+	Topic string `yaml:"topic" json:"topic" template:"true"`
+	// QoS to subscribe and publish with, one of 0, 1 or 2, defaults to 1
+	QoS *int `yaml:"qos,omitempty" json:"qos,omitempty"`
+	// ProbeTimeout for connecting and receiving the probe message, defaults to 30s
+	ProbeTimeout Duration `yaml:"probeTimeout,omitempty" json:"probeTimeout,omitempty"`
+	// Maximum duration in milliseconds for the probe message to be received. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+func (c MQTTCheck) GetEndpoint() string {
+	return c.URL
+}
+
+func (c MQTTCheck) GetType() string {
+	return "mqtt"
+}
+
+type WebsocketCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Connection provides the ws:// or wss:// url, and the username and password for basic authentication
+	Connection `yaml:",inline" json:",inline"`
+	// Headers are sent with the upgrade request
+	Headers []types.EnvVar `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Subprotocols to offer in the upgrade request
+	Subprotocols []string   `yaml:"subprotocols,omitempty" json:"subprotocols,omitempty"`
+	TLSConfig    *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Messages are sent and their responses awaited in order, the check only performs the handshake without them
+	Messages []WebsocketMessage `yaml:"messages,omitempty" json:"messages,omitempty"`
+	// ResponseTimeout for the handshake and for each expected response, defaults to 30s
+	ResponseTimeout Duration `yaml:"responseTimeout,omitempty" json:"responseTimeout,omitempty"`
+	// Maximum duration in milliseconds for the handshake and all messages. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+type WebsocketMessage struct {
+	// Text is sent as a text frame
+	Text string `yaml:"text,omitempty" json:"text,omitempty" template:"true"`
+	// JSON is sent as a text frame after it is validated and compacted
+	JSON string `yaml:"json,omitempty" json:"json,omitempty" template:"true"`
+	// Expect waits for a response after sending the message, responses that do not match are skipped
+	Expect *WebsocketExpect `yaml:"expect,omitempty" json:"expect,omitempty"`
+}
+
+type WebsocketExpect struct {
+	// Regex the response must match
+	Regex string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// Expr is a CEL expression that must return true, with the response as message and, when it is
+	// JSON, its parsed value as json
+	Expr string `yaml:"expr,omitempty" json:"expr,omitempty"`
+}
+
+func (c WebsocketCheck) GetEndpoint() string {
+	return c.URL
+}
+
+func (c WebsocketCheck) GetType() string {
+	return "websocket"
+}
+
+type NTPCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Servers to query, as host or host:port
+	Servers []string `yaml:"servers" json:"servers"`
+	// MaxOffset fails the check when the local clock is further than this from any server
+	MaxOffset Duration `yaml:"maxOffset,omitempty" json:"maxOffset,omitempty"`
+	// MaxDisagreement fails the check when the offsets of the servers differ by more than this
+	MaxDisagreement Duration `yaml:"maxDisagreement,omitempty" json:"maxDisagreement,omitempty"`
+	// QueryTimeout for each query, defaults to 5s
+	QueryTimeout Duration `yaml:"queryTimeout,omitempty" json:"queryTimeout,omitempty"`
+}
+
+func (c NTPCheck) GetEndpoint() string {
+	return strings.Join(c.Servers, ",")
+}
+
+func (c NTPCheck) GetType() string {
+	return "ntp"
+}
+
 type ICMPCheck struct {
 	Description         `yaml:",inline" json:",inline"`
 	Relatable           `yaml:",inline" json:",inline"`
 	Endpoint            string `yaml:"endpoint" json:"endpoint,omitempty"`
 	ThresholdMillis     int64  `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
 	PacketLossThreshold int64  `yaml:"packetLossThreshold,omitempty" json:"packetLossThreshold,omitempty"`
-	PacketCount         int    `yaml:"packetCount,omitempty" json:"packetCount,omitempty"`
+	// PacketCount is the number of pings, or of probes to every hop in path mode, defaults to 5
+	PacketCount int `yaml:"packetCount,omitempty" json:"packetCount,omitempty"`
+	// Path traces the route to the endpoint instead of pinging it, recording the loss and
+	// round trip times of every hop. It requires CAP_NET_RAW to receive the replies of the hops
+	Path *ICMPPath `yaml:"path,omitempty" json:"path,omitempty"`
+	// IPFamily of the address that is pinged, one of ipv4, ipv6 or both, defaults to ipv4.
+	// both pings an address of each family and reports one result per family
+	IPFamily string `yaml:"ipFamily,omitempty" json:"ipFamily,omitempty"`
+}
+
+type ICMPPath struct {
+	// Protocol of the probes, one of icmp, udp or tcp (SYN), defaults to icmp
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	// Port is the destination port of tcp probes, defaults to 80, and the first destination port of
+	// udp probes, defaults to 33434
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// MaxHops is the highest TTL probed, defaults to 30
+	MaxHops int `yaml:"maxHops,omitempty" json:"maxHops,omitempty"`
+	// Timeout to wait for the replies of each round of probes, defaults to 2s
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// MaxPathLength fails the check when the endpoint is more hops away
+	MaxPathLength int `yaml:"maxPathLength,omitempty" json:"maxPathLength,omitempty"`
+	// HopLossThreshold fails the check when the loss of a hop is higher, in percent. Hops that
+	// do not reply at all are ignored as many routers do not send time exceeded messages
+	HopLossThreshold int64 `yaml:"hopLossThreshold,omitempty" json:"hopLossThreshold,omitempty"`
 }
 
 func (c ICMPCheck) GetEndpoint() string {
@@ -437,6 +995,8 @@ type RedisCheck struct {
 	// TLSConfig configures TLS for the redis connection. Any non-empty field
 	// (enable, insecureSkipVerify, ca, cert) implies TLS is enabled.
 	TLSConfig *SwitchableTLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 // SwitchableTLSConfig is a TLSConfig with an explicit enable flag, so that
@@ -578,6 +1138,8 @@ type OpenSearchCheck struct {
 	Query       string `yaml:"query" json:"query"`
 	Index       string `yaml:"index" json:"index"`
 	Results     int64  `yaml:"results,omitempty" json:"results,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 func (c OpenSearchCheck) GetType() string {
@@ -604,6 +1166,8 @@ type ElasticsearchCheck struct {
 	Query       string `yaml:"query" json:"query,omitempty" template:"true"`
 	Index       string `yaml:"index" json:"index,omitempty" template:"true"`
 	Results     int    `yaml:"results" json:"results,omitempty" template:"true"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 func (c ElasticsearchCheck) GetType() string {
@@ -663,6 +1227,8 @@ type AlertManagerCheck struct {
 	Filters        map[string]string `yaml:"filters" json:"filters,omitempty" template:"true"`
 	ExcludeFilters map[string]string `yaml:"exclude_filters" json:"exclude_filters,omitempty" template:"true"`
 	Ignore         []string          `yaml:"ignore" json:"ignore,omitempty" template:"true"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 func (c AlertManagerCheck) GetType() string {
@@ -711,6 +1277,8 @@ type LDAPCheck struct {
 	BindDN        string `yaml:"bindDN" json:"bindDN"`
 	UserSearch    string `yaml:"userSearch,omitempty" json:"userSearch,omitempty"`
 	SkipTLSVerify bool   `yaml:"skipTLSVerify,omitempty" json:"skipTLSVerify,omitempty"`
+	// Proxy overrides the proxy of the canary, ldapi and cldap urls cannot be connected to through a proxy
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 func (c LDAPCheck) GetType() string {
@@ -753,16 +1321,37 @@ func (c NamespaceCheck) GetType() string {
 }
 
 type DNSCheck struct {
-	Description     `yaml:",inline" json:",inline"`
-	Relatable       `yaml:",inline" json:",inline"`
-	Server          string   `yaml:"server" json:"server,omitempty"`
-	Port            int      `yaml:"port,omitempty" json:"port,omitempty"`
-	Query           string   `yaml:"query,omitempty" json:"query,omitempty"`
+	Description `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// Server is the resolver to query, either host[:port] or a url: udp://host[:port], tcp://host[:port],
+	// tls://host[:port] for DNS-over-TLS or https://host/dns-query for DNS-over-HTTPS.
+	// Defaults to the system resolver
+	Server string `yaml:"server" json:"server,omitempty"`
+	// Port of udp and tcp servers without a port, defaults to 53
+	Port  int    `yaml:"port,omitempty" json:"port,omitempty"`
+	Query string `yaml:"query,omitempty" json:"query,omitempty"`
+	// QueryType is one of A, AAAA, CNAME, SRV, MX, PTR, TXT, NS, CAA, SOA, DS or DNSKEY, defaults to A
 	QueryType       string   `yaml:"querytype,omitempty" json:"querytype,omitempty"`
 	MinRecords      int      `yaml:"minrecords,omitempty" json:"minrecords,omitempty"`
 	ExactReply      []string `yaml:"exactreply,omitempty" json:"exactreply,omitempty"`
 	Timeout         int      `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 	ThresholdMillis int      `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Servers to query instead of server, in the same format. The check fails when their answers
+	// or the serials of the SOA of the zone disagree
+	Servers []string `yaml:"servers,omitempty" json:"servers,omitempty"`
+	// Authoritative also queries every nameserver of the zone of the query without recursion,
+	// the nameservers are looked up using server
+	Authoritative bool `yaml:"authoritative,omitempty" json:"authoritative,omitempty"`
+	// DNSSEC requires the answer to be signed and the response to be authenticated by the resolver,
+	// which validates the chain of trust. It cannot be combined with authoritative, as nameservers do not
+	// validate their own answers
+	DNSSEC bool `yaml:"dnssec,omitempty" json:"dnssec,omitempty"`
+	// MinSignatureValidity fails the check when a signature of the answer expires sooner, e.g. 72h
+	MinSignatureValidity Duration `yaml:"minSignatureValidity,omitempty" json:"minSignatureValidity,omitempty"`
+	// TLSConfig for DNS-over-TLS and DNS-over-HTTPS servers
+	TLSConfig *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Proxy overrides the proxy of the canary for DNS-over-HTTPS servers
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 	// SrvReply    SrvReply `yaml:"srvReply,omitempty" json:"srvReply,omitempty"`
 }
 
@@ -773,6 +1362,8 @@ func (c DNSCheck) GetEndpoint() string {
 		if c.Port != 0 {
 			s += fmt.Sprintf(":%d", c.Port)
 		}
+	} else if len(c.Servers) > 0 {
+		s += "@" + strings.Join(c.Servers, ",")
 	}
 	return s
 }
@@ -785,6 +1376,24 @@ type PubSubCheck struct {
 	Description        `yaml:",inline" json:",inline"`
 	Templatable        `yaml:",inline" json:",inline"`
 	pubsub.QueueConfig `json:",inline"`
+	// RoundTrip publishes a message to a topic and waits for it on the subscription, instead of
+	// collecting the messages on the subscription
+	RoundTrip *PubSubRoundTrip `yaml:"roundTrip,omitempty" json:"roundTrip,omitempty"`
+}
+
+type PubSubRoundTrip struct {
+	// Topic is the url of the topic the subscription receives from, e.g. mem://queue,
+	// gcppubsub://projects/project/topics/topic, awssqs://sqs.us-east-1.amazonaws.com/123456789012/queue
+	// or nats://subject. sqs, sns and gcp topics are published to with the credentials of the subscription.
+	// The subscription should be dedicated to the check as other messages are acknowledged and dropped.
+	Topic string `yaml:"topic" json:"topic" template:"true"`
+	// Body of the message, defaults to its unique id
+	Body string `yaml:"body,omitempty" json:"body,omitempty" template:"true"`
+	// Timeout for the message to be received, defaults to 30s
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// DuplicateWindow is how long to keep receiving after the message arrived to detect duplicates,
+	// defaults to 5s, 0s disables the detection
+	DuplicateWindow Duration `yaml:"duplicateWindow,omitempty" json:"duplicateWindow,omitempty"`
 }
 
 func (c PubSubCheck) GetEndpoint() string {
@@ -797,15 +1406,53 @@ func (c PubSubCheck) GetType() string {
 
 type HelmCheck struct {
 	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
 	Relatable   `yaml:",inline" json:",inline"`
-	Chartmuseum string          `yaml:"chartmuseum" json:"chartmuseum,omitempty"`
-	Project     string          `yaml:"project,omitempty" json:"project,omitempty"`
-	Auth        *Authentication `yaml:"auth,omitempty" json:"auth,omitempty"`
-	CaFile      string          `yaml:"cafile,omitempty" json:"cafile,omitempty"`
+	// Connection provides the url and credentials of the chart repository, either an http repository
+	// with an index.yaml or an oci registry, e.g. oci://ghcr.io/flanksource/charts
+	Connection `yaml:",inline" json:",inline"`
+	// Chart that must exist in the repository, required for oci repositories
+	Chart string `yaml:"chart,omitempty" json:"chart,omitempty" template:"true"`
+	// Version of the chart that must exist, either a version or a constraint such as >=1.2.0,
+	// defaults to the latest version
+	Version string `yaml:"version,omitempty" json:"version,omitempty" template:"true"`
+	// SkipDownload only checks that the chart version exists, without downloading and unpacking it
+	SkipDownload bool `yaml:"skipDownload,omitempty" json:"skipDownload,omitempty"`
+	// Keyring is a PGP public keyring to verify the signature of the provenance file with,
+	// charts without a provenance file fail the check when it is set
+	Keyring *types.EnvVar `yaml:"keyring,omitempty" json:"keyring,omitempty"`
+	// TLSConfig provides the CA bundle and client certificate for the repository
+	TLSConfig *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Maximum duration in milliseconds for all the requests. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// Deprecated: use url, the project is appended to the chartmuseum url
+	Chartmuseum string `yaml:"chartmuseum,omitempty" json:"chartmuseum,omitempty"`
+	// Deprecated: use url
+	Project string `yaml:"project,omitempty" json:"project,omitempty"`
+	// Deprecated: use username and password
+	Auth *Authentication `yaml:"auth,omitempty" json:"auth,omitempty"`
+	// Deprecated: use tlsConfig
+	CaFile string `yaml:"cafile,omitempty" json:"cafile,omitempty"`
+}
+
+// GetRepositoryURL returns the url of the repository, falling back to the deprecated chartmuseum and project
+func (c HelmCheck) GetRepositoryURL() string {
+	if c.URL != "" || c.Chartmuseum == "" {
+		return c.URL
+	}
+	if c.Project == "" {
+		return c.Chartmuseum
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Chartmuseum, "/"), c.Project)
 }
 
 func (c HelmCheck) GetEndpoint() string {
-	return fmt.Sprintf("%s/%s", c.Chartmuseum, c.Project)
+	if c.Chart == "" {
+		return c.GetRepositoryURL()
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(c.GetRepositoryURL(), "/"), c.Chart)
 }
 
 func (c HelmCheck) GetType() string {
@@ -849,6 +1496,14 @@ func (c JunitCheck) GetTimeout() int {
 	return 5
 }
 
+// GetCheckTimeout defaults to the time the check waits for the pod to finish
+func (c JunitCheck) GetCheckTimeout() (time.Duration, error) {
+	if c.CheckTimeout != "" {
+		return c.Description.GetCheckTimeout()
+	}
+	return time.Duration(c.GetTimeout()) * time.Minute, nil
+}
+
 func (c JunitCheck) GetType() string {
 	return "junit"
 }
@@ -871,6 +1526,8 @@ type PrometheusCheck struct {
 	Host string `yaml:"host,omitempty" json:"host,omitempty"`
 	// PromQL query
 	Query string `yaml:"query" json:"query" template:"true"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
 }
 
 func (c PrometheusCheck) GetType() string {
@@ -879,19 +1536,44 @@ func (c PrometheusCheck) GetType() string {
 
 type MongoDBCheck struct {
 	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
 	Connection  `yaml:",inline" json:",inline"`
+	// Database to query, defaults to the database of the url
+	Database string `yaml:"database,omitempty" json:"database,omitempty"`
+	// Collection to query, aggregations without a collection run against the database
+	Collection string `yaml:"collection,omitempty" json:"collection,omitempty"`
+	// Find is an extended JSON filter of the documents to return, the documents are available
+	// to tests as results.documents and their number as results.count
+	Find string `yaml:"find,omitempty" json:"find,omitempty" template:"true"`
+	// Limit of the documents returned by find or aggregate, defaults to 100
+	Limit int64 `yaml:"limit,omitempty" json:"limit,omitempty"`
+	// Aggregate is an extended JSON array of pipeline stages, the resulting documents are available
+	// to tests as results.documents and their number as results.count
+	Aggregate string `yaml:"aggregate,omitempty" json:"aggregate,omitempty" template:"true"`
+	// ReplicaSet reports the state of every member of the replica set, available to tests as results.replicaSet
+	ReplicaSet *MongoReplicaSet `yaml:"replicaSet,omitempty" json:"replicaSet,omitempty"`
+	// ConnectTimeout defaults to 3s
+	ConnectTimeout Duration `yaml:"connectTimeout,omitempty" json:"connectTimeout,omitempty"`
+	// QueryTimeout for connecting, the query and the replica set status, defaults to 5s
+	QueryTimeout Duration `yaml:"queryTimeout,omitempty" json:"queryTimeout,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+type MongoReplicaSet struct {
+	// MinHealthyMembers fails the check when fewer members are a healthy primary, secondary or arbiter,
+	// defaults to all members. The check always fails when there is no primary
+	MinHealthyMembers int `yaml:"minHealthyMembers,omitempty" json:"minHealthyMembers,omitempty"`
+	// MaxReplicationLag fails the check when a secondary is further behind the primary
+	MaxReplicationLag Duration `yaml:"maxReplicationLag,omitempty" json:"maxReplicationLag,omitempty"`
+	// MinElectionAge fails the check when the primary was elected more recently, to detect failovers
+	MinElectionAge Duration `yaml:"minElectionAge,omitempty" json:"minElectionAge,omitempty"`
 }
 
 func (c MongoDBCheck) GetType() string {
 	return "mongodb"
 }
 
-// Git executes a SQL style query against a github repo using mergestat.
-// Deprecated: This check type is deprecated and will be removed in a future release.
-type Git struct {
-	GitHubCheck `yaml:",inline" json:",inline"`
-}
-
 // GitHubCheck executes SQL queries against GitHub repositories using mergestat.
 // Deprecated: This check type is deprecated and will be removed in a future release.
 type GitHubCheck struct {
@@ -930,6 +1612,46 @@ func (c GitProtocolCheck) GetEndpoint() string {
 	return strings.ReplaceAll(c.Repository, "/", "-")
 }
 
+type GitCheck struct {
+	Description `yaml:",inline" json:",inline"`
+	Templatable `yaml:",inline" json:",inline"`
+	Relatable   `yaml:",inline" json:",inline"`
+	// GitConnection is the https, ssh or file url of the repository and its credentials, the certificate is
+	// the private key for ssh urls. The branch defaults to the HEAD of the remote.
+	connection.GitConnection `yaml:",inline" json:",inline"`
+	// Clone the branch to read its last commit, otherwise only the refs of the remote are listed
+	Clone bool `yaml:"clone,omitempty" json:"clone,omitempty"`
+	// MaxAge fails the check if the last commit on the branch is older, e.g. 24h. Implies clone.
+	MaxAge Duration `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+	// Push an empty commit to a scratch branch, and delete the branch afterwards. Implies clone.
+	Push *GitPush `yaml:"push,omitempty" json:"push,omitempty"`
+	// KnownHosts in the known_hosts format to verify the host key of ssh urls,
+	// defaults to the files in $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts
+	KnownHosts *types.EnvVar `yaml:"knownHosts,omitempty" json:"knownHosts,omitempty"`
+	// TLSConfig provides the CA bundle and client certificate for https urls
+	TLSConfig *TLSConfig `yaml:"tlsConfig,omitempty" json:"tlsConfig,omitempty"`
+	// Maximum duration in milliseconds for all the operations. It will fail the check if it takes longer.
+	ThresholdMillis int `yaml:"thresholdMillis,omitempty" json:"thresholdMillis,omitempty"`
+	// Proxy overrides the proxy of the canary
+	Proxy *Proxy `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+}
+
+// GitPush is the scratch branch pushed to by a git check
+type GitPush struct {
+	// Branch to push to, defaults to canary-checker/<canary name>. It must not be a branch that is in use.
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty" template:"true"`
+	// SkipDelete leaves the pushed branch in the remote
+	SkipDelete bool `yaml:"skipDelete,omitempty" json:"skipDelete,omitempty"`
+}
+
+func (c GitCheck) GetType() string {
+	return "git"
+}
+
+func (c GitCheck) GetEndpoint() string {
+	return c.URL
+}
+
 type CatalogCheck struct {
 	Description `yaml:",inline" json:",inline"`
 	Templatable `yaml:",inline" json:",inline"`
@@ -1492,15 +2214,20 @@ type MsSQL struct {
 }
 
 /*
-This check pulls data from a Pub/Sub Subscription
+This check pulls data from a Pub/Sub Subscription, or in round trip mode publishes a message to a topic
+and measures how long it takes to arrive on the subscription
 [include:external/pubsub-gcp.yaml]
+[include:external/pubsub-round-trip.yaml]
 */
 type PubSub struct {
 	PubSubCheck `yaml:",inline" json:"inline"`
 }
 
 /*
-[include:datasources/helm_pass.yaml]
+Helm check fetches the index of a chart repository, or the tags of a chart in an oci registry, and
+optionally downloads and unpacks a chart version, verifying its digest and provenance.
+
+[include:minimal/helm.yaml]
 */
 type Helm struct {
 	HelmCheck `yaml:",inline" json:"inline"`
@@ -1631,6 +2358,114 @@ type DatabaseBackup struct {
 	DatabaseBackupCheck `yaml:",inline" json:",inline"`
 }
 
+/*
+GRPC check calls the standard grpc.health.v1.Health/Check method of a gRPC server and
+optionally lists its services using server reflection.
+
+[include:minimal/grpc.yaml]
+*/
+type GRPC struct {
+	GRPCCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+TLS check performs a TLS handshake against any host:port, optionally after upgrading the connection
+with STARTTLS, and verifies the certificate chain, hostname, expiry and negotiated protocol version.
+
+[include:minimal/tls.yaml]
+*/
+type TLS struct {
+	TLSCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+HAR check replays the requests of a HAR recording in order and compares the responses with the recording.
+
+[include:minimal/har.yaml]
+*/
+type HAR struct {
+	HARCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+Registry check pulls an image from an OCI registry using the distribution API, verifying the digests of the
+manifest and layers, and optionally pushes and deletes a small generated image.
+
+[include:minimal/registry.yaml]
+*/
+type Registry struct {
+	RegistryCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+Git check lists the refs of a git repository over https, ssh or from a local path, and optionally clones the branch to check
+the age of its last commit and pushes an empty commit to a scratch branch to verify write access.
+
+[include:minimal/git.yaml]
+*/
+type Git struct {
+	GitCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+SSH check connects to an SSH server with a password or private key, verifying its host key, and
+optionally runs a command whose output and exit code can be tested like an exec check.
+
+[include:minimal/ssh.yaml]
+*/
+type SSH struct {
+	SSHCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+SMTP check connects to a mail server, upgrades with STARTTLS and authenticates, optionally sending a test
+message and waiting for it to arrive in an IMAP mailbox to measure the delivery latency.
+
+[include:minimal/smtp.yaml]
+*/
+type SMTP struct {
+	SMTPCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+Kafka check connects to the brokers, reports offline and under replicated partitions, and computes the lag
+of consumer groups on every partition.
+
+[include:minimal/kafka.yaml]
+*/
+type Kafka struct {
+	KafkaCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+MQTT check connects to a broker, subscribes to a topic and publishes a probe message to it, reporting the
+connect, publish and receive latencies and whether the topic has a retained message.
+
+[include:minimal/mqtt.yaml]
+*/
+type MQTT struct {
+	MQTTCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+Websocket check performs the upgrade handshake, sends messages and waits for responses matching a regex or
+CEL expression, reporting the handshake and round trip times.
+
+[include:minimal/websocket.yaml]
+*/
+type Websocket struct {
+	WebsocketCheck `yaml:",inline" json:",inline"`
+}
+
+/*
+NTP check queries NTP servers for the offset of the local clock, the round trip delay, stratum and leap status.
+
+[include:minimal/ntp.yaml]
+*/
+type NTP struct {
+	NTPCheck `yaml:",inline" json:",inline"`
+}
+
 type AzureDevopsCheck struct {
 	Description         `yaml:",inline" json:",inline"`
 	Templatable         `yaml:",inline" json:",inline"`
@@ -1696,29 +2531,40 @@ var AllChecks = []external.Check{
 	ElasticsearchCheck{},
 	ExecCheck{},
 	FolderCheck{},
+	GitCheck{},
 	GitHubCheck{},
+	GRPCCheck{},
 	GitProtocolCheck{},
 	PubSubCheck{},
+	HARCheck{},
 	HelmCheck{},
 	HTTPCheck{},
 	ICMPCheck{},
 	JmeterCheck{},
 	JunitCheck{},
+	KafkaCheck{},
 	Kubernetes{},
 	LDAPCheck{},
 	MongoDBCheck{},
+	MQTTCheck{},
 	MssqlCheck{},
 	MysqlCheck{},
 	NamespaceCheck{},
+	NTPCheck{},
 	OpenSearchCheck{},
 	PodCheck{},
 	PostgresCheck{},
 	PrometheusCheck{},
 	RedisCheck{},
+	RegistryCheck{},
 	ResticCheck{},
 	S3Check{},
+	SMTPCheck{},
+	SSHCheck{},
 	TCPCheck{},
+	TLSCheck{},
 	WebhookCheck{},
+	WebsocketCheck{},
 }
 
 var AllCheckTypes = lo.Map(AllChecks, func(item external.Check, index int) string {
@@ -411,6 +411,11 @@ type Description struct {
 
 	// Retries configures generic retry behavior for this check.
 	Retries *CheckRetries `yaml:"retries,omitempty" json:"retries,omitempty"`
+
+	// CheckTimeout is the maximum duration of a single run of the check, after which it is cancelled
+	// and marked as failed. Defaults to the interval between two scheduled runs of the canary.
+	// It is not named timeout, as several checks already use that key for their own timeouts.
+	CheckTimeout Duration `yaml:"checkTimeout,omitempty" json:"checkTimeout,omitempty"`
 }
 
 func (d Description) String() string {
@@ -480,6 +485,13 @@ func (d Description) GetRetries() *CheckRetries {
 	return d.Retries
 }
 
+func (d Description) GetCheckTimeout() (time.Duration, error) {
+	if d.CheckTimeout == "" {
+		return time.Duration(0), nil
+	}
+	return d.CheckTimeout.GetDurationOrZero()
+}
+
 type Connection struct {
 	// Connection name e.g. connection://http/google
 	Connection string `yaml:"connection,omitempty" json:"connection,omitempty"`
@@ -81,6 +81,11 @@ func (in *AlertManagerCheck) DeepCopyInto(out *AlertManagerCheck) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertManagerCheck.
@@ -371,6 +376,11 @@ func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
 		*out = new(CheckRetries)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make(map[string]VarSource, len(*in))
@@ -385,6 +395,13 @@ func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.HAR != nil {
+		in, out := &in.HAR, &out.HAR
+		*out = make([]HARCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.DNS != nil {
 		in, out := &in.DNS, &out.DNS
 		*out = make([]DNSCheck, len(*in))
@@ -420,6 +437,13 @@ func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = make([]RegistryCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.S3 != nil {
 		in, out := &in.S3, &out.S3
 		*out = make([]S3Check, len(*in))
@@ -434,6 +458,62 @@ func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = make([]GRPCCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = make([]TLSCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SSH != nil {
+		in, out := &in.SSH, &out.SSH
+		*out = make([]SSHCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SMTP != nil {
+		in, out := &in.SMTP, &out.SMTP
+		*out = make([]SMTPCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = make([]KafkaCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MQTT != nil {
+		in, out := &in.MQTT, &out.MQTT
+		*out = make([]MQTTCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Websocket != nil {
+		in, out := &in.Websocket, &out.Websocket
+		*out = make([]WebsocketCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NTP != nil {
+		in, out := &in.NTP, &out.NTP
+		*out = make([]NTPCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Pod != nil {
 		in, out := &in.Pod, &out.Pod
 		*out = make([]PodCheck, len(*in))
@@ -560,6 +640,13 @@ func (in *CanarySpec) DeepCopyInto(out *CanarySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Git != nil {
+		in, out := &in.Git, &out.Git
+		*out = make([]GitCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Kubernetes != nil {
 		in, out := &in.Kubernetes, &out.Kubernetes
 		*out = make([]KubernetesCheck, len(*in))
@@ -1485,6 +1572,21 @@ func (in *DNSCheck) DeepCopyInto(out *DNSCheck) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSCheck.
@@ -1714,6 +1816,11 @@ func (in *ElasticsearchCheck) DeepCopyInto(out *ElasticsearchCheck) {
 	out.Templatable = in.Templatable
 	in.Relatable.DeepCopyInto(&out.Relatable)
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchCheck.
@@ -1979,10 +2086,77 @@ func (in *GCPDatabase) DeepCopy() *GCPDatabase {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPC) DeepCopyInto(out *GRPC) {
+	*out = *in
+	in.GRPCCheck.DeepCopyInto(&out.GRPCCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPC.
+func (in *GRPC) DeepCopy() *GRPC {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCCheck) DeepCopyInto(out *GRPCCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]types.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(SwitchableTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resolve != nil {
+		in, out := &in.Resolve, &out.Resolve
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCCheck.
+func (in *GRPCCheck) DeepCopy() *GRPCCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Git) DeepCopyInto(out *Git) {
 	*out = *in
-	in.GitHubCheck.DeepCopyInto(&out.GitHubCheck)
+	in.GitCheck.DeepCopyInto(&out.GitCheck)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Git.
@@ -1995,6 +2169,45 @@ func (in *Git) DeepCopy() *Git {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitCheck) DeepCopyInto(out *GitCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.GitConnection.DeepCopyInto(&out.GitConnection)
+	if in.Push != nil {
+		in, out := &in.Push, &out.Push
+		*out = new(GitPush)
+		**out = **in
+	}
+	if in.KnownHosts != nil {
+		in, out := &in.KnownHosts, &out.KnownHosts
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitCheck.
+func (in *GitCheck) DeepCopy() *GitCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(GitCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitCheckout) DeepCopyInto(out *GitCheckout) {
 	*out = *in
@@ -2052,6 +2265,84 @@ func (in *GitProtocolCheck) DeepCopy() *GitProtocolCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitPush) DeepCopyInto(out *GitPush) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitPush.
+func (in *GitPush) DeepCopy() *GitPush {
+	if in == nil {
+		return nil
+	}
+	out := new(GitPush)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HAR) DeepCopyInto(out *HAR) {
+	*out = *in
+	in.HARCheck.DeepCopyInto(&out.HARCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HAR.
+func (in *HAR) DeepCopy() *HAR {
+	if in == nil {
+		return nil
+	}
+	out := new(HAR)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HARCheck) DeepCopyInto(out *HARCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.HAR != nil {
+		in, out := &in.HAR, &out.HAR
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]types.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HARCheck.
+func (in *HARCheck) DeepCopy() *HARCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HARCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTP) DeepCopyInto(out *HTTP) {
 	*out = *in
@@ -2075,6 +2366,11 @@ func (in *HTTPCheck) DeepCopyInto(out *HTTPCheck) {
 	out.Templatable = in.Templatable
 	in.Relatable.DeepCopyInto(&out.Relatable)
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.PhaseThresholdMillis != nil {
+		in, out := &in.PhaseThresholdMillis, &out.PhaseThresholdMillis
+		*out = new(HTTPPhaseThresholds)
+		**out = **in
+	}
 	if in.ResponseCodes != nil {
 		in, out := &in.ResponseCodes, &out.ResponseCodes
 		*out = make([]int, len(*in))
@@ -2124,6 +2420,39 @@ func (in *HTTPCheck) DeepCopyInto(out *HTTPCheck) {
 		*out = new(Crawl)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]HTTPStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Contract != nil {
+		in, out := &in.Contract, &out.Contract
+		*out = new(HTTPContract)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resolve != nil {
+		in, out := &in.Resolve, &out.Resolve
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPCheck.
@@ -2137,67 +2466,176 @@ func (in *HTTPCheck) DeepCopy() *HTTPCheck {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Helm) DeepCopyInto(out *Helm) {
+func (in *HTTPContract) DeepCopyInto(out *HTTPContract) {
 	*out = *in
-	in.HelmCheck.DeepCopyInto(&out.HelmCheck)
+	if in.OpenAPI != nil {
+		in, out := &in.OpenAPI, &out.OpenAPI
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schema != nil {
+		in, out := &in.Schema, &out.Schema
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Helm.
-func (in *Helm) DeepCopy() *Helm {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPContract.
+func (in *HTTPContract) DeepCopy() *HTTPContract {
 	if in == nil {
 		return nil
 	}
-	out := new(Helm)
+	out := new(HTTPContract)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HelmCheck) DeepCopyInto(out *HelmCheck) {
+func (in *HTTPPhaseThresholds) DeepCopyInto(out *HTTPPhaseThresholds) {
 	*out = *in
-	in.Description.DeepCopyInto(&out.Description)
-	in.Relatable.DeepCopyInto(&out.Relatable)
-	if in.Auth != nil {
-		in, out := &in.Auth, &out.Auth
-		*out = new(Authentication)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmCheck.
-func (in *HelmCheck) DeepCopy() *HelmCheck {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPPhaseThresholds.
+func (in *HTTPPhaseThresholds) DeepCopy() *HTTPPhaseThresholds {
 	if in == nil {
 		return nil
 	}
-	out := new(HelmCheck)
+	out := new(HTTPPhaseThresholds)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ICMP) DeepCopyInto(out *ICMP) {
+func (in *HTTPStep) DeepCopyInto(out *HTTPStep) {
 	*out = *in
-	in.ICMPCheck.DeepCopyInto(&out.ICMPCheck)
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]types.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResponseCodes != nil {
+		in, out := &in.ResponseCodes, &out.ResponseCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.Extract != nil {
+		in, out := &in.Extract, &out.Extract
+		*out = make([]HTTPStepExtract, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMP.
-func (in *ICMP) DeepCopy() *ICMP {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPStep.
+func (in *HTTPStep) DeepCopy() *HTTPStep {
 	if in == nil {
 		return nil
 	}
-	out := new(ICMP)
+	out := new(HTTPStep)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ICMPCheck) DeepCopyInto(out *ICMPCheck) {
+func (in *HTTPStepExtract) DeepCopyInto(out *HTTPStepExtract) {
 	*out = *in
-	in.Description.DeepCopyInto(&out.Description)
-	in.Relatable.DeepCopyInto(&out.Relatable)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPCheck.
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPStepExtract.
+func (in *HTTPStepExtract) DeepCopy() *HTTPStepExtract {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPStepExtract)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Helm) DeepCopyInto(out *Helm) {
+	*out = *in
+	in.HelmCheck.DeepCopyInto(&out.HelmCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Helm.
+func (in *Helm) DeepCopy() *Helm {
+	if in == nil {
+		return nil
+	}
+	out := new(Helm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmCheck) DeepCopyInto(out *HelmCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Keyring != nil {
+		in, out := &in.Keyring, &out.Keyring
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(Authentication)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmCheck.
+func (in *HelmCheck) DeepCopy() *HelmCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMP) DeepCopyInto(out *ICMP) {
+	*out = *in
+	in.ICMPCheck.DeepCopyInto(&out.ICMPCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMP.
+func (in *ICMP) DeepCopy() *ICMP {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPCheck) DeepCopyInto(out *ICMPCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(ICMPPath)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPCheck.
 func (in *ICMPCheck) DeepCopy() *ICMPCheck {
 	if in == nil {
 		return nil
@@ -2207,6 +2645,21 @@ func (in *ICMPCheck) DeepCopy() *ICMPCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPPath) DeepCopyInto(out *ICMPPath) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPPath.
+func (in *ICMPPath) DeepCopy() *ICMPPath {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMPPath)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JSONCheck) DeepCopyInto(out *JSONCheck) {
 	*out = *in
@@ -2310,6 +2763,66 @@ func (in *JunitCheck) DeepCopy() *JunitCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Kafka) DeepCopyInto(out *Kafka) {
+	*out = *in
+	in.KafkaCheck.DeepCopyInto(&out.KafkaCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Kafka.
+func (in *Kafka) DeepCopy() *Kafka {
+	if in == nil {
+		return nil
+	}
+	out := new(Kafka)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaCheck) DeepCopyInto(out *KafkaCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(SwitchableTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumerGroups != nil {
+		in, out := &in.ConsumerGroups, &out.ConsumerGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaCheck.
+func (in *KafkaCheck) DeepCopy() *KafkaCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Kubernetes) DeepCopyInto(out *Kubernetes) {
 	*out = *in
@@ -2461,6 +2974,11 @@ func (in *LDAPCheck) DeepCopyInto(out *LDAPCheck) {
 	in.Description.DeepCopyInto(&out.Description)
 	in.Relatable.DeepCopyInto(&out.Relatable)
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPCheck.
@@ -2494,6 +3012,56 @@ func (in Labels) DeepCopy() Labels {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MQTT) DeepCopyInto(out *MQTT) {
+	*out = *in
+	in.MQTTCheck.DeepCopyInto(&out.MQTTCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MQTT.
+func (in *MQTT) DeepCopy() *MQTT {
+	if in == nil {
+		return nil
+	}
+	out := new(MQTT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MQTTCheck) DeepCopyInto(out *MQTTCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.QoS != nil {
+		in, out := &in.QoS, &out.QoS
+		*out = new(int)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MQTTCheck.
+func (in *MQTTCheck) DeepCopy() *MQTTCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(MQTTCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mongo) DeepCopyInto(out *Mongo) {
 	*out = *in
@@ -2514,7 +3082,18 @@ func (in *Mongo) DeepCopy() *Mongo {
 func (in *MongoDBCheck) DeepCopyInto(out *MongoDBCheck) {
 	*out = *in
 	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.ReplicaSet != nil {
+		in, out := &in.ReplicaSet, &out.ReplicaSet
+		*out = new(MongoReplicaSet)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBCheck.
@@ -2527,6 +3106,21 @@ func (in *MongoDBCheck) DeepCopy() *MongoDBCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoReplicaSet) DeepCopyInto(out *MongoReplicaSet) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoReplicaSet.
+func (in *MongoReplicaSet) DeepCopy() *MongoReplicaSet {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoReplicaSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MsSQL) DeepCopyInto(out *MsSQL) {
 	*out = *in
@@ -2575,6 +3169,45 @@ func (in *MysqlCheck) DeepCopy() *MysqlCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NTP) DeepCopyInto(out *NTP) {
+	*out = *in
+	in.NTPCheck.DeepCopyInto(&out.NTPCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NTP.
+func (in *NTP) DeepCopy() *NTP {
+	if in == nil {
+		return nil
+	}
+	out := new(NTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NTPCheck) DeepCopyInto(out *NTPCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NTPCheck.
+func (in *NTPCheck) DeepCopy() *NTPCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(NTPCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Namespace) DeepCopyInto(out *Namespace) {
 	*out = *in
@@ -2677,6 +3310,11 @@ func (in *OpenSearchCheck) DeepCopyInto(out *OpenSearchCheck) {
 	out.Templatable = in.Templatable
 	in.Relatable.DeepCopyInto(&out.Relatable)
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenSearchCheck.
@@ -2797,6 +3435,11 @@ func (in *PrometheusCheck) DeepCopyInto(out *PrometheusCheck) {
 	out.Templatable = in.Templatable
 	in.Relatable.DeepCopyInto(&out.Relatable)
 	in.PrometheusConnection.DeepCopyInto(&out.PrometheusConnection)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusCheck.
@@ -2880,6 +3523,28 @@ func (in *Property) DeepCopy() *Property {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PubSub) DeepCopyInto(out *PubSub) {
 	*out = *in
@@ -2902,6 +3567,11 @@ func (in *PubSubCheck) DeepCopyInto(out *PubSubCheck) {
 	in.Description.DeepCopyInto(&out.Description)
 	out.Templatable = in.Templatable
 	in.QueueConfig.DeepCopyInto(&out.QueueConfig)
+	if in.RoundTrip != nil {
+		in, out := &in.RoundTrip, &out.RoundTrip
+		*out = new(PubSubRoundTrip)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PubSubCheck.
@@ -2914,6 +3584,21 @@ func (in *PubSubCheck) DeepCopy() *PubSubCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PubSubRoundTrip) DeepCopyInto(out *PubSubRoundTrip) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PubSubRoundTrip.
+func (in *PubSubRoundTrip) DeepCopy() *PubSubRoundTrip {
+	if in == nil {
+		return nil
+	}
+	out := new(PubSubRoundTrip)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Redis) DeepCopyInto(out *Redis) {
 	*out = *in
@@ -2946,6 +3631,11 @@ func (in *RedisCheck) DeepCopyInto(out *RedisCheck) {
 		*out = new(SwitchableTLSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisCheck.
@@ -2959,10 +3649,80 @@ func (in *RedisCheck) DeepCopy() *RedisCheck {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Relatable) DeepCopyInto(out *Relatable) {
+func (in *Registry) DeepCopyInto(out *Registry) {
 	*out = *in
-	if in.Relationships != nil {
-		in, out := &in.Relationships, &out.Relationships
+	in.RegistryCheck.DeepCopyInto(&out.RegistryCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Registry.
+func (in *Registry) DeepCopy() *Registry {
+	if in == nil {
+		return nil
+	}
+	out := new(Registry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCheck) DeepCopyInto(out *RegistryCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Connection.DeepCopyInto(&out.Connection)
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.DockerConfig != nil {
+		in, out := &in.DockerConfig, &out.DockerConfig
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Push != nil {
+		in, out := &in.Push, &out.Push
+		*out = new(RegistryPush)
+		**out = **in
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCheck.
+func (in *RegistryCheck) DeepCopy() *RegistryCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryPush) DeepCopyInto(out *RegistryPush) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPush.
+func (in *RegistryPush) DeepCopy() *RegistryPush {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryPush)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Relatable) DeepCopyInto(out *Relatable) {
+	*out = *in
+	if in.Relationships != nil {
+		in, out := &in.Relationships, &out.Relationships
 		*out = new(CheckRelationship)
 		(*in).DeepCopyInto(*out)
 	}
@@ -3090,6 +3850,102 @@ func (in *S3Check) DeepCopy() *S3Check {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTP) DeepCopyInto(out *SMTP) {
+	*out = *in
+	in.SMTPCheck.DeepCopyInto(&out.SMTPCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTP.
+func (in *SMTP) DeepCopy() *SMTP {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTPCheck) DeepCopyInto(out *SMTPCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mail != nil {
+		in, out := &in.Mail, &out.Mail
+		*out = new(SMTPMail)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RoundTrip != nil {
+		in, out := &in.RoundTrip, &out.RoundTrip
+		*out = new(SMTPRoundTrip)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTPCheck.
+func (in *SMTPCheck) DeepCopy() *SMTPCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTPCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTPMail) DeepCopyInto(out *SMTPMail) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTPMail.
+func (in *SMTPMail) DeepCopy() *SMTPMail {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTPMail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTPRoundTrip) DeepCopyInto(out *SMTPRoundTrip) {
+	*out = *in
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTPRoundTrip.
+func (in *SMTPRoundTrip) DeepCopy() *SMTPRoundTrip {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTPRoundTrip)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SQLCheck) DeepCopyInto(out *SQLCheck) {
 	*out = *in
@@ -3109,6 +3965,52 @@ func (in *SQLCheck) DeepCopy() *SQLCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSH) DeepCopyInto(out *SSH) {
+	*out = *in
+	in.SSHCheck.DeepCopyInto(&out.SSHCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSH.
+func (in *SSH) DeepCopy() *SSH {
+	if in == nil {
+		return nil
+	}
+	out := new(SSH)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHCheck) DeepCopyInto(out *SSHCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	in.PrivateKey.DeepCopyInto(&out.PrivateKey)
+	if in.KnownHosts != nil {
+		in, out := &in.KnownHosts, &out.KnownHosts
+		*out = new(types.EnvVar)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHCheck.
+func (in *SSHCheck) DeepCopy() *SSHCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Selector) DeepCopyInto(out *Selector) {
 	*out = *in
@@ -3184,6 +4086,39 @@ func (in *TCPCheck) DeepCopyInto(out *TCPCheck) {
 	in.Description.DeepCopyInto(&out.Description)
 	out.Templatable = in.Templatable
 	in.Relatable.DeepCopyInto(&out.Relatable)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resolve != nil {
+		in, out := &in.Resolve, &out.Resolve
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(SwitchableTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Messages != nil {
+		in, out := &in.Messages, &out.Messages
+		*out = make([]TCPMessage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPCheck.
@@ -3196,6 +4131,97 @@ func (in *TCPCheck) DeepCopy() *TCPCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPExpect) DeepCopyInto(out *TCPExpect) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPExpect.
+func (in *TCPExpect) DeepCopy() *TCPExpect {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPExpect)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TCPMessage) DeepCopyInto(out *TCPMessage) {
+	*out = *in
+	if in.Expect != nil {
+		in, out := &in.Expect, &out.Expect
+		*out = new(TCPExpect)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TCPMessage.
+func (in *TCPMessage) DeepCopy() *TCPMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(TCPMessage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLS) DeepCopyInto(out *TLS) {
+	*out = *in
+	in.TLSCheck.DeepCopyInto(&out.TLSCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLS.
+func (in *TLS) DeepCopy() *TLS {
+	if in == nil {
+		return nil
+	}
+	out := new(TLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSCheck) DeepCopyInto(out *TLSCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.TLSConfig.DeepCopyInto(&out.TLSConfig)
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resolve != nil {
+		in, out := &in.Resolve, &out.Resolve
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSCheck.
+func (in *TLSCheck) DeepCopy() *TLSCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
 	*out = *in
@@ -3453,3 +4479,102 @@ func (in *WebhookCheck) DeepCopy() *WebhookCheck {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Websocket) DeepCopyInto(out *Websocket) {
+	*out = *in
+	in.WebsocketCheck.DeepCopyInto(&out.WebsocketCheck)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Websocket.
+func (in *Websocket) DeepCopy() *Websocket {
+	if in == nil {
+		return nil
+	}
+	out := new(Websocket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebsocketCheck) DeepCopyInto(out *WebsocketCheck) {
+	*out = *in
+	in.Description.DeepCopyInto(&out.Description)
+	out.Templatable = in.Templatable
+	in.Relatable.DeepCopyInto(&out.Relatable)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]types.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Subprotocols != nil {
+		in, out := &in.Subprotocols, &out.Subprotocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Messages != nil {
+		in, out := &in.Messages, &out.Messages
+		*out = make([]WebsocketMessage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebsocketCheck.
+func (in *WebsocketCheck) DeepCopy() *WebsocketCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(WebsocketCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebsocketExpect) DeepCopyInto(out *WebsocketExpect) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebsocketExpect.
+func (in *WebsocketExpect) DeepCopy() *WebsocketExpect {
+	if in == nil {
+		return nil
+	}
+	out := new(WebsocketExpect)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebsocketMessage) DeepCopyInto(out *WebsocketMessage) {
+	*out = *in
+	if in.Expect != nil {
+		in, out := &in.Expect, &out.Expect
+		*out = new(WebsocketExpect)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebsocketMessage.
+func (in *WebsocketMessage) DeepCopy() *WebsocketMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(WebsocketMessage)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -45,7 +45,16 @@ func (c *AlertManagerChecker) Check(ctx *context.Context, extConfig external.Che
 		return results
 	}
 
-	req := http.NewClient().R(ctx)
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	client := http.NewClient()
+	if proxyURL := proxy.ForURL(path); proxyURL != nil {
+		client.Proxy(proxyURL.String())
+	}
+
+	req := client.R(ctx)
 	for k, v := range check.Filters {
 		req.QueryParamAdd("filter", fmt.Sprintf("%s=~%s", k, v))
 	}
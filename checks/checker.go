@@ -39,44 +39,53 @@ var All = []Checker{
 	&ElasticsearchChecker{},
 	&ExecChecker{},
 	&FolderChecker{},
-	&removedChecker{typeName: "github", specFn: func(ctx *context.Context) []external.Check {
+	&GitChecker{},
+	&GRPCChecker{},
+	&removedChecker{typeName: "github", message: removedGitMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.GitHub)
 	}},
-	&removedChecker{typeName: "gitProtocol", specFn: func(ctx *context.Context) []external.Check {
+	&removedChecker{typeName: "gitProtocol", message: removedGitMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.GitProtocol)
 	}},
-	&removedChecker{typeName: "containerdPull", specFn: func(ctx *context.Context) []external.Check {
+	&removedChecker{typeName: "containerdPull", message: removedImageMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.ContainerdPull)
 	}},
-	&removedChecker{typeName: "containerdPush", specFn: func(ctx *context.Context) []external.Check {
+	&removedChecker{typeName: "containerdPush", message: removedImageMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.ContainerdPush)
 	}},
-	&removedChecker{typeName: "dockerPull", specFn: func(ctx *context.Context) []external.Check {
+	&removedChecker{typeName: "dockerPull", message: removedImageMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.DockerPull)
 	}},
-	&removedChecker{typeName: "dockerPush", specFn: func(ctx *context.Context) []external.Check {
+	&removedChecker{typeName: "dockerPush", message: removedImageMessage, specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.DockerPush)
 	}},
-	&removedChecker{typeName: "helm", specFn: func(ctx *context.Context) []external.Check {
-		return toChecks(ctx.Canary.Spec.Helm)
-	}},
+	&HARChecker{},
+	&HelmChecker{},
 	&HTTPChecker{},
 	&IcmpChecker{},
 	&JmeterChecker{},
 	&JunitChecker{},
+	&KafkaChecker{},
 	&KubernetesChecker{},
 	&KubernetesResourceChecker{},
 	&LdapChecker{},
 	&MongoDBChecker{},
+	&MQTTChecker{},
 	&MssqlChecker{},
 	&MysqlChecker{},
+	&NTPChecker{},
 	&OpenSearchChecker{},
 	&PostgresChecker{},
 	&PrometheusChecker{},
 	&PubSubChecker{},
 	&RedisChecker{},
+	&RegistryChecker{},
 	&ResticChecker{},
 	&S3Checker{},
+	&SMTPChecker{},
+	&SSHChecker{},
+	&TLSChecker{},
+	&WebsocketChecker{},
 	&removedChecker{typeName: "namespace", specFn: func(ctx *context.Context) []external.Check {
 		return toChecks(ctx.Canary.Spec.Namespace)
 	}},
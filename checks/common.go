@@ -25,16 +25,41 @@ func age(t time.Time) string {
 	return utils.Age(time.Since(t))
 }
 
-func GetDeadline(canary v1.Canary) time.Time {
+// GetCanaryTimeout returns the default timeout of the checks in a canary, which is the interval
+// between two consecutive runs of its schedule, or 0 if the canary is not scheduled.
+func GetCanaryTimeout(canary v1.Canary) time.Duration {
 	if canary.Spec.Schedule != "" {
 		schedule, err := cron.ParseStandard(canary.Spec.Schedule)
 		if err != nil {
 			// cron syntax errors are handled elsewhere, default to a 10 second timeout
-			return time.Now().Add(10 * time.Second)
+			return 10 * time.Second
 		}
-		return schedule.Next(time.Now())
+		// measure between the next two runs, as the time left until the next run can be arbitrarily short
+		next := schedule.Next(time.Now())
+		return schedule.Next(next).Sub(next)
+	}
+	return time.Duration(canary.Spec.Interval) * time.Second
+}
+
+// GetDeadline returns the time by which a run of the canary started now should complete,
+// or the zero time if the canary is not scheduled and runs without a deadline.
+func GetDeadline(canary v1.Canary) time.Time {
+	timeout := GetCanaryTimeout(canary)
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// withCanaryDeadline bounds the context by the deadline of a canary run started now, so that
+// slow checks, retries and timeouts of individual checks cannot overrun the canary's schedule.
+func withCanaryDeadline(ctx *context.Context) (*context.Context, func()) {
+	deadline := GetDeadline(ctx.Canary)
+	if deadline.IsZero() {
+		return ctx, func() {}
 	}
-	return time.Now().Add(time.Duration(canary.Spec.Interval) * time.Second)
+	deadlineCtx, cancel := ctx.WithTimeout(time.Until(deadline))
+	return ctx.Clone().WithDutyContext(deadlineCtx), cancel
 }
 
 func getNextRuntime(canary v1.Canary, lastRuntime time.Time) (*time.Time, error) {
@@ -6,11 +6,17 @@ import (
 	"github.com/flanksource/canary-checker/pkg"
 )
 
-const removedMessage = "this check type has been removed, use kubernetesResource or exec checks instead"
+const (
+	removedMessage      = "this check type has been removed, use kubernetesResource or exec checks instead"
+	removedImageMessage = "this check type has been removed, use the registry check instead"
+	removedGitMessage   = "this check type has been removed, use the git check instead"
+)
 
 type removedChecker struct {
 	typeName string
-	specFn   func(*context.Context) []external.Check
+	// message overrides removedMessage
+	message string
+	specFn  func(*context.Context) []external.Check
 }
 
 func (c *removedChecker) Type() string { return c.typeName }
@@ -25,7 +31,11 @@ func (c *removedChecker) Run(ctx *context.Context) pkg.Results {
 
 func (c *removedChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
 	result := pkg.Success(extConfig, ctx.Canary)
-	return pkg.Results{result}.Failf(removedMessage)
+	message := removedMessage
+	if c.message != "" {
+		message = c.message
+	}
+	return pkg.Results{result}.Failf("%s", message)
 }
 
 func toChecks[T external.Check](items []T) []external.Check {
@@ -51,6 +51,14 @@ func (c *DNSChecker) Check(ctx *canaryContext.Context, extConfig external.Check)
 		timeout = 10
 	}
 
+	queryType := strings.ToUpper(check.QueryType)
+	if queryType == "" {
+		queryType = "A"
+	}
+	if usesDNSExchange(check, queryType) {
+		return checkDNSServers(ctx, check, queryType, timeout, results)
+	}
+
 	var r net.Resolver
 	if check.Server != "" {
 		dialer, err := getDialer(check, timeout)
@@ -65,17 +73,16 @@ func (c *DNSChecker) Check(ctx *canaryContext.Context, extConfig external.Check)
 		r = net.Resolver{}
 	}
 
-	queryType := check.QueryType
-	if queryType == "" {
-		queryType = "A"
-	}
+	// the lookup is cancelled with the query, so that it does not outlive the check
+	queryCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
 
 	resultCh := make(chan *pkg.CheckResult, 1)
-	if fn, ok := resolvers[strings.ToUpper(queryType)]; !ok {
+	if fn, ok := resolvers[queryType]; !ok {
 		return results.Failf("unknown query type: %s", queryType)
 	} else {
 		go func() {
-			pass, message, err := fn(ctx, &r, check)
+			pass, message, err := fn(queryCtx, &r, check)
 			if err != nil {
 				result.ErrorMessage(err)
 			}
@@ -101,7 +108,7 @@ func (c *DNSChecker) Check(ctx *canaryContext.Context, extConfig external.Check)
 			res.Duration = 1
 		}
 		return results
-	case <-time.After(time.Second * time.Duration(timeout)):
+	case <-queryCtx.Done():
 		result.Duration = result.GetDuration()
 		return results.Failf("%s", fmt.Sprintf("timed out after %d seconds", timeout))
 	}
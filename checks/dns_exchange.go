@@ -0,0 +1,478 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	canaryContext "github.com/flanksource/canary-checker/api/context"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+)
+
+// dnsQueryTypes are the query types supported when querying servers directly
+var dnsQueryTypes = map[string]uint16{
+	"A":      dns.TypeA,
+	"AAAA":   dns.TypeAAAA,
+	"CNAME":  dns.TypeCNAME,
+	"SRV":    dns.TypeSRV,
+	"MX":     dns.TypeMX,
+	"PTR":    dns.TypePTR,
+	"TXT":    dns.TypeTXT,
+	"NS":     dns.TypeNS,
+	"CAA":    dns.TypeCAA,
+	"SOA":    dns.TypeSOA,
+	"DS":     dns.TypeDS,
+	"DNSKEY": dns.TypeDNSKEY,
+}
+
+// DNSCheckResult is the data of a dns check that queries servers directly
+type DNSCheckResult struct {
+	Servers []DNSServerResult `json:"servers"`
+}
+
+type DNSServerResult struct {
+	Server  string   `json:"server"`
+	RCode   string   `json:"rcode,omitempty"`
+	Answers []string `json:"answers"`
+	// Serial of the SOA of the zone, only when querying multiple servers
+	Serial uint32 `json:"serial,omitempty"`
+	// Authenticated is the AD flag of the response
+	Authenticated bool `json:"authenticated"`
+	// SignatureExpiry is the earliest expiry of the signatures of the answer
+	SignatureExpiry *time.Time `json:"signatureExpiry,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// dnsServer is a server parsed from the server or servers of a DNSCheck
+type dnsServer struct {
+	name string
+	// network is udp, tcp, tcp-tls or https
+	network string
+	// address is the host:port, or the url of DNS-over-HTTPS servers
+	address string
+	host    string
+	// authoritative servers are queried without recursion
+	authoritative bool
+	// proxy of DNS-over-HTTPS requests
+	proxy *checkProxy
+}
+
+func (s dnsServer) String() string {
+	return s.name
+}
+
+// usesDNSExchange returns whether the check has to query servers directly, net.Resolver only
+// supports plain lookups of some query types
+func usesDNSExchange(check v1.DNSCheck, queryType string) bool {
+	_, resolved := resolvers[queryType]
+	return !resolved || check.DNSSEC || check.Authoritative || len(check.Servers) > 0 || strings.Contains(check.Server, "://")
+}
+
+func checkDNSServers(ctx *canaryContext.Context, check v1.DNSCheck, queryType string, timeout int, results pkg.Results) pkg.Results {
+	result := results[0]
+	qtype, ok := dnsQueryTypes[queryType]
+	if !ok {
+		return results.Failf("unknown query type: %s", queryType)
+	}
+	if check.DNSSEC && check.Authoritative {
+		// nameservers do not validate the chain of trust, and the DS records of the parent zone are not followed
+		return results.Invalidf("dnssec cannot be combined with authoritative, only the responses of validating resolvers are trusted")
+	}
+	minValidity, err := check.MinSignatureValidity.GetDurationOr(0)
+	if err != nil {
+		return results.Invalidf("invalid minSignatureValidity %s", check.MinSignatureValidity)
+	}
+	tlsConfig := &tls.Config{}
+	if check.TLSConfig != nil {
+		if tlsConfig, err = check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace()); err != nil {
+			return results.Invalidf("invalid tls config: %v", err)
+		}
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+
+	name := check.Query
+	if qtype == dns.TypePTR {
+		if reverse, err := dns.ReverseAddr(name); err == nil {
+			name = reverse
+		}
+	}
+	name = dns.Fqdn(name)
+
+	servers := check.Servers
+	if len(servers) == 0 && !check.Authoritative {
+		servers = []string{check.Server}
+	}
+	var targets []dnsServer
+	for _, server := range servers {
+		target, err := parseDNSServer(server, check.Port)
+		if err != nil {
+			return results.Invalidf("invalid server %s: %v", server, err)
+		}
+		target.proxy = proxy
+		targets = append(targets, target)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+	if check.Authoritative {
+		resolver, err := parseDNSServer(check.Server, check.Port)
+		if err != nil {
+			return results.Invalidf("invalid server %s: %v", check.Server, err)
+		}
+		resolver.proxy = proxy
+		nameservers, err := lookupDNSNameservers(queryCtx, resolver, name, check.Port, tlsConfig)
+		if err != nil {
+			return results.Failf("failed to look up the nameservers of %s: %v", check.Query, err)
+		}
+		targets = append(targets, nameservers...)
+	}
+
+	data := DNSCheckResult{Servers: make([]DNSServerResult, len(targets))}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data.Servers[i] = queryDNSServer(queryCtx, target, name, qtype, check.DNSSEC, len(targets) > 1, tlsConfig)
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	answers := map[string][]string{}
+	serials := map[uint32][]string{}
+	for _, server := range data.Servers {
+		if server.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", server.Server, server.Error))
+			continue
+		}
+		serverCheck := check
+		serverCheck.Server = server.Server
+		if pass, message := checkResult(server.Answers, serverCheck); !pass {
+			failures = append(failures, message)
+		}
+		if expiry := server.SignatureExpiry; minValidity > 0 && expiry != nil && time.Until(*expiry) < minValidity {
+			failures = append(failures, fmt.Sprintf("%s: signature expires at %s, in less than %s", server.Server, expiry.Format(time.RFC3339), minValidity))
+		}
+		key := strings.Join(normalizeDNSAnswers(server.Answers), ", ")
+		answers[key] = append(answers[key], server.Server)
+		serials[server.Serial] = append(serials[server.Serial], server.Server)
+	}
+	if len(answers) > 1 {
+		failures = append(failures, "answers disagree: "+describeDNSDisagreement(answers, func(key string) string { return "[" + key + "]" }))
+	}
+	if len(serials) > 1 {
+		failures = append(failures, "SOA serials disagree: "+describeDNSDisagreement(serials, func(serial uint32) string { return strconv.FormatUint(uint64(serial), 10) }))
+	}
+
+	result.Duration = result.GetDuration()
+	if result.Duration == 0 {
+		// round up submillisecond response times to 1ms
+		result.Duration = 1
+	}
+	if len(failures) > 0 {
+		return results.Failf("%s", strings.Join(failures, "; "))
+	}
+	if check.ThresholdMillis > 0 && result.Duration > int64(check.ThresholdMillis) {
+		return results.Failf("%dms > %dms", result.Duration, check.ThresholdMillis)
+	}
+	return results
+}
+
+// parseDNSServer parses host[:port], udp://, tcp://, tls:// and https:// servers, an empty server is
+// the first server of the system resolver
+func parseDNSServer(server string, port int) (dnsServer, error) {
+	if server == "" {
+		config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return dnsServer{}, fmt.Errorf("failed to read the system resolver: %w", err)
+		}
+		if len(config.Servers) == 0 {
+			return dnsServer{}, fmt.Errorf("the system resolver has no servers")
+		}
+		address := net.JoinHostPort(config.Servers[0], config.Port)
+		return dnsServer{name: address, network: "udp", address: address, host: config.Servers[0]}, nil
+	}
+
+	network, host := "udp", server
+	if scheme, rest, ok := strings.Cut(server, "://"); ok {
+		switch scheme {
+		case "https":
+			u, err := url.Parse(server)
+			if err != nil {
+				return dnsServer{}, err
+			}
+			return dnsServer{name: server, network: "https", address: server, host: u.Hostname()}, nil
+		case "udp", "tcp":
+			network = scheme
+		case "tls":
+			network, port = "tcp-tls", 853
+		default:
+			return dnsServer{}, fmt.Errorf("unsupported scheme %s, expected udp, tcp, tls or https", scheme)
+		}
+		host = strings.TrimSuffix(rest, "/")
+	}
+	if port == 0 {
+		port = 53
+	}
+	address := host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	} else {
+		host = strings.Trim(host, "[]")
+		address = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if host == "" {
+		return dnsServer{}, fmt.Errorf("missing host")
+	}
+	return dnsServer{name: server, network: network, address: address, host: host}, nil
+}
+
+// lookupDNSNameservers returns the nameservers of the zone of name using the resolver
+func lookupDNSNameservers(ctx context.Context, resolver dnsServer, name string, port int, tlsConfig *tls.Config) ([]dnsServer, error) {
+	soa, err := lookupDNSSOA(ctx, resolver, name, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	response, err := resolver.exchange(ctx, newDNSQuery(soa.Hdr.Name, dns.TypeNS, resolver, false), tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		port = 53
+	}
+	var nameservers []dnsServer
+	for _, rr := range response.Answer {
+		if ns, ok := rr.(*dns.NS); ok {
+			host := strings.TrimSuffix(ns.Ns, ".")
+			address := net.JoinHostPort(host, strconv.Itoa(port))
+			nameservers = append(nameservers, dnsServer{name: host, network: "udp", address: address, host: host, authoritative: true})
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("%s has no nameservers", soa.Hdr.Name)
+	}
+	sort.Slice(nameservers, func(i, j int) bool { return nameservers[i].name < nameservers[j].name })
+	return nameservers, nil
+}
+
+// lookupDNSSOA returns the SOA of the zone of name, which is in the authority section when name is not the apex
+func lookupDNSSOA(ctx context.Context, server dnsServer, name string, tlsConfig *tls.Config) (*dns.SOA, error) {
+	response, err := server.exchange(ctx, newDNSQuery(name, dns.TypeSOA, server, false), tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range append(response.Answer, response.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa, nil
+		}
+	}
+	return nil, fmt.Errorf("no SOA for %s (%s)", name, dns.RcodeToString[response.Rcode])
+}
+
+func newDNSQuery(name string, qtype uint16, server dnsServer, dnssec bool) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.RecursionDesired = !server.authoritative
+	if dnssec {
+		msg.SetEdns0(4096, true)
+		// request the AD flag from validating resolvers
+		msg.AuthenticatedData = !server.authoritative
+	}
+	return msg
+}
+
+func queryDNSServer(ctx context.Context, server dnsServer, name string, qtype uint16, dnssec, withSerial bool, tlsConfig *tls.Config) DNSServerResult {
+	result := DNSServerResult{Server: server.String(), Answers: []string{}}
+	response, err := server.exchange(ctx, newDNSQuery(name, qtype, server, dnssec), tlsConfig)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.RCode = dns.RcodeToString[response.Rcode]
+	result.Authenticated = response.AuthenticatedData
+	if response.Rcode != dns.RcodeSuccess {
+		result.Error = fmt.Sprintf("%s returned %s", name, result.RCode)
+		return result
+	}
+
+	var records []dns.RR
+	var signatures []*dns.RRSIG
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype == qtype {
+			records = append(records, rr)
+			result.Answers = append(result.Answers, dnsRecordValue(rr))
+		} else if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == qtype {
+			signatures = append(signatures, sig)
+		}
+		if soa, ok := rr.(*dns.SOA); ok && qtype == dns.TypeSOA {
+			result.Serial = soa.Serial
+		}
+	}
+
+	if dnssec {
+		expiry, err := verifyDNSSignatures(response, records, signatures)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.SignatureExpiry = expiry
+	}
+
+	if withSerial && result.Serial == 0 {
+		soa, err := lookupDNSSOA(ctx, server, name, tlsConfig)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to get the SOA: %v", err)
+			return result
+		}
+		result.Serial = soa.Serial
+	}
+	return result
+}
+
+// verifyDNSSignatures checks that the response was authenticated by a validating resolver and that the
+// records are signed by signatures that are currently valid, returning the earliest expiry
+func verifyDNSSignatures(response *dns.Msg, records []dns.RR, signatures []*dns.RRSIG) (*time.Time, error) {
+	if !response.AuthenticatedData {
+		return nil, fmt.Errorf("response is not authenticated")
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("answer is not signed")
+	}
+
+	var expiry *time.Time
+	for _, sig := range signatures {
+		expiration := time.Unix(int64(sig.Expiration), 0).UTC()
+		if !sig.ValidityPeriod(time.Now()) {
+			return nil, fmt.Errorf("signature by %s is not valid between %s and %s", sig.SignerName,
+				time.Unix(int64(sig.Inception), 0).UTC().Format(time.RFC3339), expiration.Format(time.RFC3339))
+		}
+		if expiry == nil || expiration.Before(*expiry) {
+			expiry = &expiration
+		}
+	}
+	return expiry, nil
+}
+
+func (s dnsServer) exchange(ctx context.Context, msg *dns.Msg, tlsConfig *tls.Config) (*dns.Msg, error) {
+	if s.network == "https" {
+		return s.exchangeHTTPS(ctx, msg, tlsConfig)
+	}
+	client := &dns.Client{Net: s.network}
+	if s.network == "tcp-tls" {
+		client.TLSConfig = tlsConfig.Clone()
+		if client.TLSConfig.ServerName == "" {
+			client.TLSConfig.ServerName = s.host
+		}
+	}
+	response, _, err := client.ExchangeContext(ctx, msg, s.address)
+	if err == nil && response.Truncated && s.network == "udp" {
+		client.Net = "tcp"
+		response, _, err = client.ExchangeContext(ctx, msg, s.address)
+	}
+	return response, err
+}
+
+// exchangeHTTPS sends the query as a DNS-over-HTTPS POST request
+func (s dnsServer) exchangeHTTPS(ctx context.Context, msg *dns.Msg, tlsConfig *tls.Config) (*dns.Msg, error) {
+	query := msg.Copy()
+	// an id of 0 makes responses cacheable
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.address, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	transport := s.proxy.Transport()
+	transport.TLSClientConfig = tlsConfig
+	defer transport.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	response := new(dns.Msg)
+	if err := response.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	response.Id = msg.Id
+	return response, nil
+}
+
+// dnsRecordValue formats the record like the net.Resolver lookups, other types in their presentation format
+func dnsRecordValue(rr dns.RR) string {
+	switch record := rr.(type) {
+	case *dns.A:
+		return record.A.String()
+	case *dns.AAAA:
+		return record.AAAA.String()
+	case *dns.CNAME:
+		return record.Target
+	case *dns.NS:
+		return record.Ns
+	case *dns.PTR:
+		return record.Ptr
+	case *dns.MX:
+		return fmt.Sprintf("%s %d", record.Mx, record.Preference)
+	case *dns.TXT:
+		return strings.Join(record.Txt, "")
+	}
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+func normalizeDNSAnswers(answers []string) []string {
+	normalized := make([]string, len(answers))
+	for i, answer := range answers {
+		normalized[i] = strings.ToLower(answer)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// describeDNSDisagreement lists the servers that returned each value
+func describeDNSDisagreement[K comparable](servers map[K][]string, format func(K) string) string {
+	var groups []string
+	for value, names := range servers {
+		sort.Strings(names)
+		groups = append(groups, fmt.Sprintf("%s from %s", format(value), strings.Join(names, ", ")))
+	}
+	sort.Strings(groups)
+	return strings.Join(groups, " vs ")
+}
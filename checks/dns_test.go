@@ -0,0 +1,283 @@
+package checks
+
+import (
+	"crypto"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// testDNSZone serves example.com, signing answers with key when DNSSEC is requested
+type testDNSZone struct {
+	mu      sync.Mutex
+	serial  uint32
+	address string
+	// authenticated sets the AD flag of signed responses to recursive queries
+	authenticated bool
+	// validity of the signatures
+	validity time.Duration
+	key      *dns.DNSKEY
+	signer   crypto.Signer
+}
+
+func newTestDNSZone(t *testing.T, serial uint32, address string) *testDNSZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 300},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	private, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testDNSZone{serial: serial, address: address, authenticated: true, validity: 24 * time.Hour, key: key, signer: private.(crypto.Signer)}
+}
+
+func (z *testDNSZone) update(fn func(zone *testDNSZone)) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	fn(z)
+}
+
+func (z *testDNSZone) answer(req *dns.Msg) *dns.Msg {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	reply := new(dns.Msg)
+	reply.SetReply(req)
+	question := req.Question[0]
+	records := []string{
+		fmt.Sprintf("example.com. 300 IN SOA localhost. hostmaster.example.com. %d 3600 600 86400 300", z.serial),
+		"example.com. 300 IN NS 127.0.0.1.",
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org"`,
+		"www.example.com. 300 IN A " + z.address,
+		"www.example.com. 300 IN AAAA 2001:db8::1",
+		z.key.String(),
+	}
+	exists := false
+	for _, record := range records {
+		rr, _ := dns.NewRR(record)
+		if strings.EqualFold(rr.Header().Name, question.Name) {
+			exists = true
+			if rr.Header().Rrtype == question.Qtype {
+				reply.Answer = append(reply.Answer, rr)
+			}
+		}
+	}
+	if !exists {
+		reply.Rcode = dns.RcodeNameError
+	}
+	if len(reply.Answer) == 0 {
+		soa, _ := dns.NewRR(records[0])
+		reply.Ns = append(reply.Ns, soa)
+	}
+
+	if opt := req.IsEdns0(); opt != nil && opt.Do() && len(reply.Answer) > 0 {
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: question.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 300},
+			KeyTag:     z.key.KeyTag(),
+			SignerName: "example.com.",
+			Algorithm:  z.key.Algorithm,
+			Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+			Expiration: uint32(time.Now().Add(z.validity).Unix()),
+		}
+		if err := sig.Sign(z.signer, reply.Answer); err != nil {
+			reply.Rcode = dns.RcodeServerFailure
+		}
+		reply.Answer = append(reply.Answer, sig)
+		reply.AuthenticatedData = z.authenticated && req.RecursionDesired
+		reply.SetEdns0(4096, true)
+	}
+	return reply
+}
+
+func (z *testDNSZone) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	_ = w.WriteMsg(z.answer(req))
+}
+
+// newTestDNSServer serves the zone over udp, or over tcp or tls with a listener
+func newTestDNSServer(t *testing.T, zone *testDNSZone, listener net.Listener) string {
+	server := &dns.Server{Handler: zone}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	var addr net.Addr
+	if listener != nil {
+		server.Listener, addr = listener, listener.Addr()
+	} else {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.PacketConn, addr = conn, conn.LocalAddr()
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return addr.String()
+}
+
+func newTestDNSCheck(server, queryType, query string, reply ...string) v1.DNSCheck {
+	return v1.DNSCheck{
+		Description: v1.Description{Name: "dns"},
+		Server:      server,
+		Query:       query,
+		QueryType:   queryType,
+		MinRecords:  1,
+		ExactReply:  reply,
+		Timeout:     2,
+	}
+}
+
+func TestDNSCheckerRecordTypes(t *testing.T) {
+	zone := newTestDNSZone(t, 7, "192.0.2.1")
+	udp := newTestDNSServer(t, zone, nil)
+	host, port, _ := net.SplitHostPort(udp)
+
+	tlsServer, ca := newTLSTestServer(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsServer.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := newTestDNSServer(t, zone, listener)
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" || req.Unpack(body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		packed, _ := zone.answer(req).Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(packed)
+	}))
+	t.Cleanup(doh.Close)
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp := newTestDNSServer(t, zone, listener)
+
+	checks := map[string]v1.DNSCheck{
+		"AAAA":   newTestDNSCheck(host, "AAAA", "www.example.com", "2001:db8::1"),
+		"CAA":    newTestDNSCheck(host, "caa", "example.com", `0 issue "letsencrypt.org"`),
+		"SOA":    newTestDNSCheck(host, "SOA", "example.com", "localhost. hostmaster.example.com. 7 3600 600 86400 300"),
+		"DNSKEY": newTestDNSCheck(host, "DNSKEY", "example.com", strings.TrimPrefix(zone.key.String(), zone.key.Hdr.String())),
+		"tcp":    newTestDNSCheck("tcp://"+tcp, "A", "www.example.com", "192.0.2.1"),
+		"tls":    newTestDNSCheck("tls://"+dot, "A", "www.example.com", "192.0.2.1"),
+		"https":  newTestDNSCheck(doh.URL+"/dns-query", "AAAA", "www.example.com", "2001:db8::1"),
+	}
+	for name, check := range checks {
+		check.Port, _ = strconv.Atoi(port)
+		check.TLSConfig = &v1.TLSConfig{CA: ca}
+		results := (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+		if !results[0].Pass {
+			t.Errorf("%s: expected the check to pass, got %s", name, results[0].Error)
+		}
+	}
+
+	check := checks["tls"]
+	check.TLSConfig = &v1.TLSConfig{CA: ca}
+	results := (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	if servers := results[0].Data["servers"].([]any); len(servers) != 1 || servers[0].(map[string]any)["rcode"] != "NOERROR" {
+		t.Errorf("expected the response of the server, got %v", servers)
+	}
+	check.TLSConfig = nil
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "certificate") {
+		t.Errorf("expected the certificate to be untrusted, got %s", results[0].Error)
+	}
+
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), newTestDNSCheck(udp, "AAAA", "missing.example.com"))
+	if results[0].Pass || results[0].Error != udp+": missing.example.com. returned NXDOMAIN" {
+		t.Errorf("expected the name to not exist, got %s", results[0].Error)
+	}
+}
+
+func TestDNSCheckerServers(t *testing.T) {
+	primary := newTestDNSServer(t, newTestDNSZone(t, 2, "192.0.2.1"), nil)
+	secondary := newTestDNSServer(t, newTestDNSZone(t, 2, "192.0.2.1"), nil)
+	stale := newTestDNSServer(t, newTestDNSZone(t, 1, "192.0.2.2"), nil)
+
+	check := newTestDNSCheck("", "A", "www.example.com")
+	check.Servers = []string{primary, secondary}
+	results := (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the servers to agree, got %s", results[0].Error)
+	}
+	for _, server := range results[0].Data["servers"].([]any) {
+		if server := server.(map[string]any); server["serial"] != float64(2) || fmt.Sprint(server["answers"]) != "[192.0.2.1]" {
+			t.Errorf("expected serial 2 and the address, got %v", server)
+		}
+	}
+
+	check.Servers = append(check.Servers, stale)
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	expected := fmt.Sprintf("answers disagree: [192.0.2.1] from %s, %s vs [192.0.2.2] from %s; SOA serials disagree: 1 from %s vs 2 from %s, %s",
+		primary, secondary, stale, stale, primary, secondary)
+	if primary > secondary {
+		expected = fmt.Sprintf("answers disagree: [192.0.2.1] from %s, %s vs [192.0.2.2] from %s; SOA serials disagree: 1 from %s vs 2 from %s, %s",
+			secondary, primary, stale, stale, secondary, primary)
+	}
+	if results[0].Pass || results[0].Error != expected {
+		t.Errorf("expected the stale server to disagree, got %s", results[0].Error)
+	}
+}
+
+func TestDNSCheckerDNSSEC(t *testing.T) {
+	zone := newTestDNSZone(t, 1, "192.0.2.1")
+	server := newTestDNSServer(t, zone, nil)
+	host, port, _ := net.SplitHostPort(server)
+
+	check := newTestDNSCheck(server, "A", "www.example.com", "192.0.2.1")
+	check.DNSSEC = true
+	results := (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	resolved := results[0].Data["servers"].([]any)[0].(map[string]any)
+	if resolved["authenticated"] != true || resolved["signatureExpiry"] == nil {
+		t.Errorf("expected an authenticated response with the signature expiry, got %v", resolved)
+	}
+
+	check.MinSignatureValidity = "48h"
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "in less than 48h0m0s") {
+		t.Errorf("expected the signature to expire too soon, got %s", results[0].Error)
+	}
+
+	check.MinSignatureValidity = ""
+	zone.update(func(zone *testDNSZone) { zone.authenticated = false })
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != server+": response is not authenticated" {
+		t.Errorf("expected the response to not be authenticated, got %s", results[0].Error)
+	}
+
+	zone.update(func(zone *testDNSZone) { zone.authenticated, zone.validity = true, -time.Minute })
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "signature by example.com. is not valid between") {
+		t.Errorf("expected the signature to be expired, got %s", results[0].Error)
+	}
+
+	// the nameservers of the zone do not validate the chain of trust of their answers
+	check.Server, check.Authoritative = host, true
+	check.Port, _ = strconv.Atoi(port)
+	results = (&DNSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Invalid {
+		t.Errorf("expected dnssec to be rejected for authoritative queries, got %s", results[0].Error)
+	}
+}
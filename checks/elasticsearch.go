@@ -45,6 +45,14 @@ func (c *ElasticsearchChecker) Check(ctx *context.Context, extConfig external.Ch
 		Password:  connection.Password,
 	}
 
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	if proxy != nil {
+		cfg.Transport = proxy.Transport()
+	}
+
 	es, err := elasticsearch.NewClient(cfg)
 	if err != nil {
 		return results.ErrorMessage(err)
@@ -0,0 +1,63 @@
+package checks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/flanksource/gomplate/v3"
+)
+
+// responseExpectation is a regex and a CEL expression that a response must both match
+type responseExpectation struct {
+	regex *regexp.Regexp
+	expr  string
+}
+
+func newResponseExpectation(regex, expr string) (*responseExpectation, error) {
+	if regex == "" && expr == "" {
+		return nil, errors.New("expect requires a regex or expr")
+	}
+	expectation := &responseExpectation{expr: expr}
+	if regex != "" {
+		var err error
+		if expectation.regex, err = regexp.Compile(regex); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return expectation, nil
+}
+
+// match evaluates the expression with env, and the response parsed as json when it is JSON
+func (e *responseExpectation) match(response []byte, env map[string]any) (bool, error) {
+	if e.regex != nil && !e.regex.Match(response) {
+		return false, nil
+	}
+	if e.expr == "" {
+		return true, nil
+	}
+	var value any
+	if json.Unmarshal(response, &value) == nil {
+		env["json"] = value
+	}
+	output, err := gomplate.RunTemplate(env, gomplate.Template{Expression: e.expr})
+	if err != nil {
+		return false, err
+	}
+	matched, err := strconv.ParseBool(output)
+	if err != nil {
+		return false, fmt.Errorf("%s returned %q instead of a boolean", e.expr, output)
+	}
+	return matched, nil
+}
+
+func (e *responseExpectation) String() string {
+	if e.regex != nil && e.expr != "" {
+		return fmt.Sprintf("/%s/ and %s", e.regex, e.expr)
+	} else if e.regex != nil {
+		return fmt.Sprintf("/%s/", e.regex)
+	}
+	return e.expr
+}
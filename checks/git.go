@@ -0,0 +1,457 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitHTTP "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitSSH "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+	"github.com/flanksource/duty/types"
+)
+
+var gitCommitAge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "canary_check_git_commit_age",
+		Help: "The number of hours since the last commit on the branch",
+	},
+	[]string{"url", "branch"},
+)
+
+func init() {
+	prometheus.MustRegister(gitCommitAge)
+}
+
+type GitChecker struct{}
+
+// Type: returns checker type
+func (c *GitChecker) Type() string {
+	return "git"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *GitChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.Git {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// GitCheckResult is the data of a git check
+type GitCheckResult struct {
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+	// Heads are the commits of the branches of the remote
+	Heads map[string]string `json:"heads"`
+	// Commit is the last commit on the branch, it is only read when cloning
+	Commit *GitCommit `json:"commit,omitempty"`
+	// Pushed is the commit that was pushed to the scratch branch
+	Pushed string `json:"pushed,omitempty"`
+}
+
+type GitCommit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	// AgeHours is the number of hours since the commit
+	AgeHours float64 `json:"ageHours"`
+}
+
+func (c *GitChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.GitCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	remote, err := newGitRemote(ctx, check)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+
+	var maxAge time.Duration
+	if check.MaxAge != "" {
+		if maxAge, err = check.MaxAge.GetDurationOrZero(); err != nil {
+			return results.Invalidf("invalid maxAge %s: %v", check.MaxAge, err)
+		}
+	}
+
+	var pushBranch string
+	if check.Push != nil {
+		if pushBranch = check.Push.Branch; pushBranch == "" {
+			pushBranch = "canary-checker/" + ctx.Canary.Name
+		}
+		if err := plumbing.NewBranchReferenceName(pushBranch).Validate(); err != nil {
+			return results.Invalidf("invalid push branch %s: %v", pushBranch, err)
+		}
+	}
+
+	start := time.Now()
+
+	refs, err := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{remote.url},
+	}).ListContext(ctx, remote.listOptions())
+	if err != nil {
+		return results.Failf("failed to list refs of %s: %v", remote.endpoint, err)
+	}
+
+	data := GitCheckResult{URL: remote.endpoint, Branch: check.Branch, Heads: gitHeads(refs)}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+
+	if data.Branch == "" {
+		if data.Branch = gitDefaultBranch(refs); data.Branch == "" {
+			return results.Failf("failed to find the default branch of %s", remote.endpoint)
+		}
+	}
+	head, ok := data.Heads[data.Branch]
+	if !ok {
+		return results.Failf("branch %s not found", data.Branch)
+	}
+	if data.Branch == pushBranch {
+		return results.Invalidf("the push branch must not be the branch that is checked")
+	}
+
+	if check.Clone || maxAge > 0 || check.Push != nil {
+		repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, remote.cloneOptions(data.Branch))
+		if err != nil {
+			return results.Failf("failed to clone %s: %v", remote.endpoint, err)
+		}
+		commit, err := repo.CommitObject(plumbing.NewHash(head))
+		if err != nil {
+			return results.Failf("failed to read commit %s: %v", head, err)
+		}
+
+		age := time.Since(commit.Committer.When)
+		data.Commit = &GitCommit{
+			Hash:     commit.Hash.String(),
+			Author:   commit.Author.Name,
+			Email:    commit.Author.Email,
+			Message:  strings.TrimSpace(commit.Message),
+			Time:     commit.Committer.When,
+			AgeHours: age.Hours(),
+		}
+		gitCommitAge.WithLabelValues(remote.endpoint, data.Branch).Set(age.Hours())
+		if maxAge > 0 && age > maxAge {
+			return results.Failf("the last commit on %s is %s old, older than %s", data.Branch, utils.Age(age), check.MaxAge)
+		}
+
+		if check.Push != nil {
+			pushed, err := gitPushEmptyCommit(ctx, repo, remote, commit, pushBranch)
+			if err != nil {
+				return results.Failf("failed to push to %s: %v", pushBranch, err)
+			}
+			data.Pushed = pushed.String()
+
+			if !check.Push.SkipDelete {
+				if err := repo.PushContext(ctx, remote.pushOptions(config.RefSpec(":"+plumbing.NewBranchReferenceName(pushBranch)))); err != nil {
+					return results.Failf("failed to delete %s: %v", pushBranch, err)
+				}
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// gitRemote is the resolved url and credentials of a git check
+type gitRemote struct {
+	url string
+	// endpoint is the url without credentials
+	endpoint        string
+	auth            transport.AuthMethod
+	proxy           transport.ProxyOptions
+	insecureSkipTLS bool
+	caBundle        []byte
+	clientCert      []byte
+	clientKey       []byte
+}
+
+func (r gitRemote) listOptions() *git.ListOptions {
+	return &git.ListOptions{
+		Auth:            r.auth,
+		ProxyOptions:    r.proxy,
+		InsecureSkipTLS: r.insecureSkipTLS,
+		CABundle:        r.caBundle,
+		ClientCert:      r.clientCert,
+		ClientKey:       r.clientKey,
+	}
+}
+
+func (r gitRemote) cloneOptions(branch string) *git.CloneOptions {
+	return &git.CloneOptions{
+		URL:             r.url,
+		Auth:            r.auth,
+		ReferenceName:   plumbing.NewBranchReferenceName(branch),
+		SingleBranch:    true,
+		NoCheckout:      true,
+		Depth:           1,
+		Tags:            git.NoTags,
+		ProxyOptions:    r.proxy,
+		InsecureSkipTLS: r.insecureSkipTLS,
+		CABundle:        r.caBundle,
+		ClientCert:      r.clientCert,
+		ClientKey:       r.clientKey,
+	}
+}
+
+func (r gitRemote) pushOptions(refSpecs ...config.RefSpec) *git.PushOptions {
+	return &git.PushOptions{
+		RemoteName:      git.DefaultRemoteName,
+		RefSpecs:        refSpecs,
+		Auth:            r.auth,
+		ProxyOptions:    r.proxy,
+		InsecureSkipTLS: r.insecureSkipTLS,
+		CABundle:        r.caBundle,
+		ClientCert:      r.clientCert,
+		ClientKey:       r.clientKey,
+	}
+}
+
+// newGitRemote resolves the url and credentials of the check, the url and credentials of the connection
+// are overridden by those of the check
+func newGitRemote(ctx *context.Context, check v1.GitCheck) (*gitRemote, error) {
+	var username, password, key string
+	remote := &gitRemote{url: check.URL}
+
+	connection, err := ctx.HydrateConnectionByURL(check.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("error getting connection: %w", err)
+	}
+	if connection != nil {
+		if remote.url == "" {
+			remote.url = connection.URL
+		}
+		username, password, key = connection.Username, connection.Password, connection.Certificate
+	}
+	if check.Username != nil {
+		value, err := ctx.GetEnvValueFromCache(*check.Username, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get username: %w", err)
+		} else if value != "" {
+			username = value
+		}
+	}
+	if check.Password != nil {
+		value, err := ctx.GetEnvValueFromCache(*check.Password, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get password: %w", err)
+		} else if value != "" {
+			password = value
+		}
+	}
+	if check.Certificate != nil {
+		value, err := ctx.GetEnvValueFromCache(*check.Certificate, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get certificate: %w", err)
+		} else if value != "" {
+			key = value
+		}
+	}
+
+	if remote.url == "" {
+		return nil, errors.New("a url or connection is required")
+	}
+	endpoint, err := transport.NewEndpoint(remote.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", remote.url, err)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		if username != "" || password != "" {
+			if username == "" {
+				// tokens are accepted with any username
+				username = "git"
+			}
+			remote.auth = &gitHTTP.BasicAuth{Username: username, Password: password}
+		}
+	case "ssh":
+		if username == "" {
+			if username = endpoint.User; username == "" {
+				username = "git"
+			}
+		}
+		if remote.auth, err = newGitSSHAuth(ctx, check, username, password, key); err != nil {
+			return nil, err
+		}
+	case "file":
+		// local repositories need no authentication
+	default:
+		return nil, fmt.Errorf("unsupported protocol %s, expected https, ssh or file", endpoint.Protocol)
+	}
+
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	if endpoint.Protocol == "http" || endpoint.Protocol == "https" {
+		if proxyURL := proxy.ForURL(remote.url); proxyURL != nil {
+			remote.proxy.URL = proxyURL.String()
+		}
+	} else if endpoint.Protocol == "ssh" {
+		if proxyURL := proxy.ForAddress(net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))); proxyURL != nil {
+			remote.proxy.URL = proxyURL.String()
+		}
+	}
+
+	if check.TLSConfig != nil {
+		remote.insecureSkipTLS = check.TLSConfig.InsecureSkipVerify
+		for _, v := range []struct {
+			name  string
+			value types.EnvVar
+			out   *[]byte
+		}{
+			{"ca", check.TLSConfig.CA, &remote.caBundle},
+			{"cert", check.TLSConfig.Cert, &remote.clientCert},
+			{"key", check.TLSConfig.Key, &remote.clientKey},
+		} {
+			value, err := ctx.GetEnvValueFromCache(v.value, ctx.GetNamespace())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get tls %s: %w", v.name, err)
+			}
+			if value != "" {
+				*v.out = []byte(value)
+			}
+		}
+	}
+
+	endpoint.User, endpoint.Password = "", ""
+	remote.endpoint = endpoint.String()
+	return remote, nil
+}
+
+// newGitSSHAuth authenticates with the private key if there is one, or else the password
+func newGitSSHAuth(ctx *context.Context, check v1.GitCheck, username, password, key string) (gitSSH.AuthMethod, error) {
+	var auth gitSSH.AuthMethod
+	var err error
+	if key != "" {
+		if auth, err = gitSSH.NewPublicKeys(username, []byte(key), password); err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+	} else {
+		auth = &gitSSH.Password{User: username, Password: password}
+	}
+
+	if check.KnownHosts == nil {
+		return auth, nil
+	}
+	knownHosts, err := ctx.GetEnvValueFromCache(*check.KnownHosts, ctx.GetNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get known hosts: %w", err)
+	}
+	if knownHosts == "" {
+		return auth, nil
+	}
+	callback, err := knownHostsCallback(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+	switch auth := auth.(type) {
+	case *gitSSH.PublicKeys:
+		auth.HostKeyCallback = callback
+	case *gitSSH.Password:
+		auth.HostKeyCallback = callback
+	}
+	return auth, nil
+}
+
+// gitHeads returns the commits of the branches in refs
+func gitHeads(refs []*plumbing.Reference) map[string]string {
+	heads := map[string]string{}
+	for _, ref := range refs {
+		if ref.Name().IsBranch() && ref.Type() == plumbing.HashReference {
+			heads[ref.Name().Short()] = ref.Hash().String()
+		}
+	}
+	return heads
+}
+
+// gitDefaultBranch returns the branch HEAD points to, falling back to the first branch at the same
+// commit for servers that do not advertise the symref
+func gitDefaultBranch(refs []*plumbing.Reference) string {
+	var head *plumbing.Reference
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+		}
+	}
+	if head == nil {
+		return ""
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short()
+	}
+	var branches []string
+	for _, ref := range refs {
+		if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+			branches = append(branches, ref.Name().Short())
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		for _, branch := range branches {
+			if branch == name {
+				return branch
+			}
+		}
+	}
+	if len(branches) > 0 {
+		return branches[0]
+	}
+	return ""
+}
+
+// gitPushEmptyCommit force pushes an empty commit on top of parent to the branch
+func gitPushEmptyCommit(ctx *context.Context, repo *git.Repository, remote *gitRemote, parent *object.Commit, branch string) (plumbing.Hash, error) {
+	signature := object.Signature{Name: "canary-checker", Email: "canary-checker@flanksource.com", When: time.Now()}
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      fmt.Sprintf("canary-checker push check for %s", ctx.Canary.Name),
+		TreeHash:     parent.TreeHash,
+		ParentHashes: []plumbing.Hash{parent.Hash},
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), hash)
+	if err := repo.Storer.SetReference(ref); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := repo.PushContext(ctx, remote.pushOptions(config.RefSpec(fmt.Sprintf("+%s:%s", ref.Name(), ref.Name())))); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return hash, nil
+}
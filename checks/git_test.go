@@ -0,0 +1,137 @@
+package checks
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// newTestGitRepo creates a repository with a commit on master and on a stale branch, the file
+// transport needs the git binary
+func newTestGitRepo(t *testing.T) (string, *git.Repository) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(author string, when time.Time) {
+		if _, err := worktree.Commit("test", &git.CommitOptions{
+			AllowEmptyCommits: true,
+			Author:            &object.Signature{Name: author, Email: author + "@example.com", When: when},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("stale", time.Now().Add(-72*time.Hour))
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("stale"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+		t.Fatal(err)
+	}
+	commit("alice", time.Now().Add(-time.Hour))
+	return dir, repo
+}
+
+func TestGitChecker(t *testing.T) {
+	dir, _ := newTestGitRepo(t)
+	ctx := newRetryTestContext(nil)
+	ctx.Canary.Name = "git"
+
+	check := v1.GitCheck{Description: v1.Description{Name: "git"}, Clone: true}
+	check.URL = "file://" + dir
+	results := (&GitChecker{}).Check(ctx, check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["branch"] != "master" {
+		t.Errorf("expected the default branch to be master, got %v", data["branch"])
+	}
+	if heads := data["heads"].(map[string]any); len(heads) != 2 || heads["stale"] == nil {
+		t.Errorf("expected the master and stale heads, got %v", heads)
+	}
+	if commit := data["commit"].(map[string]any); commit["author"] != "alice" {
+		t.Errorf("expected the last commit to be by alice, got %v", commit)
+	}
+
+	check.Branch = "stale"
+	check.MaxAge = "24h"
+	results = (&GitChecker{}).Check(ctx, check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "older than 24h") {
+		t.Errorf("expected the stale branch to fail, got %s", results[0].Error)
+	}
+
+	check.Branch = "missing"
+	results = (&GitChecker{}).Check(ctx, check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "branch missing not found") {
+		t.Errorf("expected a missing branch to fail, got %s", results[0].Error)
+	}
+}
+
+func TestGitCheckerPush(t *testing.T) {
+	dir, repo := newTestGitRepo(t)
+	ctx := newRetryTestContext(nil)
+	ctx.Canary.Name = "git"
+
+	check := v1.GitCheck{Description: v1.Description{Name: "git"}, Push: &v1.GitPush{SkipDelete: true}}
+	check.URL = "file://" + dir
+	results := (&GitChecker{}).Check(ctx, check)
+	if !results[0].Pass {
+		t.Fatalf("expected the push to pass, got %s", results[0].Error)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("canary-checker/git"), true)
+	if err != nil || ref.Hash().String() != results[0].Data["pushed"] {
+		t.Fatalf("expected canary-checker/git to be pushed, got %v, %v", ref, err)
+	}
+
+	// the scratch branch is force pushed and then deleted
+	check.Push.SkipDelete = false
+	results = (&GitChecker{}).Check(ctx, check)
+	if !results[0].Pass {
+		t.Fatalf("expected the push to pass, got %s", results[0].Error)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName("canary-checker/git"), true); err != plumbing.ErrReferenceNotFound {
+		t.Errorf("expected canary-checker/git to be deleted, got %v", err)
+	}
+
+	check.Push.Branch = "master"
+	if results = (&GitChecker{}).Check(ctx, check); results[0].Pass {
+		t.Error("expected pushing to the checked branch to be rejected")
+	}
+}
+
+func TestGitDefaultBranch(t *testing.T) {
+	hash := plumbing.NewHash("058b5c57d6fe1497a7c57f325129fcc0d5a7a4c5")
+	other := plumbing.NewHash("91cb6ed09d56fedaad8a7b28a78c684cce260f54")
+	refs := []*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/feature", hash),
+		plumbing.NewHashReference("refs/heads/main", hash),
+		plumbing.NewHashReference("refs/heads/release", other),
+	}
+
+	tests := map[string]*plumbing.Reference{
+		"release": plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/release"),
+		"main":    plumbing.NewHashReference(plumbing.HEAD, hash),
+		"":        plumbing.NewHashReference(plumbing.HEAD, plumbing.ZeroHash),
+	}
+	for expected, head := range tests {
+		if branch := gitDefaultBranch(append(refs, head)); branch != expected {
+			t.Errorf("gitDefaultBranch(%s) = %s, expected %s", head, branch, expected)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package checks
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+type GRPCChecker struct{}
+
+// Type: returns checker type
+func (c *GRPCChecker) Type() string {
+	return "grpc"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *GRPCChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.GRPC {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+func (c *GRPCChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.GRPCCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	host, _, err := net.SplitHostPort(check.Endpoint)
+	if err != nil {
+		return results.Invalidf("invalid endpoint %s, expected host:port: %v", check.Endpoint, err)
+	}
+	pinned, err := pinnedAddresses(check.Resolve, host)
+	if err != nil {
+		return results.Invalidf("invalid resolve: %v", err)
+	}
+	if len(pinned) > 1 {
+		return checkEachAddress(check.GetName(), pinned, func(name, addr string) pkg.Results {
+			pinnedCheck := check
+			pinnedCheck.Name = name
+			pinnedCheck.Resolve = pinToAddress(check.Resolve, host, addr)
+			return c.Check(ctx, pinnedCheck)
+		})
+	}
+
+	creds := insecure.NewCredentials()
+	if check.TLSConfig.Enabled() {
+		tlsConfig, err := check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("invalid tls config: %v", err)
+		}
+		tlsConfig.MinVersion = tls.VersionTLS12
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	md := metadata.MD{}
+	for _, header := range check.Headers {
+		value, err := ctx.GetEnvValueFromCache(header, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("failed to get header %s: %v", header.Name, err)
+		}
+		md.Append(header.Name, value)
+	}
+	callCtx := metadata.NewOutgoingContext(ctx, md)
+
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	target := check.Endpoint
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if proxy.ForAddress(check.Endpoint) != nil || len(pinned) > 0 {
+		// passthrough hands the unresolved host:port to the dialer, so that it is pinned or resolved by the proxy,
+		// while the authority and TLS server name remain the original host
+		target = "passthrough:///" + check.Endpoint
+		opts = append(opts, grpc.WithContextDialer(func(ctx gocontext.Context, addr string) (net.Conn, error) {
+			return proxy.DialContext(ctx, "tcp", pinHostPort(check.Resolve, addr))
+		}))
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return results.Failf("failed to create client: %v", err)
+	}
+	defer conn.Close()
+
+	var header, trailer metadata.MD
+	start := time.Now()
+	response, err := grpc_health_v1.NewHealthClient(conn).Check(callCtx,
+		&grpc_health_v1.HealthCheckRequest{Service: check.Service},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+
+	data := map[string]interface{}{
+		"code":     status.Code(err).String(),
+		"status":   response.GetStatus().String(),
+		"latency":  elapsed.Milliseconds(),
+		"metadata": grpcMetadata(header, trailer),
+	}
+	result.AddData(data)
+
+	if err != nil {
+		return results.Failf("health check failed: %s", status.Convert(err).Message())
+	}
+
+	if check.Reflection {
+		services, err := listGRPCServices(callCtx, conn)
+		if err != nil {
+			return results.Failf("failed to list services: %v", err)
+		}
+		result.AddData(map[string]interface{}{"services": services})
+	}
+
+	if response.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return results.Failf("expected %s, got %s", grpc_health_v1.HealthCheckResponse_SERVING, response.GetStatus())
+	}
+
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+
+	return results
+}
+
+// grpcMetadata merges the response headers and trailers, joining multiple values of a key
+func grpcMetadata(mds ...metadata.MD) map[string]string {
+	out := make(map[string]string)
+	for _, md := range mds {
+		for k, v := range md {
+			if existing, ok := out[k]; ok {
+				v = append([]string{existing}, v...)
+			}
+			out[k] = strings.Join(v, ",")
+		}
+	}
+	return out
+}
+
+// listGRPCServices lists the services of the server using the v1 reflection API,
+// falling back to v1alpha for servers that do not implement v1 yet
+func listGRPCServices(ctx gocontext.Context, conn *grpc.ClientConn) ([]string, error) {
+	services, err := listGRPCServicesV1(ctx, conn)
+	if status.Code(err) == codes.Unimplemented {
+		return listGRPCServicesV1Alpha(ctx, conn)
+	}
+	return services, err
+}
+
+func listGRPCServicesV1(ctx gocontext.Context, conn *grpc.ClientConn) ([]string, error) {
+	stream, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend() //nolint:errcheck
+
+	if err := stream.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := response.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("%s", e.GetErrorMessage())
+	}
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+//nolint:staticcheck
+func listGRPCServicesV1Alpha(ctx gocontext.Context, conn *grpc.ClientConn) ([]string, error) {
+	stream, err := grpc_reflection_v1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend() //nolint:errcheck
+
+	if err := stream.Send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := response.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("%s", e.GetErrorMessage())
+	}
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	sort.Strings(services)
+	return services, nil
+}
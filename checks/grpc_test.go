@@ -0,0 +1,113 @@
+package checks
+
+import (
+	gocontext "context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+// startGRPCTestServer starts a server with the health and reflection services that echoes
+// the x-token request metadata back as the x-echo response header
+func startGRPCTestServer(t *testing.T) (string, *health.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx gocontext.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-token")) > 0 {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("x-echo", md.Get("x-token")[0]))
+		}
+		return handler(ctx, req)
+	}))
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), healthServer
+}
+
+func TestGRPCCheckerServing(t *testing.T) {
+	endpoint, _ := startGRPCTestServer(t)
+
+	check := v1.GRPCCheck{
+		Description: v1.Description{Name: "grpc"},
+		Endpoint:    endpoint,
+		Reflection:  true,
+		Headers:     []types.EnvVar{{Name: "x-token", ValueStatic: "secret"}},
+	}
+
+	results := (&GRPCChecker{}).Check(newRetryTestContext(nil), check)
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+	result := results[0]
+	if !result.Pass {
+		t.Fatalf("expected check to pass, got error: %s", result.Error)
+	}
+	if result.Data["status"] != "SERVING" || result.Data["code"] != "OK" {
+		t.Fatalf("unexpected status %v and code %v", result.Data["status"], result.Data["code"])
+	}
+	if md := result.Data["metadata"].(map[string]string); md["x-echo"] != "secret" {
+		t.Fatalf("expected the x-token header to be sent as metadata, got %v", md)
+	}
+	services, _ := result.Data["services"].([]string)
+	found := false
+	for _, service := range services {
+		found = found || service == grpc_health_v1.Health_ServiceDesc.ServiceName
+	}
+	if !found {
+		t.Fatalf("expected reflection to list the health service, got %v", services)
+	}
+}
+
+func TestGRPCCheckerNotServing(t *testing.T) {
+	endpoint, healthServer := startGRPCTestServer(t)
+	healthServer.SetServingStatus("orders", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	check := v1.GRPCCheck{
+		Description: v1.Description{Name: "grpc"},
+		Endpoint:    endpoint,
+		Service:     "orders",
+	}
+
+	results := (&GRPCChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass {
+		t.Fatalf("expected check to fail for a service that is not serving")
+	}
+	if results[0].Data["status"] != "NOT_SERVING" {
+		t.Fatalf("expected NOT_SERVING status, got %v", results[0].Data["status"])
+	}
+}
+
+func TestGRPCCheckerUnknownService(t *testing.T) {
+	endpoint, _ := startGRPCTestServer(t)
+
+	check := v1.GRPCCheck{
+		Description: v1.Description{Name: "grpc"},
+		Endpoint:    endpoint,
+		Service:     "missing",
+	}
+
+	results := (&GRPCChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass {
+		t.Fatalf("expected check to fail for an unknown service")
+	}
+	if results[0].Data["code"] != "NotFound" {
+		t.Fatalf("expected NotFound code, got %v", results[0].Data["code"])
+	}
+}
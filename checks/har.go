@@ -0,0 +1,433 @@
+package checks
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/cookiejar"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/har"
+	"github.com/flanksource/commons/http"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/runner"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+// harSkippedHeaders are recorded request headers that are not replayed, either because they are
+// set by the http client (or the cookie jar) or because they are HTTP/2 pseudo headers
+var harSkippedHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Cookie":            true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+}
+
+type HARChecker struct{}
+
+// Type: returns checker type
+func (c *HARChecker) Type() string {
+	return "har"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *HARChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.HAR {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// HARReplayResult is the outcome of replaying a single recorded request
+type HARReplayResult struct {
+	Method         string `json:"method"`
+	URL            string `json:"url"`
+	Status         int    `json:"status,omitempty"`
+	ExpectedStatus int    `json:"expectedStatus"`
+	Duration       int64  `json:"duration"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (c *HARChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.HARCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	switch check.StatusMatch {
+	case "", "exact", "class":
+	default:
+		return results.Invalidf("invalid statusMatch %s, expected exact or class", check.StatusMatch)
+	}
+	switch check.BodyMatch {
+	case "", "none", "exact", "json", "size":
+	default:
+		return results.Invalidf("invalid bodyMatch %s, expected one of none, exact, json or size", check.BodyMatch)
+	}
+
+	entries, err := loadHAR(ctx, check)
+	if err != nil {
+		return results.Invalidf("failed to load har: %v", err)
+	}
+
+	var excludes []*regexp.Regexp
+	for _, exclude := range check.Exclude {
+		re, err := regexp.Compile(exclude)
+		if err != nil {
+			return results.Invalidf("invalid exclude regex %s: %v", exclude, err)
+		}
+		excludes = append(excludes, re)
+	}
+
+	hosts := make(map[string]string, len(check.Hosts))
+	for host, replacement := range check.Hosts {
+		if hosts[host], err = template(ctx, v1.Template{Template: replacement}); err != nil {
+			return results.Invalidf("failed to template host %s: %v", host, err)
+		}
+	}
+
+	headers := make(map[string]string, len(check.Headers))
+	for _, header := range check.Headers {
+		value, err := ctx.GetEnvValueFromCache(header, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("failed to get header %s: %v", header.Name, err)
+		}
+		if headers[textproto.CanonicalMIMEHeaderKey(header.Name)], err = template(ctx, v1.Template{Template: value}); err != nil {
+			return results.Invalidf("failed to template header %s: %v", header.Name, err)
+		}
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return results.ErrorMessage(err)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	// the proxy can differ per host, so a client is created for each proxy and all share the jar
+	clients := map[string]*http.Client{}
+	clientFor := func(uri string) *http.Client {
+		var proxyURL string
+		if u := proxy.ForURL(uri); u != nil {
+			proxyURL = u.String()
+		}
+		if client, ok := clients[proxyURL]; ok {
+			return client
+		}
+		// every hop of a redirect is recorded as a separate entry, so redirects are not followed
+		client := http.NewClient().
+			UserAgent("canary-checker/" + runner.Version).
+			RedirectPolicy(0).
+			Use(cookieJarMiddleware(jar, 0))
+		if proxyURL != "" {
+			client.Proxy(proxyURL)
+		}
+		if ctx.HARCollector != nil {
+			client.HARCollector(ctx.HARCollector)
+		}
+		clients[proxyURL] = client
+		return client
+	}
+
+	var replayed []HARReplayResult
+	var failures []string
+	start := time.Now()
+
+	for _, entry := range entries {
+		// requests that were cancelled or blocked by the browser have no status
+		if entry.Response.Status == 0 || matchesAny(excludes, entry.Request.URL) {
+			continue
+		}
+		r := replayHAREntry(ctx, clientFor, check, entry, hosts, headers)
+		replayed = append(replayed, r)
+		if r.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s %s: %s", r.Method, r.URL, r.Error))
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	result.AddDetails(replayed)
+
+	if len(replayed) == 0 {
+		return results.Failf("no requests to replay")
+	}
+	if len(failures) > 0 {
+		return results.Failf("%d/%d requests did not match the recording: %s", len(failures), len(replayed), failures[0])
+	}
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+func matchesAny(excludes []*regexp.Regexp, uri string) bool {
+	for _, re := range excludes {
+		if re.MatchString(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadHAR(ctx *context.Context, check v1.HARCheck) ([]har.Entry, error) {
+	var data []byte
+	switch {
+	case check.File != "" && check.HAR != nil:
+		return nil, fmt.Errorf("only one of file or har can be specified")
+	case check.File != "":
+		content, err := os.ReadFile(check.File)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	case check.HAR != nil:
+		content, err := ctx.GetEnvValueFromCache(*check.HAR, ctx.GetNamespace())
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(content)
+	default:
+		return nil, fmt.Errorf("one of file or har is required")
+	}
+
+	var file har.File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid har: %w", err)
+	}
+
+	// binary response bodies are recorded base64 encoded, the encoding is not part of har.Content
+	var encodings struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					Content struct {
+						Encoding string `json:"encoding"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &encodings); err != nil {
+		return nil, fmt.Errorf("invalid har: %w", err)
+	}
+	for i, entry := range encodings.Log.Entries {
+		if entry.Response.Content.Encoding != "base64" {
+			continue
+		}
+		content := &file.Log.Entries[i].Response.Content
+		decoded, err := base64.StdEncoding.DecodeString(content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 response body of entry %d: %w", i, err)
+		}
+		content.Text = string(decoded)
+	}
+	return file.Log.Entries, nil
+}
+
+// rewriteHARHost replaces the host of a recorded url, the replacement is either a host[:port]
+// or a base url whose scheme is used as well
+func rewriteHARHost(recorded string, hosts map[string]string) (string, error) {
+	u, err := url.Parse(recorded)
+	if err != nil {
+		return "", err
+	}
+	replacement, ok := hosts[u.Host]
+	if !ok {
+		if replacement, ok = hosts[u.Hostname()]; !ok {
+			return recorded, nil
+		}
+	}
+	if strings.Contains(replacement, "://") {
+		base, err := url.Parse(replacement)
+		if err != nil {
+			return "", fmt.Errorf("invalid host %s: %w", replacement, err)
+		}
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+	} else {
+		u.Host = replacement
+	}
+	return u.String(), nil
+}
+
+func replayHAREntry(ctx *context.Context, clientFor func(string) *http.Client, check v1.HARCheck, entry har.Entry, hosts, headers map[string]string) HARReplayResult {
+	r := HARReplayResult{
+		Method:         entry.Request.Method,
+		URL:            entry.Request.URL,
+		ExpectedStatus: entry.Response.Status,
+	}
+
+	uri, err := rewriteHARHost(entry.Request.URL, hosts)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.URL = uri
+
+	request := clientFor(uri).R(ctx)
+	for _, header := range entry.Request.Headers {
+		name := textproto.CanonicalMIMEHeaderKey(header.Name)
+		if strings.HasPrefix(name, ":") || harSkippedHeaders[name] {
+			continue
+		}
+		if _, ok := headers[name]; !ok {
+			request.Header(name, header.Value)
+		}
+	}
+	for name, value := range headers {
+		request.Header(name, value)
+	}
+	if entry.Request.PostData != nil && entry.Request.PostData.Text != "" {
+		if request.GetHeader("Content-Type") == "" && entry.Request.PostData.MimeType != "" {
+			request.Header("Content-Type", entry.Request.PostData.MimeType)
+		}
+		if err := request.Body(entry.Request.PostData.Text); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+	}
+
+	ctx.Tracef("replaying %s %s", r.Method, uri)
+	start := time.Now()
+	response, err := request.Do(entry.Request.Method, uri)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	body, err := response.AsString()
+	r.Duration = time.Since(start).Milliseconds()
+	r.Status = response.StatusCode
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	if err := compareHARResponse(check, entry.Response, response.StatusCode, body); err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// compareHARResponse compares a response with the recorded response, bodies that were not
+// captured in the recording (or were truncated) are not compared
+func compareHARResponse(check v1.HARCheck, recorded har.Response, status int, body string) error {
+	if check.StatusMatch == "class" {
+		if status/100 != recorded.Status/100 {
+			return fmt.Errorf("expected %dxx, got %d", recorded.Status/100, status)
+		}
+	} else if status != recorded.Status {
+		return fmt.Errorf("expected %d, got %d", recorded.Status, status)
+	}
+
+	expected := recorded.Content.Text
+	if expected == "" || recorded.Content.Truncated {
+		return nil
+	}
+
+	switch check.BodyMatch {
+	case "exact":
+		if body != expected {
+			return fmt.Errorf("body does not match the recording, expected %s, got %s", pkg.TruncateMessage(expected), pkg.TruncateMessage(body))
+		}
+	case "size":
+		tolerance := check.SizeTolerance
+		if tolerance == 0 {
+			tolerance = 10
+		}
+		diff := len(body) - len(expected)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff*100 > tolerance*len(expected) {
+			return fmt.Errorf("body size %d differs from the recorded %d by more than %d%%", len(body), len(expected), tolerance)
+		}
+	case "json":
+		var expectedJSON, actualJSON interface{}
+		if err := json.Unmarshal([]byte(expected), &expectedJSON); err != nil {
+			return fmt.Errorf("recorded body is not json: %w", err)
+		}
+		if err := json.Unmarshal([]byte(body), &actualJSON); err != nil {
+			return fmt.Errorf("body is not json: %w", err)
+		}
+		if err := compareJSONShape("", expectedJSON, actualJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareJSONShape checks that actual has all the keys of expected with values of the same type,
+// arrays are compared using their first element and recorded nulls match any value
+func compareJSONShape(path string, expected, actual interface{}) error {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %s", jsonPathOrRoot(path), jsonTypeName(actual))
+		}
+		for key, value := range e {
+			v, ok := a[key]
+			if !ok {
+				return fmt.Errorf("%s/%s: missing", path, key)
+			}
+			if err := compareJSONShape(path+"/"+key, value, v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %s", jsonPathOrRoot(path), jsonTypeName(actual))
+		}
+		if len(e) > 0 && len(a) > 0 {
+			return compareJSONShape(path+"/0", e[0], a[0])
+		}
+	case nil:
+		// a recorded null carries no type information
+	default:
+		if jsonTypeName(expected) != jsonTypeName(actual) {
+			return fmt.Errorf("%s: expected a %s, got %s", jsonPathOrRoot(path), jsonTypeName(expected), jsonTypeName(actual))
+		}
+	}
+	return nil
+}
+
+func jsonPathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,178 @@
+package checks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/commons/har"
+	"github.com/flanksource/duty/types"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func newTestHAR(t *testing.T, entries ...har.Entry) string {
+	data, err := json.Marshal(har.File{Log: har.Log{Version: "1.2", Entries: entries}})
+	if err != nil {
+		t.Fatalf("failed to marshal har: %v", err)
+	}
+	return string(data)
+}
+
+func TestHARCheckerReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Header.Get("Authorization") != "Bearer live" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/items":
+			if _, err := r.Cookie("session"); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items": [{"id": 2, "name": "b"}], "next": "x"}`))
+		}
+	}))
+	defer server.Close()
+
+	recording := newTestHAR(t,
+		har.Entry{
+			Request: har.Request{Method: "POST", URL: "https://app.example.com/login", Headers: []har.Header{
+				{Name: ":authority", Value: "app.example.com"},
+				{Name: "Authorization", Value: "****"},
+			}},
+			Response: har.Response{Status: 204},
+		},
+		har.Entry{
+			Request:  har.Request{Method: "GET", URL: "https://app.example.com/static/app.js"},
+			Response: har.Response{Status: 200},
+		},
+		har.Entry{
+			Request: har.Request{Method: "GET", URL: "https://app.example.com/api/items"},
+			Response: har.Response{Status: 200, Content: har.Content{
+				MimeType: "application/json",
+				Text:     `{"items": [{"id": 1, "name": "a"}], "next": null}`,
+			}},
+		},
+	)
+
+	check := v1.HARCheck{
+		Description: v1.Description{Name: "har"},
+		HAR:         &types.EnvVar{ValueStatic: recording},
+		Hosts:       map[string]string{"app.example.com": server.URL},
+		Headers:     []types.EnvVar{{Name: "authorization", ValueStatic: "Bearer live"}},
+		Exclude:     []string{`/static/`},
+		BodyMatch:   "json",
+	}
+
+	results := (&HARChecker{}).Check(newRetryTestContext(nil), check)
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+	if !results[0].Pass {
+		t.Fatalf("expected replay to pass, got error: %s", results[0].Error)
+	}
+	replayed, ok := results[0].Detail.([]HARReplayResult)
+	if !ok || len(replayed) != 2 {
+		t.Fatalf("expected 2 replayed requests, got %v", results[0].Detail)
+	}
+	if !strings.HasPrefix(replayed[1].URL, server.URL) {
+		t.Errorf("expected the host to be replaced, got %s", replayed[1].URL)
+	}
+
+	check.Headers = nil
+	results = (&HARChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass {
+		t.Fatal("expected replay without credentials to fail")
+	}
+	if !strings.Contains(results[0].Error, "2/2 requests did not match") {
+		t.Errorf("unexpected error: %s", results[0].Error)
+	}
+}
+
+func TestLoadHARDecodesBase64Bodies(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "recording.har")
+	recording := `{"log": {"entries": [
+		{"request": {"method": "GET", "url": "https://example.com/"}, "response": {"status": 200, "content": {"text": "plain"}}},
+		{"request": {"method": "GET", "url": "https://example.com/logo"}, "response": {"status": 200, "content": {"text": "iVBORw==", "encoding": "base64"}}}
+	]}}`
+	if err := os.WriteFile(file, []byte(recording), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadHAR(nil, v1.HARCheck{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Response.Content.Text != "plain" || entries[1].Response.Content.Text != "\x89PNG" {
+		t.Errorf("expected the base64 body to be decoded, got %q and %q", entries[0].Response.Content.Text, entries[1].Response.Content.Text)
+	}
+
+	if err := os.WriteFile(file, []byte(strings.Replace(recording, "iVBORw==", "not base64", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHAR(nil, v1.HARCheck{File: file}); err == nil || !strings.Contains(err.Error(), "invalid base64 response body of entry 1") {
+		t.Errorf("expected an invalid base64 body to fail, got %v", err)
+	}
+}
+
+func TestCompareHARResponse(t *testing.T) {
+	recorded := har.Response{Status: 201, Content: har.Content{Text: `{"id": 1, "tags": ["a"], "owner": {"name": "x"}}`}}
+
+	tests := []struct {
+		name   string
+		check  v1.HARCheck
+		status int
+		body   string
+		err    string
+	}{
+		{name: "exact status", status: 201},
+		{name: "status mismatch", status: 200, err: "expected 201, got 200"},
+		{name: "status class", check: v1.HARCheck{StatusMatch: "class"}, status: 200},
+		{name: "status class mismatch", check: v1.HARCheck{StatusMatch: "class"}, status: 404, err: "expected 2xx, got 404"},
+		{name: "json", check: v1.HARCheck{BodyMatch: "json"}, status: 201, body: `{"id": 2, "tags": [], "owner": {"name": "y", "age": 1}}`},
+		{name: "json missing key", check: v1.HARCheck{BodyMatch: "json"}, status: 201, body: `{"id": 2, "tags": ["b"]}`, err: "/owner: missing"},
+		{name: "json type", check: v1.HARCheck{BodyMatch: "json"}, status: 201, body: `{"id": "2", "tags": ["b"], "owner": {}}`, err: "/id: expected a number, got string"},
+		{name: "exact body", check: v1.HARCheck{BodyMatch: "exact"}, status: 201, body: `{}`, err: "body does not match"},
+		{name: "size", check: v1.HARCheck{BodyMatch: "size"}, status: 201, body: `{"id": 12, "tags": ["a"], "owner": {"name": "x"}}`},
+		{name: "size exceeded", check: v1.HARCheck{BodyMatch: "size", SizeTolerance: 5}, status: 201, body: `{}`, err: "differs from the recorded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareHARResponse(tt.check, recorded, tt.status, tt.body)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRewriteHARHost(t *testing.T) {
+	hosts := map[string]string{
+		"www.example.com":      "staging.example.com",
+		"api.example.com:8443": "http://localhost:8080",
+	}
+	tests := map[string]string{
+		"https://www.example.com/a?b=c":     "https://staging.example.com/a?b=c",
+		"https://api.example.com:8443/v1/x": "http://localhost:8080/v1/x",
+		"https://other.example.com/":        "https://other.example.com/",
+	}
+	for recorded, expected := range tests {
+		out, err := rewriteHARHost(recorded, hosts)
+		if err != nil || out != expected {
+			t.Errorf("rewriteHARHost(%s) = %s, %v; expected %s", recorded, out, err, expected)
+		}
+	}
+}
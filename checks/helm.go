@@ -0,0 +1,541 @@
+package checks
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	netHTTP "net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/flanksource/yaml.v3"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+	"github.com/flanksource/duty/types"
+)
+
+const (
+	helmChartMediaType      = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	helmProvenanceMediaType = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+
+	// maxChartSize limits the size of the chart archives and indexes that are read into memory
+	maxChartSize = 32 << 20
+	// maxUnpackedChartSize limits the size of the files in a chart archive
+	maxUnpackedChartSize = 128 << 20
+)
+
+var (
+	helmCharts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_helm_charts",
+			Help: "The number of charts in a helm repository",
+		},
+		[]string{"url"},
+	)
+	helmChartLatest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_helm_chart_latest",
+			Help: "The latest version of a chart in a helm repository, the value is always 1",
+		},
+		[]string{"url", "chart", "version"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(helmCharts, helmChartLatest)
+}
+
+type HelmChecker struct{}
+
+// Type: returns checker type
+func (c *HelmChecker) Type() string {
+	return "helm"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *HelmChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.Helm {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// HelmCheckResult is the data of a helm check
+type HelmCheckResult struct {
+	URL    string `json:"url"`
+	Charts int    `json:"charts"`
+	// Versions is the number of versions of all the charts
+	Versions int `json:"versions"`
+	// Latest are the latest stable versions of the charts
+	Latest map[string]string `json:"latest"`
+	Chart  *HelmChart        `json:"chart,omitempty"`
+}
+
+type HelmChart struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	Size       int    `json:"size,omitempty"`
+	Files      int    `json:"files,omitempty"`
+	// Provenance is set if the chart has a provenance file that matches the chart archive
+	Provenance bool `json:"provenance"`
+	// Signed is set if the signature of the provenance file was verified with the keyring
+	Signed bool `json:"signed"`
+}
+
+// helmChartVersion is a version of a chart in a repository
+type helmChartVersion struct {
+	version    *semver.Version
+	appVersion string
+	// filename of the chart archive, which the provenance file refers to
+	filename string
+	// fetch downloads the chart archive and its provenance file, which is nil if the chart has none.
+	// The digest of the archive is verified when the repository provides one.
+	fetch func() (archive []byte, prov []byte, err error)
+}
+
+func (c *HelmChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.HelmCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	if check.Auth != nil && check.Username.IsEmpty() && check.Password.IsEmpty() {
+		check.Username, check.Password = check.Auth.Username, check.Auth.Password
+	}
+	check.URL = check.GetRepositoryURL()
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	if connection.URL == "" {
+		return results.Invalidf("a url or connection is required")
+	}
+
+	if check.CaFile != "" && check.TLSConfig == nil {
+		ca, err := os.ReadFile(check.CaFile)
+		if err != nil {
+			return results.Invalidf("failed to read cafile: %v", err)
+		}
+		check.TLSConfig = &v1.TLSConfig{CA: types.EnvVar{ValueStatic: string(ca)}}
+	}
+	httpClient, err := newProxiedHTTPClient(ctx, check.Proxy, check.TLSConfig)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+
+	var keyring openpgp.EntityList
+	if check.Keyring != nil {
+		value, err := ctx.GetEnvValueFromCache(*check.Keyring, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("failed to get keyring: %v", err)
+		}
+		if keyring, err = readKeyring(value); err != nil {
+			return results.Invalidf("invalid keyring: %v", err)
+		}
+	}
+
+	endpoint := v1.SanitizeEndpoints(connection.URL)
+	data := HelmCheckResult{URL: endpoint, Latest: map[string]string{}}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+
+	start := time.Now()
+
+	var charts map[string][]helmChartVersion
+	if strings.HasPrefix(connection.URL, "oci://") {
+		if check.Chart == "" {
+			return results.Invalidf("a chart is required for oci repositories")
+		}
+		charts, err = listOCIChartVersions(ctx, httpClient, connection.URL, connection.Username, connection.Password, check.Chart)
+	} else {
+		charts, err = listHelmRepositoryCharts(ctx, httpClient, connection.URL, connection.Username, connection.Password)
+	}
+	if err != nil {
+		return results.Failf("%v", err)
+	}
+
+	data.Charts = len(charts)
+	helmCharts.WithLabelValues(endpoint).Set(float64(len(charts)))
+	for name, versions := range charts {
+		data.Versions += len(versions)
+		helmChartLatest.DeletePartialMatch(prometheus.Labels{"url": endpoint, "chart": name})
+		if latest, err := selectHelmChartVersion(versions, ""); err == nil {
+			data.Latest[name] = latest.version.Original()
+			helmChartLatest.WithLabelValues(endpoint, name, latest.version.Original()).Set(1)
+		}
+	}
+
+	if check.Chart != "" {
+		versions, ok := charts[check.Chart]
+		if !ok {
+			return results.Failf("chart %s not found", check.Chart)
+		}
+		selected, err := selectHelmChartVersion(versions, check.Version)
+		if err != nil {
+			return results.Failf("%s: %v", check.Chart, err)
+		}
+		data.Chart = &HelmChart{Name: check.Chart, Version: selected.version.Original(), AppVersion: selected.appVersion}
+
+		if !check.SkipDownload {
+			archive, prov, err := selected.fetch()
+			if err != nil {
+				return results.Failf("failed to download %s-%s: %v", check.Chart, data.Chart.Version, err)
+			}
+			data.Chart.Size = len(archive)
+			data.Chart.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(archive))
+
+			if data.Chart.Files, err = unpackHelmChart(archive, check.Chart, data.Chart.Version); err != nil {
+				return results.Failf("invalid chart %s-%s: %v", check.Chart, data.Chart.Version, err)
+			}
+			if prov == nil && keyring != nil {
+				return results.Failf("%s-%s has no provenance file", check.Chart, data.Chart.Version)
+			}
+			if prov != nil {
+				if data.Chart.Signed, err = verifyHelmProvenance(prov, archive, selected.filename, keyring); err != nil {
+					return results.Failf("failed to verify the provenance of %s-%s: %v", check.Chart, data.Chart.Version, err)
+				}
+				data.Chart.Provenance = true
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// helmIndex is the index.yaml of a chart repository
+type helmIndex struct {
+	Entries map[string][]struct {
+		Name       string   `yaml:"name"`
+		Version    string   `yaml:"version"`
+		AppVersion string   `yaml:"appVersion"`
+		Digest     string   `yaml:"digest"`
+		URLs       []string `yaml:"urls"`
+	} `yaml:"entries"`
+}
+
+// listHelmRepositoryCharts returns the charts in the index.yaml of a chart repository
+func listHelmRepositoryCharts(ctx *context.Context, client *netHTTP.Client, repoURL, username, password string) (map[string][]helmChartVersion, error) {
+	base, err := url.Parse(strings.TrimSuffix(repoURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", repoURL, err)
+	}
+
+	get := func(u *url.URL) ([]byte, int, error) {
+		req, err := netHTTP.NewRequestWithContext(ctx, netHTTP.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		// credentials are only sent to the host of the repository, like helm does by default
+		if username != "" && u.Host == base.Host {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != netHTTP.StatusOK {
+			return nil, resp.StatusCode, fmt.Errorf("GET %s: %s", v1.SanitizeEndpoints(u.String()), resp.Status)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxChartSize+1))
+		if err == nil && len(body) > maxChartSize {
+			err = fmt.Errorf("%s is larger than %d bytes", v1.SanitizeEndpoints(u.String()), maxChartSize)
+		}
+		return body, resp.StatusCode, err
+	}
+
+	body, _, err := get(base.JoinPath("index.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var index helmIndex
+	if err := yaml.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("invalid index.yaml: %w", err)
+	}
+	if index.Entries == nil {
+		return nil, fmt.Errorf("invalid index.yaml: no entries")
+	}
+
+	charts := map[string][]helmChartVersion{}
+	for name, entries := range index.Entries {
+		for _, entry := range entries {
+			version, err := semver.NewVersion(entry.Version)
+			if err != nil || len(entry.URLs) == 0 {
+				continue
+			}
+			chartURL, err := base.Parse(entry.URLs[0])
+			if err != nil {
+				continue
+			}
+			digest := entry.Digest
+			charts[name] = append(charts[name], helmChartVersion{
+				version:    version,
+				appVersion: entry.AppVersion,
+				filename:   path.Base(chartURL.Path),
+				fetch: func() ([]byte, []byte, error) {
+					archive, _, err := get(chartURL)
+					if err != nil {
+						return nil, nil, err
+					}
+					if actual := fmt.Sprintf("%x", sha256.Sum256(archive)); digest != "" && !strings.EqualFold(strings.TrimPrefix(digest, "sha256:"), actual) {
+						return nil, nil, fmt.Errorf("digest mismatch, expected %s, got %s", digest, actual)
+					}
+					provURL := *chartURL
+					provURL.Path, provURL.RawPath = chartURL.Path+".prov", ""
+					prov, status, err := get(&provURL)
+					if status == netHTTP.StatusNotFound {
+						return archive, nil, nil
+					}
+					return archive, prov, err
+				},
+			})
+		}
+	}
+	return charts, nil
+}
+
+// listOCIChartVersions returns the versions of a chart in an oci registry from the tags of its repository
+func listOCIChartVersions(ctx *context.Context, client *netHTTP.Client, repoURL, username, password, chart string) (map[string][]helmChartVersion, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %s", v1.SanitizeEndpoints(repoURL))
+	}
+	registry := &registryClient{
+		client:     client,
+		baseURL:    "https://" + u.Host,
+		repository: strings.Trim(path.Join(u.Path, chart), "/"),
+		username:   username,
+		password:   password,
+	}
+
+	tags, err := registry.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tags of %s: %w", registry.repository, err)
+	}
+
+	var versions []helmChartVersion
+	for _, tag := range tags {
+		// + is not allowed in tags, so helm replaces it with _
+		version, err := semver.NewVersion(strings.ReplaceAll(tag, "_", "+"))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, helmChartVersion{
+			version:  version,
+			filename: fmt.Sprintf("%s-%s.tgz", path.Base(chart), version.Original()),
+			fetch: func() ([]byte, []byte, error) {
+				return pullOCIChart(ctx, registry, tag)
+			},
+		})
+	}
+	if len(versions) == 0 {
+		return map[string][]helmChartVersion{}, nil
+	}
+	return map[string][]helmChartVersion{chart: versions}, nil
+}
+
+// pullOCIChart pulls the chart archive and provenance file of a tag, the registry client verifies their digests
+func pullOCIChart(ctx *context.Context, registry *registryClient, tag string) ([]byte, []byte, error) {
+	d, err := registry.resolve(ctx, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, mediaType, err := registry.manifest(ctx, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mediaType != ocispec.MediaTypeImageManifest {
+		return nil, nil, fmt.Errorf("unsupported manifest type %s", mediaType)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	var archive, prov []byte
+	for _, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case helmChartMediaType:
+			archive, err = registry.readBlob(ctx, layer, maxChartSize)
+		case helmProvenanceMediaType:
+			prov, err = registry.readBlob(ctx, layer, maxChartSize)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if archive == nil {
+		return nil, nil, fmt.Errorf("%s is not a helm chart", tag)
+	}
+	return archive, prov, nil
+}
+
+// selectHelmChartVersion returns the latest version that matches a version or constraint, prereleases
+// are only selected by the latest version if there is no other version
+func selectHelmChartVersion(versions []helmChartVersion, constraint string) (helmChartVersion, error) {
+	sorted := make([]helmChartVersion, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].version.GreaterThan(sorted[j].version)
+	})
+
+	if constraint == "" {
+		for _, v := range sorted {
+			if v.version.Prerelease() == "" {
+				return v, nil
+			}
+		}
+		if len(sorted) > 0 {
+			return sorted[0], nil
+		}
+		return helmChartVersion{}, errors.New("no versions found")
+	}
+
+	if exact, err := semver.StrictNewVersion(strings.TrimPrefix(constraint, "v")); err == nil {
+		for _, v := range sorted {
+			if v.version.Equal(exact) {
+				return v, nil
+			}
+		}
+		return helmChartVersion{}, fmt.Errorf("version %s not found", constraint)
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return helmChartVersion{}, fmt.Errorf("invalid version %s: %w", constraint, err)
+	}
+	for _, v := range sorted {
+		if c.Check(v.version) {
+			return v, nil
+		}
+	}
+	return helmChartVersion{}, fmt.Errorf("no version matches %s", constraint)
+}
+
+// unpackHelmChart reads every file in a chart archive and verifies that its Chart.yaml is for the
+// chart and version, it returns the number of files
+func unpackHelmChart(archive []byte, name, version string) (int, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	var files int
+	var metadata *struct {
+		Name    string `yaml:"name"`
+		Version string `yaml:"version"`
+	}
+	reader := tar.NewReader(io.LimitReader(gz, maxUnpackedChartSize))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		files++
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return files, err
+		}
+		if parts := strings.Split(path.Clean(header.Name), "/"); len(parts) == 2 && parts[1] == "Chart.yaml" && metadata == nil {
+			metadata = &struct {
+				Name    string `yaml:"name"`
+				Version string `yaml:"version"`
+			}{}
+			if err := yaml.Unmarshal(content, metadata); err != nil {
+				return files, fmt.Errorf("invalid Chart.yaml: %w", err)
+			}
+		}
+	}
+
+	if metadata == nil {
+		return files, errors.New("no Chart.yaml found")
+	}
+	if metadata.Name != path.Base(name) || metadata.Version != version {
+		return files, fmt.Errorf("the Chart.yaml is for %s-%s", metadata.Name, metadata.Version)
+	}
+	return files, nil
+}
+
+// verifyHelmProvenance verifies that the provenance file has the digest of the archive, and if there
+// is a keyring that its signature is valid. It returns whether the signature was verified.
+func verifyHelmProvenance(prov, archive []byte, filename string, keyring openpgp.EntityList) (bool, error) {
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		return false, errors.New("the provenance file is not a signed message")
+	}
+
+	// the message is the Chart.yaml followed by the digests of the files
+	_, filesYAML, ok := strings.Cut(string(block.Plaintext), "\n...\n")
+	if !ok {
+		return false, errors.New("the provenance file has no files section")
+	}
+	var sums struct {
+		Files map[string]string `yaml:"files"`
+	}
+	if err := yaml.Unmarshal([]byte(filesYAML), &sums); err != nil {
+		return false, fmt.Errorf("invalid files section: %w", err)
+	}
+	expected, ok := sums.Files[filename]
+	if !ok && len(sums.Files) == 1 {
+		for _, sum := range sums.Files {
+			expected = sum
+		}
+	} else if !ok {
+		return false, fmt.Errorf("%s not found in the provenance file", filename)
+	}
+	sum := sha256.Sum256(archive)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); expected != actual {
+		return false, fmt.Errorf("digest mismatch, expected %s, got %s", expected, actual)
+	}
+
+	if keyring == nil {
+		return false, nil
+	}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body, nil); err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+	return true, nil
+}
+
+// readKeyring reads an armored or binary PGP keyring
+func readKeyring(keyring string) (openpgp.EntityList, error) {
+	if strings.Contains(keyring, "-----BEGIN PGP") {
+		return openpgp.ReadArmoredKeyRing(strings.NewReader(keyring))
+	}
+	return openpgp.ReadKeyRing(strings.NewReader(keyring))
+}
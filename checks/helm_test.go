@@ -0,0 +1,205 @@
+package checks
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/flanksource/duty/types"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func newTestChart(t *testing.T, name, version string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string]string{
+		name + "/Chart.yaml":            fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\nappVersion: v%s\n", name, version, version),
+		name + "/templates/config.yaml": "apiVersion: v1\nkind: ConfigMap\n",
+	}
+	for path, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: path, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// signTestChart returns a provenance file for the archive in the format of helm package --sign
+func signTestChart(t *testing.T, signer *openpgp.Entity, name, version string, archive []byte) []byte {
+	filename := fmt.Sprintf("%s-%s.tgz", name, version)
+	message := fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n\n...\nfiles:\n  %s: sha256:%x\n", name, version, filename, sha256.Sum256(archive))
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, signer.PrivateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func armoredPublicKey(t *testing.T, entity *openpgp.Entity) string {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestHelmCheckerRepository(t *testing.T) {
+	signer, err := openpgp.NewEntity("canary", "", "canary@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	var index strings.Builder
+	index.WriteString("apiVersion: v1\nentries:\n  app:\n")
+	for _, version := range []string{"1.0.0", "1.1.0", "2.0.0-rc.1"} {
+		archive := newTestChart(t, "app", version)
+		files["/charts/app-"+version+".tgz"] = archive
+		files["/charts/app-"+version+".tgz.prov"] = signTestChart(t, signer, "app", version, archive)
+		fmt.Fprintf(&index, "  - name: app\n    version: %s\n    appVersion: v%s\n    digest: %x\n    urls: [charts/app-%s.tgz]\n", version, version, sha256.Sum256(archive), version)
+	}
+	index.WriteString("  library:\n  - name: library\n    version: 0.1.0\n    urls: [charts/library-0.1.0.tgz]\n")
+	files["/index.yaml"] = []byte(index.String())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if body, ok := files[r.URL.Path]; ok {
+			_, _ = w.Write(body)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	check := v1.HelmCheck{
+		Description: v1.Description{Name: "helm"},
+		Connection: v1.Connection{URL: server.URL, Authentication: types.Authentication{
+			Username: types.EnvVar{ValueStatic: "user"},
+			Password: types.EnvVar{ValueStatic: "pass"},
+		}},
+		Chart:   "app",
+		Keyring: &types.EnvVar{ValueStatic: armoredPublicKey(t, signer)},
+	}
+	results := (&HelmChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["charts"] != float64(2) || data["versions"] != float64(4) {
+		t.Errorf("expected 2 charts with 4 versions, got %v", data)
+	}
+	if latest := data["latest"].(map[string]any); latest["app"] != "1.1.0" || latest["library"] != "0.1.0" {
+		t.Errorf("expected the latest stable versions, got %v", latest)
+	}
+	chart := data["chart"].(map[string]any)
+	if chart["version"] != "1.1.0" || chart["files"] != float64(2) || chart["signed"] != true {
+		t.Errorf("expected app-1.1.0 to be downloaded and verified, got %v", chart)
+	}
+
+	check.Version = ">=2.0.0-0"
+	if results = (&HelmChecker{}).Check(newRetryTestContext(nil), check); !results[0].Pass {
+		t.Errorf("expected the prerelease to match the constraint, got %s", results[0].Error)
+	}
+
+	check.Version = "3.0.0"
+	if results = (&HelmChecker{}).Check(newRetryTestContext(nil), check); results[0].Pass || !strings.Contains(results[0].Error, "version 3.0.0 not found") {
+		t.Errorf("expected a missing version to fail, got %s", results[0].Error)
+	}
+
+	// a tampered archive fails the digest in the index
+	check.Version = "1.0.0"
+	files["/charts/app-1.0.0.tgz"] = newTestChart(t, "app", "1.0.1")
+	if results = (&HelmChecker{}).Check(newRetryTestContext(nil), check); results[0].Pass || !strings.Contains(results[0].Error, "digest mismatch") {
+		t.Errorf("expected a digest mismatch, got %s", results[0].Error)
+	}
+
+	// a signature by another key is rejected
+	other, _ := openpgp.NewEntity("other", "", "other@example.com", nil)
+	check.Version = ""
+	check.Keyring = &types.EnvVar{ValueStatic: armoredPublicKey(t, other)}
+	if results = (&HelmChecker{}).Check(newRetryTestContext(nil), check); results[0].Pass || !strings.Contains(results[0].Error, "invalid signature") {
+		t.Errorf("expected the signature to be rejected, got %s", results[0].Error)
+	}
+}
+
+func TestHelmOCIChart(t *testing.T) {
+	_, server := newTestRegistry()
+	defer server.Close()
+
+	ctx := newRetryTestContext(nil)
+	client := &registryClient{client: server.Client(), baseURL: server.URL, repository: "canary/test", username: "user", password: "pass"}
+	for _, version := range []string{"0.9.0", "1.0.0+build.1"} {
+		config, err := client.pushBlob(ctx, "application/vnd.cncf.helm.config.v1+json", []byte(`{"name":"test"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chart, err := client.pushBlob(ctx, helmChartMediaType, newTestChart(t, "test", version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest, _ := json.Marshal(ocispec.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageManifest,
+			Config:    config,
+			Layers:    []ocispec.Descriptor{chart},
+		})
+		if _, err := client.pushManifest(ctx, strings.ReplaceAll(version, "+", "_"), ocispec.MediaTypeImageManifest, manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	charts, err := listOCIChartVersions(ctx, server.Client(), "oci://"+strings.TrimPrefix(server.URL, "https://")+"/canary", "user", "pass", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := selectHelmChartVersion(charts["test"], "")
+	if err != nil || latest.version.Original() != "1.0.0+build.1" {
+		t.Fatalf("expected 1.0.0+build.1 to be the latest version, got %v, %v", latest.version, err)
+	}
+	archive, prov, err := latest.fetch()
+	if err != nil || prov != nil {
+		t.Fatalf("failed to pull the chart: %v", err)
+	}
+	if files, err := unpackHelmChart(archive, "test", "1.0.0+build.1"); err != nil || files != 2 {
+		t.Errorf("expected the chart to unpack, got %d files, %v", files, err)
+	}
+}
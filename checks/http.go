@@ -3,6 +3,7 @@ package checks
 import (
 	"encoding/json"
 	"fmt"
+	netHTTP "net/http"
 	"net/url"
 	"regexp"
 	"sort"
@@ -55,10 +56,19 @@ var (
 		},
 		[]string{"url"},
 	)
+
+	phaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "canary_check_http_phase_duration",
+			Help:    "A histogram of the duration in milliseconds of each phase of HTTP checks per route.",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 3000, 10000},
+		},
+		[]string{"phase", "url"},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(responseStatus, sslExpiration)
+	prometheus.MustRegister(responseStatus, sslExpiration, phaseDuration)
 }
 
 type HTTPChecker struct {
@@ -79,7 +89,7 @@ func (c *HTTPChecker) Run(ctx *context.Context) pkg.Results {
 	return results
 }
 
-func (c *HTTPChecker) generateHTTPRequest(ctx *context.Context, check v1.HTTPCheck, conn *models.Connection) (*http.Request, error) {
+func (c *HTTPChecker) generateHTTPRequest(ctx *context.Context, check v1.HTTPCheck, conn *models.Connection, timer *httpTimer, jar netHTTP.CookieJar) (*http.Request, error) {
 	username := check.Authentication.Username
 	password := check.Authentication.Password
 	if conn != nil {
@@ -146,17 +156,51 @@ func (c *HTTPChecker) generateHTTPRequest(ctx *context.Context, check v1.HTTPChe
 	if check.ThresholdMillis > 0 {
 		client.Timeout(time.Duration(check.ThresholdMillis) * time.Millisecond)
 	}
+	maxRedirects := maxRedirectDefault
 	if check.MaxRedirects != nil {
-		client.RedirectPolicy(*check.MaxRedirects)
+		maxRedirects = *check.MaxRedirects
+	}
+	if jar != nil {
+		// the jar follows the redirects, so that the cookies of each hop are stored
+		client.RedirectPolicy(0)
 	} else {
-		client.RedirectPolicy(maxRedirectDefault)
+		client.RedirectPolicy(maxRedirects)
 	}
 
 	if ctx.HARCollector != nil {
 		client.HARCollector(ctx.HARCollector)
 	}
 
-	return client.R(ctx), nil
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	if proxyURL := proxy.ForURL(check.URL); proxyURL != nil {
+		client.Proxy(proxyURL.String())
+	}
+
+	var pinnedHost string
+	if u, err := url.Parse(check.URL); err == nil {
+		if addrs, _ := pinnedAddresses(check.Resolve, u.Hostname()); len(addrs) > 0 {
+			if strings.Contains(addrs[0], ":") {
+				client.ConnectTo("[" + addrs[0] + "]")
+			} else {
+				client.ConnectTo(addrs[0])
+			}
+			pinnedHost = u.Hostname()
+		}
+	}
+
+	if jar != nil {
+		client.Use(cookieJarMiddleware(jar, maxRedirects))
+	}
+
+	request := client.R(timer.WithContext(ctx))
+	// the Host header is also used as the TLS server name
+	if pinnedHost != "" && !lo.ContainsBy(check.Headers, func(h types.EnvVar) bool { return strings.EqualFold(h.Name, "Host") }) {
+		request.Header("Host", pinnedHost)
+	}
+	return request, nil
 }
 
 func hydrate(ctx *context.Context, check v1.HTTPCheck) (*v1.HTTPCheck, *models.Connection, oops.OopsErrorBuilder, pkg.Results) {
@@ -182,7 +226,7 @@ func hydrate(ctx *context.Context, check v1.HTTPCheck) (*v1.HTTPCheck, *models.C
 		return nil, nil, oops, results.Invalidf("error getting connection  %v", err)
 	}
 
-	if connection.URL == "" {
+	if connection.URL == "" && len(check.Steps) == 0 {
 		return nil, nil, oops, results.Invalidf("no url or connection specified")
 	}
 
@@ -222,17 +266,20 @@ func hydrate(ctx *context.Context, check v1.HTTPCheck) (*v1.HTTPCheck, *models.C
 
 	oops = oops.With("url", uri)
 
-	if _uri, err := url.Parse(uri); err != nil {
-		return nil, nil, oops, results.WithError(oops.Wrap(err)).Invalidf("invalid url  '%s'", uri)
-	} else if _uri.Scheme == "" {
-		return nil, nil, oops, results.WithError(oops.Errorf("invalid url")).Invalidf("invalid url, missing scheme '%s'", uri)
-	} else if _uri.Host == "" {
-		return nil, nil, oops, results.WithError(oops.Errorf("invalid url")).Invalidf("invalid url, missing host '%s'", uri)
-	} else if _uri.User != nil {
-		connection.Username = _uri.User.Username()
-		connection.Password, _ = _uri.User.Password()
-		_uri.User = nil
-		uri = _uri.String()
+	// steps without a base url must each specify an absolute url
+	if uri != "" {
+		if _uri, err := url.Parse(uri); err != nil {
+			return nil, nil, oops, results.WithError(oops.Wrap(err)).Invalidf("invalid url  '%s'", uri)
+		} else if _uri.Scheme == "" {
+			return nil, nil, oops, results.WithError(oops.Errorf("invalid url")).Invalidf("invalid url, missing scheme '%s'", uri)
+		} else if _uri.Host == "" {
+			return nil, nil, oops, results.WithError(oops.Errorf("invalid url")).Invalidf("invalid url, missing host '%s'", uri)
+		} else if _uri.User != nil {
+			connection.Username = _uri.User.Username()
+			connection.Password, _ = _uri.User.Password()
+			_uri.User = nil
+			uri = _uri.String()
+		}
 	}
 
 	check.URL = uri
@@ -278,6 +325,15 @@ func crawl(ctx *context.Context, check v1.HTTPCheck, results *pkg.Results) {
 		c.MaxDepth = check.Crawl.Depth
 	}
 
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		results.Invalidf("invalid proxy: %v", err)
+		return
+	}
+	if proxy != nil {
+		c.SetProxyFunc(proxy.ProxyFunc)
+	}
+
 	ctx.Tracef("%s %s depth=%d, parallelism=%d, delay=%v", console.Greenf("CRAWL"), check.URL, c.MaxDepth, rule.Parallelism, rule.Delay)
 	if err := c.Limit(&rule); err != nil {
 		results.Invalidf("%v", err.Error())
@@ -373,7 +429,45 @@ func (c *HTTPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 
 	result := results[0]
 
-	request, err := c.generateHTTPRequest(ctx, *check, connection)
+	families, err := ipFamilies(check.IPFamily)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if len(families) > 1 {
+		return checkEachFamily(check.GetName(), families, func(name, family string) pkg.Results {
+			familyCheck := extConfig.(v1.HTTPCheck)
+			familyCheck.Name = name
+			familyCheck.IPFamily = family
+			return c.Check(ctx, familyCheck)
+		})
+	}
+
+	if u, err := url.Parse(check.URL); err == nil && u.Hostname() != "" {
+		check.Resolve, err = resolveFamily(ctx, check.Resolve, u.Hostname(), strings.ToLower(check.IPFamily))
+		if err != nil {
+			return results.Failf("%v", err)
+		}
+		pinned, err := pinnedAddresses(check.Resolve, u.Hostname())
+		if err != nil {
+			return results.Invalidf("invalid resolve: %v", err)
+		}
+		if len(pinned) > 1 {
+			return checkEachAddress(check.GetName(), pinned, func(name, addr string) pkg.Results {
+				// the original check is used, so that it is hydrated again for each address
+				pinnedCheck := extConfig.(v1.HTTPCheck)
+				pinnedCheck.Name = name
+				pinnedCheck.Resolve = pinToAddress(check.Resolve, u.Hostname(), addr)
+				return c.Check(ctx, pinnedCheck)
+			})
+		}
+	}
+
+	if len(check.Steps) > 0 {
+		return c.runSteps(ctx, *check, connection, results)
+	}
+
+	timer := newHTTPTimer()
+	request, err := c.generateHTTPRequest(ctx, *check, connection, timer, nil)
 	if err != nil {
 		return results.ErrorMessage(oops.Wrap(err))
 	}
@@ -430,6 +524,15 @@ func (c *HTTPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 	}
 	data["content"] = responseBody
 
+	timer.Done()
+	timings := timer.Durations()
+	for _, phase := range httpPhases {
+		phaseDuration.WithLabelValues(phase, check.URL).Observe(float64(timings[phase].Microseconds()) / 1000)
+	}
+	if details, ok := result.Data["results"].(map[string]interface{}); ok {
+		details["timings"] = timer.Millis()
+	}
+
 	if response.IsJSON() {
 		var jsonContent interface{}
 		if err := json.Unmarshal([]byte(responseBody), &jsonContent); err == nil {
@@ -459,10 +562,32 @@ func (c *HTTPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
 	}
 
+	if check.PhaseThresholdMillis != nil {
+		thresholds := check.PhaseThresholdMillis.GetThresholds()
+		for _, phase := range httpPhases {
+			if threshold, ok := thresholds[phase]; ok && int64(threshold) < timings[phase].Milliseconds() {
+				return results.Failf("%s threshold exceeded %s > %d", phase, utils.Age(timings[phase]), threshold)
+			}
+		}
+	}
+
 	if check.ResponseContent != "" && !strings.Contains(responseBody, check.ResponseContent) {
 		return results.Failf("expected %v, found %v", check.ResponseContent, pkg.TruncateMessage(responseBody))
 	}
 
+	if check.Contract != nil {
+		violations, err := validateContract(ctx, *check, response.Response, []byte(responseBody))
+		if err != nil {
+			return results.Failf("contract validation failed: %v", err)
+		}
+		if details, ok := result.Data["results"].(map[string]interface{}); ok {
+			details["contract"] = violations
+		}
+		if len(violations) > 0 {
+			return results.Failf("response does not match contract (%d violations): %s", len(violations), violations[0])
+		}
+	}
+
 	if check.MaxSSLExpiry > 0 {
 		if age == nil {
 			return results.Failf("No certificate found to check age")
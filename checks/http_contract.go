@@ -0,0 +1,316 @@
+package checks
+
+import (
+	"bytes"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	netHTTP "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flanksource/canary-checker/api/context"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/flanksource/yaml.v3"
+)
+
+// openAPIDocuments caches the parsed OpenAPI documents by their url, or the hash of their content
+var openAPIDocuments = gocache.New(time.Hour, time.Hour)
+
+// openAPIDocument is a parsed OpenAPI document with a router that ignores its servers
+type openAPIDocument struct {
+	doc       *openapi3.T
+	router    routers.Router
+	routerErr error
+}
+
+// ContractViolation is a single difference between a response and its contract,
+// path is a JSON pointer into the response e.g. /body/items/0/id, /headers or /status
+type ContractViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v ContractViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// validateContract validates the response against the OpenAPI document and/or JSON Schema of the contract
+func validateContract(ctx *context.Context, check v1.HTTPCheck, response *netHTTP.Response, body []byte) ([]ContractViolation, error) {
+	var violations []ContractViolation
+	contract := *check.Contract
+
+	if contract.OpenAPI != nil {
+		document, err := ctx.GetEnvValueFromCache(*contract.OpenAPI, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get openapi document: %w", err)
+		}
+		// documents referenced by url are fetched like the check itself
+		client, err := newProxiedHTTPClient(ctx, check.Proxy, check.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		v, err := validateOpenAPI(ctx, client, document, contract.OperationID, check.GetMethod(), check.URL, response, body)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+
+	if contract.Schema != nil {
+		schema, err := ctx.GetEnvValueFromCache(*contract.Schema, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get json schema: %w", err)
+		}
+		v, err := validateJSONSchema(schema, body)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+
+	return violations, nil
+}
+
+// loadOpenAPI parses an inline document, or fetches it with client if it is a url. Documents are cached,
+// so that they are not fetched and parsed on every run of the check.
+func loadOpenAPI(ctx gocontext.Context, client *netHTTP.Client, document string) (*openAPIDocument, error) {
+	document = strings.TrimSpace(document)
+	isURL := (strings.HasPrefix(document, "http://") || strings.HasPrefix(document, "https://")) && !strings.ContainsAny(document, "\n ")
+
+	key := document
+	if !isURL {
+		hash := sha256.Sum256([]byte(document))
+		key = hex.EncodeToString(hash[:])
+	}
+	if cached, ok := openAPIDocuments.Get(key); ok {
+		return cached.(*openAPIDocument), nil
+	}
+
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	loader.ReadFromURIFunc = readOpenAPIFromHTTP(client)
+
+	var doc *openapi3.T
+	if isURL {
+		location, err := url.Parse(document)
+		if err != nil {
+			return nil, fmt.Errorf("invalid openapi url %s: %w", document, err)
+		}
+		loader.IsExternalRefsAllowed = true
+		if doc, err = loader.LoadFromURI(location); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if doc, err = loader.LoadFromData([]byte(document)); err != nil {
+			return nil, err
+		}
+	}
+
+	// operations are matched by their method and path only, so that a document listing the url of
+	// one deployment can be used to check any other
+	withoutServers := *doc
+	withoutServers.Servers = nil
+	parsed := &openAPIDocument{doc: doc}
+	parsed.router, parsed.routerErr = legacy.NewRouter(&withoutServers)
+
+	openAPIDocuments.SetDefault(key, parsed)
+	return parsed, nil
+}
+
+// readOpenAPIFromHTTP reads documents and their external references over http with client
+func readOpenAPIFromHTTP(client *netHTTP.Client) openapi3.ReadFromURIFunc {
+	return func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		if location.Scheme != "http" && location.Scheme != "https" {
+			return nil, openapi3.ErrURINotSupported
+		}
+		req, err := netHTTP.NewRequestWithContext(loader.Context, netHTTP.MethodGet, location.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("failed to get %s: %s", location, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+}
+
+func findOpenAPIRoute(document *openAPIDocument, operationID string, request *netHTTP.Request) (*routers.Route, error) {
+	doc := document.doc
+	if operationID == "" {
+		if document.routerErr != nil {
+			return nil, document.routerErr
+		}
+		var err error
+		for _, path := range openAPIRequestPaths(doc, request.URL.Path) {
+			u := *request.URL
+			u.Path = path
+			var route *routers.Route
+			if route, _, err = document.router.FindRoute(&netHTTP.Request{Method: request.Method, URL: &u}); err == nil {
+				return route, nil
+			}
+		}
+		return nil, fmt.Errorf("no operation found for %s %s, specify an operationId: %w", request.Method, request.URL, err)
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for method, operation := range item.Operations() {
+			if operation.OperationID == operationID {
+				return &routers.Route{Spec: doc, Path: path, PathItem: item, Method: method, Operation: operation}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("operation %s not found", operationID)
+}
+
+// openAPIRequestPaths returns the path of the request, followed by the path relative to the base path of
+// each server of the document, e.g. /users/1 for /v1/users/1 with a server of https://example.com/v1
+func openAPIRequestPaths(doc *openapi3.T, path string) []string {
+	paths := []string{path}
+	for _, server := range doc.Servers {
+		basePath, err := server.BasePath()
+		if err != nil || basePath == "/" {
+			continue
+		}
+		basePath = strings.TrimSuffix(basePath, "/")
+		if relative, ok := strings.CutPrefix(path, basePath); ok && strings.HasPrefix(relative, "/") {
+			paths = append(paths, relative)
+		}
+	}
+	return paths
+}
+
+func validateOpenAPI(ctx gocontext.Context, client *netHTTP.Client, document, operationID, method, uri string, response *netHTTP.Response, body []byte) ([]ContractViolation, error) {
+	doc, err := loadOpenAPI(ctx, client, document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi document: %w", err)
+	}
+
+	request, err := netHTTP.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := findOpenAPIRoute(doc, operationID, request)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request: request,
+			Route:   route,
+		},
+		Status: response.StatusCode,
+		Header: response.Header,
+		Body:   io.NopCloser(bytes.NewReader(body)),
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+		},
+	}
+
+	err = openapi3filter.ValidateResponse(ctx, input)
+	if err == nil {
+		return nil, nil
+	}
+	return openAPIViolations(err), nil
+}
+
+// openAPIViolations flattens the errors returned by openapi3filter into violations
+func openAPIViolations(err error) []ContractViolation {
+	var responseErr *openapi3filter.ResponseError
+	if !errors.As(err, &responseErr) {
+		return []ContractViolation{{Path: "", Message: err.Error()}}
+	}
+
+	var prefix string
+	switch {
+	case strings.HasPrefix(responseErr.Reason, "status"):
+		prefix = "/status"
+	case strings.Contains(responseErr.Reason, "header"):
+		prefix = "/headers"
+	default:
+		prefix = "/body"
+	}
+
+	if responseErr.Err == nil {
+		return []ContractViolation{{Path: prefix, Message: responseErr.Reason}}
+	}
+	var violations []ContractViolation
+	for _, e := range flattenSchemaErrors(responseErr.Err) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(e, &schemaErr) {
+			message := schemaErr.Reason
+			if prefix == "/headers" {
+				message = responseErr.Reason + ": " + message
+			}
+			violations = append(violations, ContractViolation{
+				Path:    prefix + toJSONPointer(schemaErr.JSONPointer()),
+				Message: message,
+			})
+		} else {
+			violations = append(violations, ContractViolation{Path: prefix, Message: responseErr.Reason + ": " + e.Error()})
+		}
+	}
+	return violations
+}
+
+func flattenSchemaErrors(err error) []error {
+	// MultiError.As matches any of the errors it contains, so errors.As cannot be used to unwrap it
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var out []error
+		for _, e := range multi {
+			out = append(out, flattenSchemaErrors(e)...)
+		}
+		return out
+	}
+	return []error{err}
+}
+
+func toJSONPointer(path []string) string {
+	var sb strings.Builder
+	for _, p := range path {
+		sb.WriteString("/")
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(p))
+	}
+	return sb.String()
+}
+
+func validateJSONSchema(schema string, body []byte) ([]ContractViolation, error) {
+	// yaml is a superset of json, so both can be used for the schema
+	var schemaObj interface{}
+	if err := yaml.Unmarshal([]byte(schema), &schemaObj); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schemaObj), gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate against json schema: %w", err)
+	}
+
+	var violations []ContractViolation
+	for _, e := range result.Errors() {
+		path := strings.TrimPrefix(e.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+		violations = append(violations, ContractViolation{Path: "/body" + path, Message: e.Description()})
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations, nil
+}
@@ -0,0 +1,161 @@
+package checks
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOpenAPIDocument = `
+openapi: 3.0.0
+info:
+  title: users
+  version: "1"
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema: {type: string}
+      responses:
+        "200":
+          description: ok
+          headers:
+            X-Rate-Limit:
+              required: true
+              schema: {type: integer}
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id: {type: integer}
+                  name: {type: string}
+                  tags: {type: array, items: {type: string}}
+`
+
+func TestValidateOpenAPI(t *testing.T) {
+	header := http.Header{"Content-Type": {"application/json"}, "X-Rate-Limit": {"10"}}
+
+	tests := []struct {
+		name        string
+		operationID string
+		response    *http.Response
+		body        string
+		expected    []ContractViolation
+	}{
+		{
+			name:     "valid",
+			response: &http.Response{StatusCode: 200, Header: header},
+			body:     `{"id": 1, "name": "a", "tags": ["x"]}`,
+		},
+		{
+			name:        "body",
+			operationID: "getUser",
+			response:    &http.Response{StatusCode: 200, Header: header},
+			body:        `{"id": "1", "tags": [1]}`,
+			expected: []ContractViolation{
+				{Path: "/body/id", Message: "value must be an integer"},
+				{Path: "/body/tags/0", Message: "value must be a string"},
+				{Path: "/body/name", Message: `property "name" is missing`},
+			},
+		},
+		{
+			name:     "status",
+			response: &http.Response{StatusCode: 500, Header: http.Header{}},
+			expected: []ContractViolation{{Path: "/status", Message: "status is not supported"}},
+		},
+		{
+			name:     "header",
+			response: &http.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"application/json"}}},
+			body:     `{"id": 1, "name": "a"}`,
+			expected: []ContractViolation{{Path: "/headers", Message: `response header "X-Rate-Limit" missing`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := validateOpenAPI(gocontext.Background(), http.DefaultClient, testOpenAPIDocument, tt.operationID, "GET", "http://example.com/users/1", tt.response, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(violations, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, violations)
+			}
+		})
+	}
+}
+
+func TestValidateOpenAPIUnknownOperation(t *testing.T) {
+	_, err := validateOpenAPI(gocontext.Background(), http.DefaultClient, testOpenAPIDocument, "listUsers", "GET", "http://example.com/users/1", &http.Response{StatusCode: 200}, nil)
+	if err == nil {
+		t.Fatal("expected an error for an unknown operation")
+	}
+}
+
+func TestValidateOpenAPIIgnoresServers(t *testing.T) {
+	document := strings.Replace(testOpenAPIDocument, "paths:", "servers:\n  - url: https://api.example.com/v1\npaths:", 1)
+	header := http.Header{"Content-Type": {"application/json"}, "X-Rate-Limit": {"10"}}
+
+	for _, uri := range []string{"http://staging.internal/users/1", "http://10.0.0.1:8080/v1/users/1"} {
+		violations, err := validateOpenAPI(gocontext.Background(), http.DefaultClient, document, "", "GET", uri, &http.Response{StatusCode: 200, Header: header}, []byte(`{"id": 1, "name": "a"}`))
+		if err != nil {
+			t.Fatalf("expected %s to match an operation, got %v", uri, err)
+		}
+		if len(violations) > 0 {
+			t.Errorf("expected no violations for %s, got %v", uri, violations)
+		}
+	}
+}
+
+func TestLoadOpenAPIFromURLIsCached(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(testOpenAPIDocument))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := loadOpenAPI(gocontext.Background(), server.Client(), server.URL+"/openapi.yaml"); err != nil {
+			t.Fatalf("failed to load the document: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the document to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := `
+type: object
+required: [id]
+properties:
+  items:
+    type: array
+    items: {type: integer}
+`
+	violations, err := validateJSONSchema(schema, []byte(`{"items": [1, "a"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ContractViolation{
+		{Path: "/body", Message: "id is required"},
+		{Path: "/body/items/1", Message: "Invalid type. Expected: integer, given: string"},
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("expected %v, got %v", expected, violations)
+	}
+
+	violations, err = validateJSONSchema(schema, []byte(`{"id": 1, "items": [1, 2]}`))
+	if err != nil || len(violations) != 0 {
+		t.Errorf("expected no violations, got %v, %v", violations, err)
+	}
+}
@@ -0,0 +1,359 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	netHTTP "net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/flanksource/canary-checker/api/context"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+	"github.com/flanksource/commons/console"
+	"github.com/flanksource/commons/http/middlewares"
+	"github.com/flanksource/duty/models"
+	"github.com/ohler55/ojg/jp"
+)
+
+// HTTPStepResult is the outcome of a single step of a multi-step HTTP check
+type HTTPStepResult struct {
+	Name     string                 `json:"name"`
+	Method   string                 `json:"method"`
+	URL      string                 `json:"url"`
+	Code     int                    `json:"code,omitempty"`
+	Duration int64                  `json:"duration"`
+	Timings  map[string]interface{} `json:"timings,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// cookieJarMiddleware sends the cookies in the jar with every request and stores the cookies
+// set by the responses, so that sessions are carried across the steps of a check.
+// Redirects are followed here instead of by the client, as the cookies set by intermediate
+// redirect responses would otherwise never reach the jar.
+func cookieJarMiddleware(jar netHTTP.CookieJar, maxRedirects int) middlewares.Middleware {
+	return func(rt netHTTP.RoundTripper) netHTTP.RoundTripper {
+		return middlewares.RoundTripperFunc(func(req *netHTTP.Request) (*netHTTP.Response, error) {
+			for redirects := 0; ; redirects++ {
+				resp, err := roundTripWithJar(rt, jar, req)
+				if err != nil {
+					return nil, err
+				}
+				location := resp.Header.Get("Location")
+				if maxRedirects <= 0 || location == "" || !isRedirect(resp.StatusCode) {
+					return resp, nil
+				}
+				if redirects >= maxRedirects {
+					_ = resp.Body.Close()
+					return nil, fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				next, err := redirectRequest(req, resp.StatusCode, location)
+				if err != nil {
+					_ = resp.Body.Close()
+					return nil, err
+				}
+				if next == nil {
+					// the body of the request cannot be sent again
+					return resp, nil
+				}
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 2<<10))
+				_ = resp.Body.Close()
+				req = next
+			}
+		})
+	}
+}
+
+func roundTripWithJar(rt netHTTP.RoundTripper, jar netHTTP.CookieJar, req *netHTTP.Request) (*netHTTP.Response, error) {
+	if cookies := jar.Cookies(req.URL); len(cookies) > 0 {
+		req = req.Clone(req.Context())
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+	}
+	resp, err := rt.RoundTrip(req)
+	if err == nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			jar.SetCookies(req.URL, cookies)
+		}
+	}
+	return resp, err
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case netHTTP.StatusMovedPermanently, netHTTP.StatusFound, netHTTP.StatusSeeOther,
+		netHTTP.StatusTemporaryRedirect, netHTTP.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectRequest returns the request to the location of a redirect the way net/http builds it,
+// or nil when the body of a 307 or 308 redirect cannot be replayed
+func redirectRequest(req *netHTTP.Request, status int, location string) (*netHTTP.Request, error) {
+	target, err := req.URL.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the redirect location %q: %w", location, err)
+	}
+	next := req.Clone(req.Context())
+	next.URL = target
+	if target.Host != req.URL.Host {
+		next.Host = ""
+	}
+	if target.Hostname() != req.URL.Hostname() {
+		next.Header.Del("Authorization")
+		next.Header.Del("Cookie")
+	}
+
+	if status == netHTTP.StatusTemporaryRedirect || status == netHTTP.StatusPermanentRedirect {
+		if req.GetBody != nil {
+			if next.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		} else if req.Body != nil && req.Body != netHTTP.NoBody {
+			return nil, nil
+		}
+		return next, nil
+	}
+
+	if req.Method != netHTTP.MethodHead {
+		next.Method = netHTTP.MethodGet
+	}
+	next.Body, next.GetBody, next.ContentLength = nil, nil, 0
+	next.Header.Del("Content-Type")
+	next.Header.Del("Content-Length")
+	return next, nil
+}
+
+func (c *HTTPChecker) runSteps(ctx *context.Context, check v1.HTTPCheck, connection *models.Connection, results pkg.Results) pkg.Results {
+	result := results[0]
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return results.ErrorMessage(err)
+	}
+
+	vars := map[string]interface{}{}
+	steps := map[string]interface{}{}
+	var details []HTTPStepResult
+
+	defer func() {
+		result.AddDetails(details)
+		result.AddData(map[string]interface{}{
+			"steps": steps,
+			"vars":  vars,
+		})
+	}()
+
+	start := time.Now()
+	for i, step := range check.Steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		stepResult, data, err := c.runStep(ctx, check, connection, step, jar, vars, steps)
+		details = append(details, stepResult)
+		if data != nil {
+			steps[step.Name] = data
+		}
+		if err != nil {
+			result.Duration = time.Since(start).Milliseconds()
+			return results.Failf("step %s: %v", step.Name, err)
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+
+	return results
+}
+
+// runStep executes a single step, returning the response data that is made available to later steps
+func (c *HTTPChecker) runStep(ctx *context.Context, check v1.HTTPCheck, connection *models.Connection, step v1.HTTPStep, jar netHTTP.CookieJar, vars, steps map[string]interface{}) (HTTPStepResult, map[string]interface{}, error) {
+	stepResult := HTTPStepResult{Name: step.Name, Method: step.GetMethod()}
+
+	fail := func(err error) (HTTPStepResult, map[string]interface{}, error) {
+		stepResult.Error = err.Error()
+		return stepResult, nil, err
+	}
+
+	stepCtx := ctx.Clone()
+	stepCtx.Environment["vars"] = vars
+	stepCtx.Environment["steps"] = steps
+
+	stepURL, err := template(stepCtx, v1.Template{Template: step.URL})
+	if err != nil {
+		return fail(fmt.Errorf("failed to template url: %w", err))
+	}
+	if stepURL, err = resolveStepURL(check.URL, stepURL); err != nil {
+		return fail(err)
+	}
+	stepResult.URL = stepURL
+
+	body, err := template(stepCtx, v1.Template{Template: step.Body})
+	if err != nil {
+		return fail(fmt.Errorf("failed to template body: %w", err))
+	}
+
+	// the proxy is chosen based on the url of the check
+	check.URL = stepURL
+	timer := newHTTPTimer()
+	request, err := c.generateHTTPRequest(stepCtx, check, connection, timer, jar)
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, header := range step.Headers {
+		value, err := stepCtx.GetEnvValueFromCache(header, stepCtx.GetNamespace())
+		if err != nil {
+			return fail(fmt.Errorf("failed to get header %s: %w", header.Name, err))
+		}
+		if value, err = template(stepCtx, v1.Template{Template: value}); err != nil {
+			return fail(fmt.Errorf("failed to template header %s: %w", header.Name, err))
+		}
+		request.Header(header.Name, value)
+	}
+
+	if body != "" {
+		if err := request.Body(body); err != nil {
+			return fail(err)
+		}
+	}
+
+	ctx.Tracef("%s	%s	%s", console.Greenf("%s", step.GetMethod()), stepURL, step.Name)
+
+	start := time.Now()
+	response, err := request.Do(step.GetMethod(), stepURL)
+	if err != nil {
+		return fail(err)
+	}
+	responseBody, err := response.AsString()
+	timer.Done()
+	stepResult.Duration = time.Since(start).Milliseconds()
+	stepResult.Timings = timer.Millis()
+	if err != nil {
+		return fail(err)
+	}
+
+	status := response.StatusCode
+	stepResult.Code = status
+	responseStatus.WithLabelValues(strconv.Itoa(status), statusCodeToClass(status), stepURL).Inc()
+	timings := timer.Durations()
+	for _, phase := range httpPhases {
+		phaseDuration.WithLabelValues(phase, stepURL).Observe(float64(timings[phase].Microseconds()) / 1000)
+	}
+
+	data := map[string]interface{}{
+		"code":    status,
+		"headers": response.GetHeaders(),
+		"content": responseBody,
+		"json":    make(map[string]any),
+	}
+	if response.IsJSON() {
+		var jsonContent interface{}
+		if err := json.Unmarshal([]byte(responseBody), &jsonContent); err == nil {
+			data["json"] = jsonContent
+		}
+	}
+
+	if ok := response.IsOK(step.ResponseCodes...); !ok {
+		if len(step.ResponseCodes) == 0 {
+			err = fmt.Errorf("expected %d to be 200..299", status)
+		} else {
+			err = fmt.Errorf("expected %d to be in %v", status, step.ResponseCodes)
+		}
+		stepResult.Error = err.Error()
+		return stepResult, data, err
+	}
+
+	stepCtx.WithEnvValues(data)
+
+	for _, extract := range step.Extract {
+		value, err := extractStepValue(stepCtx, extract, data)
+		if err != nil {
+			err = fmt.Errorf("failed to extract %s: %w", extract.Name, err)
+			stepResult.Error = err.Error()
+			return stepResult, data, err
+		}
+		vars[extract.Name] = value
+	}
+
+	if step.Test != "" {
+		out, err := template(stepCtx, v1.Template{Expression: step.Test})
+		if err != nil {
+			err = fmt.Errorf("failed to evaluate test: %w", err)
+		} else if out != trueString {
+			err = fmt.Errorf("test failed: %s", step.Test)
+		}
+		if err != nil {
+			stepResult.Error = err.Error()
+			return stepResult, data, err
+		}
+	}
+
+	return stepResult, data, nil
+}
+
+// resolveStepURL resolves the url of a step against the url of the check
+func resolveStepURL(base, ref string) (string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid url '%s': %w", ref, err)
+	}
+	if !refURL.IsAbs() {
+		if base == "" {
+			return "", fmt.Errorf("relative url '%s' requires the url of the check to be set", ref)
+		}
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", fmt.Errorf("invalid url '%s': %w", base, err)
+		}
+		refURL = baseURL.ResolveReference(refURL)
+	}
+	if refURL.Host == "" {
+		return "", fmt.Errorf("invalid url, missing host '%s'", ref)
+	}
+	return refURL.String(), nil
+}
+
+func extractStepValue(ctx *context.Context, extract v1.HTTPStepExtract, data map[string]interface{}) (interface{}, error) {
+	switch {
+	case extract.JSONPath != "":
+		expr, err := jp.ParseString(extract.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonPath %s: %w", extract.JSONPath, err)
+		}
+		matches := expr.Get(data["json"])
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no match for %s", extract.JSONPath)
+		}
+		return matches[0], nil
+
+	case extract.Expr != "":
+		return template(ctx, v1.Template{Expression: extract.Expr})
+
+	case extract.Regex != "":
+		re, err := regexp.Compile(extract.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %w", extract.Regex, err)
+		}
+		match := re.FindStringSubmatch(data["content"].(string))
+		if match == nil {
+			return nil, fmt.Errorf("no match for %s", extract.Regex)
+		}
+		if len(match) > 1 {
+			return match[1], nil
+		}
+		return match[0], nil
+	}
+
+	return nil, fmt.Errorf("one of jsonPath, expr or regex is required")
+}
@@ -0,0 +1,149 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func TestCookieJarMiddlewareCarriesSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/profile":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Transport: cookieJarMiddleware(jar, 10)(http.DefaultTransport)}
+
+	response, err := client.Get(server.URL + "/profile")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 before login, got %d", response.StatusCode)
+	}
+
+	for _, path := range []string{"/login", "/profile"} {
+		response, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("expected %s to return 200, got %d", path, response.StatusCode)
+		}
+	}
+}
+
+func TestCookieJarMiddlewareStoresRedirectCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/profile", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/profile":
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Transport: cookieJarMiddleware(jar, 2)(http.DefaultTransport)}
+
+	response, err := client.Post(server.URL+"/login", "text/plain", strings.NewReader("user"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Request.URL.Path != "/profile" {
+		t.Fatalf("expected the redirect to /profile to send the session, got %d from %s", response.StatusCode, response.Request.URL)
+	}
+
+	if _, err := client.Get(server.URL + "/loop"); err == nil || !strings.Contains(err.Error(), "stopped after 2 redirects") {
+		t.Errorf("expected the redirects to be limited, got %v", err)
+	}
+}
+
+func TestResolveStepURL(t *testing.T) {
+	tests := []struct {
+		base, ref, expected string
+		err                 bool
+	}{
+		{base: "https://example.com/api/", ref: "login", expected: "https://example.com/api/login"},
+		{base: "https://example.com/api/", ref: "/health", expected: "https://example.com/health"},
+		{base: "https://example.com", ref: "https://other.com/x", expected: "https://other.com/x"},
+		{base: "", ref: "https://other.com/x", expected: "https://other.com/x"},
+		{base: "", ref: "/login", err: true},
+	}
+
+	for _, tt := range tests {
+		out, err := resolveStepURL(tt.base, tt.ref)
+		if tt.err {
+			if err == nil {
+				t.Errorf("resolveStepURL(%q, %q) expected an error, got %s", tt.base, tt.ref, out)
+			}
+			continue
+		}
+		if err != nil || out != tt.expected {
+			t.Errorf("resolveStepURL(%q, %q) = %s, %v; expected %s", tt.base, tt.ref, out, err, tt.expected)
+		}
+	}
+}
+
+func TestExtractStepValue(t *testing.T) {
+	data := map[string]interface{}{
+		"content": `{"data": {"token": "s3cret", "items": [{"id": 1}, {"id": 2}]}}`,
+		"json": map[string]interface{}{
+			"data": map[string]interface{}{
+				"token": "s3cret",
+				"items": []interface{}{
+					map[string]interface{}{"id": float64(1)},
+					map[string]interface{}{"id": float64(2)},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		extract  v1.HTTPStepExtract
+		expected interface{}
+		err      bool
+	}{
+		{extract: v1.HTTPStepExtract{JSONPath: "$.data.token"}, expected: "s3cret"},
+		{extract: v1.HTTPStepExtract{JSONPath: "$.data.items[1].id"}, expected: float64(2)},
+		{extract: v1.HTTPStepExtract{JSONPath: "$.data.missing"}, err: true},
+		{extract: v1.HTTPStepExtract{Regex: `"token": "(\w+)"`}, expected: "s3cret"},
+		{extract: v1.HTTPStepExtract{Regex: `s3\w+`}, expected: "s3cret"},
+		{extract: v1.HTTPStepExtract{Regex: `nomatch`}, err: true},
+		{extract: v1.HTTPStepExtract{}, err: true},
+	}
+
+	for _, tt := range tests {
+		value, err := extractStepValue(nil, tt.extract, data)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%+v: expected an error, got %v", tt.extract, value)
+			}
+			continue
+		}
+		if err != nil || value != tt.expected {
+			t.Errorf("%+v: got %v, %v; expected %v", tt.extract, value, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package checks
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	httpPhaseDNS       = "dns"
+	httpPhaseConnect   = "connect"
+	httpPhaseTLS       = "tls"
+	httpPhaseFirstByte = "firstByte"
+	httpPhaseTransfer  = "transfer"
+	httpPhaseTotal     = "total"
+)
+
+// httpPhases are the phases of an HTTP request in the order in which they occur
+var httpPhases = []string{httpPhaseDNS, httpPhaseConnect, httpPhaseTLS, httpPhaseFirstByte, httpPhaseTransfer}
+
+// httpTimer records the duration of each phase of an HTTP request using httptrace.
+// Durations of requests that are redirected are summed across all the requests.
+type httpTimer struct {
+	mu           sync.Mutex
+	start        time.Time
+	end          time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	durations    map[string]time.Duration
+}
+
+func newHTTPTimer() *httpTimer {
+	return &httpTimer{durations: make(map[string]time.Duration)}
+}
+
+// WithContext returns a context that reports the events of requests made with it to the timer
+func (t *httpTimer) WithContext(ctx gocontext.Context) gocontext.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// the request starts when the first connection is requested
+			if t.start.IsZero() {
+				t.start = time.Now()
+			}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.since(httpPhaseDNS, t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.since(httpPhaseConnect, t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(httpPhaseTLS, t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+			t.since(httpPhaseFirstByte, t.wroteRequest)
+		},
+	})
+}
+
+func (t *httpTimer) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+}
+
+func (t *httpTimer) since(phase string, start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !start.IsZero() {
+		t.durations[phase] += time.Since(start)
+	}
+}
+
+// Done marks the end of the request once the response body has been read
+func (t *httpTimer) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end = time.Now()
+	if !t.firstByte.IsZero() {
+		t.durations[httpPhaseTransfer] = t.end.Sub(t.firstByte)
+	}
+	if !t.start.IsZero() {
+		t.durations[httpPhaseTotal] = t.end.Sub(t.start)
+	}
+}
+
+// Durations returns the duration of every phase, phases that did not occur
+// e.g. dns and tls when a connection is reused, have a duration of 0
+func (t *httpTimer) Durations() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]time.Duration, len(httpPhases)+1)
+	for _, phase := range append(httpPhases, httpPhaseTotal) {
+		out[phase] = t.durations[phase]
+	}
+	return out
+}
+
+// Millis returns the duration of every phase in milliseconds
+func (t *httpTimer) Millis() map[string]interface{} {
+	out := make(map[string]interface{})
+	for phase, d := range t.Durations() {
+		out[phase] = float64(d.Microseconds()) / 1000
+	}
+	return out
+}
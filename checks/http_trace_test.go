@@ -0,0 +1,49 @@
+package checks
+
+import (
+	gocontext "context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPTimerRecordsPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	timer := newHTTPTimer()
+	request, err := http.NewRequestWithContext(timer.WithContext(gocontext.Background()), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, _ = io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	timer.Done()
+
+	durations := timer.Durations()
+	if durations[httpPhaseTLS] <= 0 || durations[httpPhaseConnect] <= 0 {
+		t.Fatalf("expected connect and tls phases to be recorded, got %v", durations)
+	}
+	if durations[httpPhaseFirstByte] < 20*time.Millisecond {
+		t.Fatalf("expected firstByte to include the server processing time, got %v", durations[httpPhaseFirstByte])
+	}
+	if durations[httpPhaseTotal] < durations[httpPhaseFirstByte] {
+		t.Fatalf("expected total %v to be at least firstByte %v", durations[httpPhaseTotal], durations[httpPhaseFirstByte])
+	}
+
+	millis := timer.Millis()
+	for _, phase := range append(httpPhases, httpPhaseTotal) {
+		if _, ok := millis[phase]; !ok {
+			t.Fatalf("expected %s in timings, got %v", phase, millis)
+		}
+	}
+}
@@ -57,14 +57,45 @@ func (c *IcmpChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 	result := pkg.Success(check, ctx.Canary)
 	results = append(results, result)
 
+	families, err := ipFamilies(check.IPFamily)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if len(families) > 1 {
+		return checkEachFamily(check.GetName(), families, func(name, family string) pkg.Results {
+			familyCheck := check
+			familyCheck.Name = name
+			familyCheck.IPFamily = family
+			return c.Check(ctx, familyCheck)
+		})
+	}
+	family := ipFamilyIPv4
+	if len(families) == 1 {
+		family = families[0]
+	}
+
 	endpoint := check.Endpoint
-	ips, err := dns.Lookup("A", endpoint)
+	recordType := "A"
+	if family == ipFamilyIPv6 {
+		recordType = "AAAA"
+	}
+	ips, err := dns.Lookup(recordType, endpoint)
 	if err != nil {
 		return results.ErrorMessage(err)
 	}
 
 	for _, urlObj := range ips {
-		pingerStats, err := c.checkICMP(urlObj, check.PacketCount)
+		if len(families) == 0 && urlObj.To4() == nil {
+			// an IPv6 endpoint is pinged over IPv6 unless another family is requested
+			family = ipFamilyIPv6
+		}
+		if !isFamily(urlObj, family) {
+			return results.Failf("%s is not an %s address", urlObj, family)
+		}
+		if check.Path != nil {
+			return c.checkPath(check, urlObj, results)
+		}
+		pingerStats, err := c.checkICMP(ctx, urlObj, familyNetwork("ip", family), check.PacketCount)
 		if err != nil {
 			return results.ErrorMessage(err)
 		}
@@ -83,7 +114,7 @@ func (c *IcmpChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 			return results.Failf("timeout after %d ", latency)
 		}
 		if check.PacketLossThreshold < int64(loss*100) {
-			return results.Failf("%s packet loss of %0.0f%% > than threshold of %d%%", urlObj.To4(), loss, check.PacketLossThreshold)
+			return results.Failf("%s packet loss of %0.0f%% > than threshold of %d%%", urlObj, loss, check.PacketLossThreshold)
 		}
 
 		packetLoss.WithLabelValues(endpoint, ips[0].String()).Set(loss)
@@ -93,18 +124,19 @@ func (c *IcmpChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 	return results.Failf("no IP found for %s", endpoint)
 }
 
-func (c *IcmpChecker) checkICMP(ip net.IP, packetCount int) (*ping.Statistics, error) {
+func (c *IcmpChecker) checkICMP(ctx *context.Context, ip net.IP, network string, packetCount int) (*ping.Statistics, error) {
 	pinger, err := ping.NewPinger(ip.String())
 	if err != nil {
 		return nil, err
 	}
-	pinger.SetNetwork("ip4")
+	pinger.SetNetwork(network)
 	pinger.SetPrivileged(PRIVILEGED)
 	if packetCount == 0 {
 		packetCount = 5
 	}
 	pinger.Count = packetCount
 	pinger.Timeout = time.Second * 10
-	err = pinger.Run()
+	// stop pinging once the check is cancelled or times out
+	err = pinger.RunWithContext(ctx)
 	return pinger.Statistics(), err
 }
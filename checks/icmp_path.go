@@ -0,0 +1,556 @@
+package checks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+)
+
+var (
+	hopLoss = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_icmp_hop_packetloss",
+			Help: "Packet loss percentage of a hop on the path to the endpoint",
+		},
+		[]string{"endpoint", "hop", "ip"},
+	)
+	hopRTT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_icmp_hop_rtt_seconds",
+			Help: "Median round trip time of a hop on the path to the endpoint",
+		},
+		[]string{"endpoint", "hop", "ip"},
+	)
+	pathLength = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_icmp_path_length",
+			Help: "Number of hops to the endpoint",
+		},
+		[]string{"endpoint"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(hopLoss, hopRTT, pathLength)
+}
+
+const (
+	protocolICMP = 1
+	protocolTCP  = 6
+	protocolUDP  = 17
+)
+
+// ICMPPathResult is the path to the endpoint, it is added to the details of the result
+type ICMPPathResult struct {
+	Destination string `json:"destination"`
+	Protocol    string `json:"protocol"`
+	// Reached is whether the destination replied to the probes
+	Reached bool `json:"reached"`
+	// Length is the number of hops to the destination, 0 if it was not reached
+	Length int       `json:"length"`
+	Hops   []ICMPHop `json:"hops"`
+}
+
+type ICMPHop struct {
+	TTL int `json:"ttl"`
+	// Address that replied first, empty if the hop did not reply
+	Address string `json:"address,omitempty"`
+	// Addresses that replied when the probes took different routes
+	Addresses []string `json:"addresses,omitempty"`
+	Sent      int      `json:"sent"`
+	Received  int      `json:"received"`
+	// Loss in percent
+	Loss float64 `json:"loss"`
+	// RTT are the round trip times in milliseconds
+	RTT *ICMPHopRTT `json:"rtt,omitempty"`
+}
+
+type ICMPHopRTT struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func (c *IcmpChecker) checkPath(check v1.ICMPCheck, ip net.IP, results pkg.Results) pkg.Results {
+	result := results[0]
+	path := *check.Path
+	if path.Protocol == "" {
+		path.Protocol = "icmp"
+	}
+	if path.Protocol != "icmp" && path.Protocol != "udp" && path.Protocol != "tcp" {
+		return results.Invalidf("invalid protocol %s, expected icmp, udp or tcp", path.Protocol)
+	}
+	timeout, err := path.Timeout.GetDurationOr(2 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid timeout %s", path.Timeout)
+	}
+	if path.MaxHops <= 0 {
+		path.MaxHops = 30
+	}
+	if path.MaxHops > 255 {
+		return results.Invalidf("invalid maxHops %d, the highest TTL is 255", path.MaxHops)
+	}
+	if ip.To4() == nil {
+		return results.Invalidf("path mode only supports IPv4, %s is not an IPv4 address", ip)
+	}
+	packetCount := check.PacketCount
+	if packetCount == 0 {
+		packetCount = 5
+	}
+
+	tracer := newPathTracer(ip.To4(), path.Protocol, path.Port, timeout)
+	if err := tracer.open(); err != nil {
+		return results.Failf("failed to trace the path to %s: %v", ip, err)
+	}
+	tracer.trace(packetCount, path.MaxHops)
+	tracer.close()
+
+	data := tracer.result()
+	result.AddDetails(data)
+
+	endpoint := check.Endpoint
+	// the route may have changed, so the hops of earlier traces are removed
+	hopLoss.DeletePartialMatch(prometheus.Labels{"endpoint": endpoint})
+	hopRTT.DeletePartialMatch(prometheus.Labels{"endpoint": endpoint})
+	for _, hop := range data.Hops {
+		if hop.Address == "" {
+			continue
+		}
+		labels := []string{endpoint, strconv.Itoa(hop.TTL), hop.Address}
+		hopLoss.WithLabelValues(labels...).Set(hop.Loss)
+		if hop.RTT != nil {
+			hopRTT.WithLabelValues(labels...).Set(hop.RTT.P50 / 1000)
+		}
+	}
+	pathLength.WithLabelValues(endpoint).Set(float64(data.Length))
+
+	if !data.Reached {
+		return results.Failf("%s did not reply within %d hops", ip, path.MaxHops)
+	}
+	destination := data.Hops[len(data.Hops)-1]
+	result.Duration = int64(destination.RTT.Avg)
+	if result.Duration == 0 {
+		// For submillisecond response times, round up to 1ms
+		result.Duration = 1
+	}
+	if path.MaxPathLength > 0 && data.Length > path.MaxPathLength {
+		return results.Failf("path to %s is %d hops, more than %d", ip, data.Length, path.MaxPathLength)
+	}
+	if path.HopLossThreshold > 0 {
+		for _, hop := range data.Hops {
+			if hop.Received > 0 && hop.Loss > float64(path.HopLossThreshold) {
+				return results.Failf("hop %d (%s) packet loss of %0.0f%% > than threshold of %d%%", hop.TTL, hop.Address, hop.Loss, path.HopLossThreshold)
+			}
+		}
+	}
+	if check.PacketLossThreshold > 0 && destination.Loss > float64(check.PacketLossThreshold) {
+		return results.Failf("%s packet loss of %0.0f%% > than threshold of %d%%", ip, destination.Loss, check.PacketLossThreshold)
+	}
+	if check.ThresholdMillis > 0 && result.Duration > check.ThresholdMillis {
+		return results.Failf("timeout after %d ", result.Duration)
+	}
+	packetLoss.WithLabelValues(endpoint, ip.String()).Set(destination.Loss)
+	return results
+}
+
+// pathProbe is a packet sent with a TTL, keyed by the ICMP sequence, UDP destination port or TCP sequence
+type pathProbe struct {
+	ttl     int
+	sent    time.Time
+	rtt     time.Duration
+	from    net.IP
+	replied bool
+	// reached is whether the destination replied instead of a hop
+	reached bool
+}
+
+// pathTracer sends TTL-stepped probes to a destination and matches the ICMP time exceeded messages of the
+// hops, and the reply of the destination, to them. It only supports IPv4
+type pathTracer struct {
+	dst      net.IP
+	protocol string
+	port     int
+	timeout  time.Duration
+	// id is the ICMP echo id, or the source port of TCP probes
+	id int
+
+	icmpConn *icmp.PacketConn
+	udpConn  *ipv4.PacketConn
+	tcpConn  *ipv4.PacketConn
+	src      net.IP
+	srcPort  int
+
+	mu      sync.Mutex
+	probes  map[int]*pathProbe
+	replies chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newPathTracer(dst net.IP, protocol string, port int, timeout time.Duration) *pathTracer {
+	if port == 0 && protocol == "tcp" {
+		port = 80
+	} else if port == 0 {
+		port = 33434
+	}
+	return &pathTracer{
+		dst:      dst,
+		protocol: protocol,
+		port:     port,
+		timeout:  timeout,
+		id:       32768 + rand.Intn(28000), // nolint:gosec
+		probes:   make(map[int]*pathProbe),
+		replies:  make(chan struct{}, 1),
+	}
+}
+
+// open listens for ICMP messages, and opens the connection the probes are sent with
+func (t *pathTracer) open() error {
+	var err error
+	if t.icmpConn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0"); err != nil {
+		return fmt.Errorf("failed to listen for icmp messages, CAP_NET_RAW is required: %w", err)
+	}
+	switch t.protocol {
+	case "udp":
+		conn, err := net.ListenPacket("udp4", "0.0.0.0:0")
+		if err != nil {
+			t.close()
+			return err
+		}
+		t.udpConn = ipv4.NewPacketConn(conn)
+		t.srcPort = conn.LocalAddr().(*net.UDPAddr).Port
+	case "tcp":
+		// the source address is needed for the checksum of the TCP header
+		conn, err := net.Dial("udp4", net.JoinHostPort(t.dst.String(), strconv.Itoa(t.port)))
+		if err != nil {
+			t.close()
+			return err
+		}
+		t.src = conn.LocalAddr().(*net.UDPAddr).IP.To4()
+		_ = conn.Close()
+		raw, err := net.ListenPacket("ip4:tcp", "0.0.0.0")
+		if err != nil {
+			t.close()
+			return err
+		}
+		t.tcpConn = ipv4.NewPacketConn(raw)
+		t.srcPort = t.id
+		t.wg.Add(1)
+		go t.receiveTCP()
+	}
+	t.wg.Add(1)
+	go t.receiveICMP()
+	return nil
+}
+
+func (t *pathTracer) close() {
+	if t.icmpConn != nil {
+		_ = t.icmpConn.Close()
+	}
+	if t.udpConn != nil {
+		_ = t.udpConn.Close()
+	}
+	if t.tcpConn != nil {
+		_ = t.tcpConn.Close()
+	}
+	t.wg.Wait()
+}
+
+// trace sends a probe to every TTL in each round and waits for the replies of the round
+func (t *pathTracer) trace(rounds, maxHops int) {
+	for round := 0; round < rounds; round++ {
+		for ttl := 1; ttl <= maxHops; ttl++ {
+			key := round*maxHops + ttl
+			t.mu.Lock()
+			t.probes[key] = &pathProbe{ttl: ttl, sent: time.Now()}
+			t.mu.Unlock()
+			// probes that fail to be sent are lost
+			_ = t.send(key, ttl)
+		}
+		deadline := time.After(t.timeout)
+		for waiting := true; waiting && !t.roundReplied(round, maxHops); {
+			select {
+			case <-t.replies:
+			case <-deadline:
+				waiting = false
+			}
+		}
+	}
+}
+
+// roundReplied returns whether every hop up to the destination replied to the probes of the round
+func (t *pathTracer) roundReplied(round, maxHops int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		probe := t.probes[round*maxHops+ttl]
+		if !probe.replied {
+			return false
+		}
+		if probe.reached {
+			return true
+		}
+	}
+	return true
+}
+
+func (t *pathTracer) send(key, ttl int) error {
+	switch t.protocol {
+	case "udp":
+		if err := t.udpConn.SetTTL(ttl); err != nil {
+			return err
+		}
+		_, err := t.udpConn.WriteTo([]byte("canary-checker"), nil, &net.UDPAddr{IP: t.dst, Port: t.port + key})
+		return err
+	case "tcp":
+		if err := t.tcpConn.SetTTL(ttl); err != nil {
+			return err
+		}
+		_, err := t.tcpConn.WriteTo(t.tcpSYN(uint32(key)), nil, &net.IPAddr{IP: t.dst})
+		return err
+	}
+	message := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: t.id, Seq: key, Data: []byte("canary-checker")},
+	}
+	packet, err := message.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if err := t.icmpConn.IPv4PacketConn().SetTTL(ttl); err != nil {
+		return err
+	}
+	_, err = t.icmpConn.WriteTo(packet, &net.IPAddr{IP: t.dst})
+	return err
+}
+
+// tcpSYN returns a TCP header with the SYN flag and the key as the sequence number
+func (t *pathTracer) tcpSYN(seq uint32) []byte {
+	header := make([]byte, 20)
+	binary.BigEndian.PutUint16(header[0:], uint16(t.srcPort))
+	binary.BigEndian.PutUint16(header[2:], uint16(t.port))
+	binary.BigEndian.PutUint32(header[4:], seq)
+	header[12] = 5 << 4 // data offset of 5 words
+	header[13] = 0x02   // SYN
+	binary.BigEndian.PutUint16(header[14:], 65535)
+
+	pseudo := make([]byte, 0, 12+len(header))
+	pseudo = append(pseudo, t.src...)
+	pseudo = append(pseudo, t.dst...)
+	pseudo = append(pseudo, 0, protocolTCP, 0, byte(len(header)))
+	pseudo = append(pseudo, header...)
+	var sum uint32
+	for i := 0; i < len(pseudo); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(pseudo[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	binary.BigEndian.PutUint16(header[16:], ^uint16(sum))
+	return header
+}
+
+func (t *pathTracer) receiveICMP() {
+	defer t.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := t.icmpConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if key, ok := t.matchICMP(buf[:n]); ok {
+			from := peer.(*net.IPAddr).IP
+			t.reply(key, from, from.Equal(t.dst))
+		}
+	}
+}
+
+// matchICMP returns the key of the probe an ICMP message replies to
+func (t *pathTracer) matchICMP(packet []byte) (int, bool) {
+	message, err := icmp.ParseMessage(protocolICMP, packet)
+	if err != nil {
+		return 0, false
+	}
+	var quote []byte
+	switch body := message.Body.(type) {
+	case *icmp.Echo:
+		if message.Type != ipv4.ICMPTypeEchoReply || t.protocol != "icmp" || body.ID != t.id {
+			return 0, false
+		}
+		return body.Seq, true
+	case *icmp.TimeExceeded:
+		quote = body.Data
+	case *icmp.DstUnreach:
+		quote = body.Data
+	default:
+		return 0, false
+	}
+
+	// the quote is the IPv4 header of the probe followed by at least 8 bytes of its payload
+	if len(quote) < ipv4.HeaderLen {
+		return 0, false
+	}
+	headerLen := int(quote[0]&0x0f) * 4
+	if len(quote) < headerLen+8 || !net.IP(quote[16:20]).Equal(t.dst) {
+		return 0, false
+	}
+	payload := quote[headerLen:]
+	switch {
+	case quote[9] == protocolICMP && t.protocol == "icmp":
+		if int(binary.BigEndian.Uint16(payload[4:])) != t.id {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(payload[6:])), true
+	case quote[9] == protocolUDP && t.protocol == "udp":
+		if int(binary.BigEndian.Uint16(payload[0:])) != t.srcPort {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(payload[2:])) - t.port, true
+	case quote[9] == protocolTCP && t.protocol == "tcp":
+		if int(binary.BigEndian.Uint16(payload[0:])) != t.srcPort {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint32(payload[4:])), true
+	}
+	return 0, false
+}
+
+func (t *pathTracer) receiveTCP() {
+	defer t.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, _, peer, err := t.tcpConn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if key, ok := t.matchTCP(buf[:n]); ok {
+			t.reply(key, peer.(*net.IPAddr).IP, true)
+		}
+	}
+}
+
+// matchTCP returns the key of the probe a SYN-ACK or RST of the destination replies to
+func (t *pathTracer) matchTCP(header []byte) (int, bool) {
+	if len(header) < 20 ||
+		int(binary.BigEndian.Uint16(header[0:])) != t.port ||
+		int(binary.BigEndian.Uint16(header[2:])) != t.srcPort {
+		return 0, false
+	}
+	flags := header[13]
+	synAck := flags&0x12 == 0x12
+	rst := flags&0x04 != 0
+	if !synAck && !rst {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(header[8:]) - 1), true
+}
+
+func (t *pathTracer) reply(key int, from net.IP, reached bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	probe, ok := t.probes[key]
+	if !ok || probe.replied {
+		return
+	}
+	probe.replied, probe.rtt, probe.from, probe.reached = true, time.Since(probe.sent), from, reached
+	select {
+	case t.replies <- struct{}{}:
+	default:
+	}
+}
+
+// result summarizes the probes of every hop up to the destination, or up to the last hop that replied
+func (t *pathTracer) result() ICMPPathResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := ICMPPathResult{Destination: t.dst.String(), Protocol: t.protocol, Hops: []ICMPHop{}}
+	last := 0
+	for _, probe := range t.probes {
+		if probe.reached && (result.Length == 0 || probe.ttl < result.Length) {
+			result.Length = probe.ttl
+		}
+		if probe.replied {
+			last = max(last, probe.ttl)
+		}
+	}
+	result.Reached = result.Length > 0
+	if result.Reached {
+		last = result.Length
+	}
+
+	keys := make([]int, 0, len(t.probes))
+	for key := range t.probes {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	for ttl := 1; ttl <= last; ttl++ {
+		hop := ICMPHop{TTL: ttl}
+		var rtts []time.Duration
+		for _, key := range keys {
+			probe := t.probes[key]
+			if probe.ttl != ttl {
+				continue
+			}
+			hop.Sent++
+			if !probe.replied {
+				continue
+			}
+			hop.Received++
+			rtts = append(rtts, probe.rtt)
+			if hop.Address == "" {
+				hop.Address = probe.from.String()
+			}
+			if address := probe.from.String(); !lo.Contains(hop.Addresses, address) {
+				hop.Addresses = append(hop.Addresses, address)
+			}
+		}
+		if len(hop.Addresses) < 2 {
+			hop.Addresses = nil
+		}
+		if hop.Sent > 0 {
+			hop.Loss = float64(hop.Sent-hop.Received) / float64(hop.Sent) * 100
+		}
+		hop.RTT = newICMPHopRTT(rtts)
+		result.Hops = append(result.Hops, hop)
+	}
+	return result
+}
+
+func newICMPHopRTT(rtts []time.Duration) *ICMPHopRTT {
+	if len(rtts) == 0 {
+		return nil
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	var total time.Duration
+	for _, rtt := range rtts {
+		total += rtt
+	}
+	// nearest rank percentile
+	percentile := func(p float64) float64 {
+		rank := int(p/100*float64(len(rtts))+0.5) - 1
+		return millis(rtts[min(max(rank, 0), len(rtts)-1)])
+	}
+	return &ICMPHopRTT{
+		Min: millis(rtts[0]),
+		Avg: millis(total / time.Duration(len(rtts))),
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: millis(rtts[len(rtts)-1]),
+	}
+}
@@ -0,0 +1,160 @@
+package checks
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// newTestTimeExceeded returns a time exceeded message quoting a probe to dst with the first 8 bytes of its payload
+func newTestTimeExceeded(t *testing.T, protocol int, dst net.IP, payload []byte) []byte {
+	header, err := (&ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(payload),
+		TTL:      1,
+		Protocol: protocol,
+		Src:      net.ParseIP("192.0.2.1"),
+		Dst:      dst,
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := (&icmp.Message{
+		Type: ipv4.ICMPTypeTimeExceeded,
+		Body: &icmp.TimeExceeded{Data: append(header, payload...)},
+	}).Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestPathTracerMatch(t *testing.T) {
+	dst := net.ParseIP("192.0.2.10").To4()
+	quote := func(first, second uint16, third uint32) []byte {
+		payload := make([]byte, 8)
+		binary.BigEndian.PutUint16(payload[0:], first)
+		binary.BigEndian.PutUint16(payload[2:], second)
+		binary.BigEndian.PutUint32(payload[4:], third)
+		return payload
+	}
+
+	tracer := newPathTracer(dst, "icmp", 0, time.Second)
+	echo := quote(uint16(ipv4.ICMPTypeEcho)<<8, 0, uint32(tracer.id)<<16|7)
+	if key, ok := tracer.matchICMP(newTestTimeExceeded(t, protocolICMP, dst, echo)); !ok || key != 7 {
+		t.Errorf("expected the echo request 7 to be matched, got %d %v", key, ok)
+	}
+	reply, _ := (&icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: tracer.id, Seq: 9}}).Marshal(nil)
+	if key, ok := tracer.matchICMP(reply); !ok || key != 9 {
+		t.Errorf("expected the echo reply 9 to be matched, got %d %v", key, ok)
+	}
+	other := quote(uint16(ipv4.ICMPTypeEcho)<<8, 0, uint32(tracer.id+1)<<16|7)
+	if _, ok := tracer.matchICMP(newTestTimeExceeded(t, protocolICMP, dst, other)); ok {
+		t.Error("expected the echo request of another tracer to be ignored")
+	}
+	if _, ok := tracer.matchICMP(newTestTimeExceeded(t, protocolICMP, net.ParseIP("192.0.2.11"), echo)); ok {
+		t.Error("expected a probe to another destination to be ignored")
+	}
+
+	tracer = newPathTracer(dst, "udp", 0, time.Second)
+	tracer.srcPort = 40000
+	if key, ok := tracer.matchICMP(newTestTimeExceeded(t, protocolUDP, dst, quote(40000, 33434+12, 0))); !ok || key != 12 {
+		t.Errorf("expected the udp probe 12 to be matched, got %d %v", key, ok)
+	}
+
+	tracer = newPathTracer(dst, "tcp", 443, time.Second)
+	tracer.srcPort = tracer.id
+	if key, ok := tracer.matchICMP(newTestTimeExceeded(t, protocolTCP, dst, quote(uint16(tracer.id), 443, 3))); !ok || key != 3 {
+		t.Errorf("expected the tcp probe 3 to be matched, got %d %v", key, ok)
+	}
+	synAck := make([]byte, 20)
+	binary.BigEndian.PutUint16(synAck[0:], 443)
+	binary.BigEndian.PutUint16(synAck[2:], uint16(tracer.id))
+	binary.BigEndian.PutUint32(synAck[8:], 4)
+	synAck[13] = 0x12
+	if key, ok := tracer.matchTCP(synAck); !ok || key != 3 {
+		t.Errorf("expected the syn-ack to be matched to probe 3, got %d %v", key, ok)
+	}
+}
+
+func TestPathTracerResult(t *testing.T) {
+	tracer := newPathTracer(net.ParseIP("192.0.2.10").To4(), "icmp", 0, time.Second)
+	start := time.Now()
+	replies := map[int]string{
+		// round 1
+		1: "10.0.0.1", 3: "192.0.2.10", 4: "192.0.2.10",
+		// round 2, the second hop never replies
+		5: "10.0.0.1", 7: "192.0.2.10", 8: "192.0.2.10",
+		// round 3, the first hop is load balanced
+		9: "10.0.0.2", 11: "192.0.2.10", 12: "192.0.2.10",
+	}
+	for key := 1; key <= 16; key++ {
+		tracer.probes[key] = &pathProbe{ttl: (key-1)%4 + 1, sent: start}
+	}
+	for key, from := range replies {
+		tracer.reply(key, net.ParseIP(from), from == "192.0.2.10")
+	}
+
+	result := tracer.result()
+	if !result.Reached || result.Length != 3 || len(result.Hops) != 3 {
+		t.Fatalf("expected the destination to be 3 hops away, got %+v", result)
+	}
+	first, second, destination := result.Hops[0], result.Hops[1], result.Hops[2]
+	if first.Address != "10.0.0.1" || strings.Join(first.Addresses, ",") != "10.0.0.1,10.0.0.2" || first.Sent != 4 || first.Received != 3 || first.Loss != 25 {
+		t.Errorf("expected the first hop to lose 1 of 4 probes over 2 routes, got %+v", first)
+	}
+	if second.Address != "" || second.Received != 0 || second.Loss != 100 || second.RTT != nil {
+		t.Errorf("expected the second hop to not reply, got %+v", second)
+	}
+	if destination.Address != "192.0.2.10" || destination.Loss != 25 || destination.RTT == nil || destination.RTT.Min > destination.RTT.P90 {
+		t.Errorf("expected the destination to reply to 3 probes, got %+v", destination)
+	}
+
+	rtt := newICMPHopRTT([]time.Duration{4 * time.Millisecond, time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond})
+	if *rtt != (ICMPHopRTT{Min: 1, Avg: 2.5, P50: 2, P90: 4, P99: 4, Max: 4}) {
+		t.Errorf("unexpected round trip times %+v", rtt)
+	}
+}
+
+func TestICMPCheckerPath(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	for _, path := range []v1.ICMPPath{
+		{Protocol: "icmp"},
+		{Protocol: "udp"},
+		{Protocol: "tcp", Port: port},
+	} {
+		path.MaxHops, path.Timeout, path.MaxPathLength = 3, "1s", 1
+		check := v1.ICMPCheck{
+			Description: v1.Description{Name: "path"},
+			Endpoint:    "127.0.0.1",
+			PacketCount: 2,
+			Path:        &path,
+		}
+		results := (&IcmpChecker{}).Check(newRetryTestContext(nil), check)
+		if strings.Contains(results[0].Error, "CAP_NET_RAW is required") {
+			t.Skipf("raw sockets are not permitted: %s", results[0].Error)
+		}
+		if !results[0].Pass {
+			t.Errorf("%s: expected the check to pass, got %s", path.Protocol, results[0].Error)
+			continue
+		}
+		trace := results[0].Detail.(ICMPPathResult)
+		if !trace.Reached || trace.Length != 1 || trace.Hops[0].Address != "127.0.0.1" || trace.Hops[0].Received != 2 {
+			t.Errorf("%s: expected the destination to be the first hop, got %+v", path.Protocol, trace)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package checks
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xdg-go/scram"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/duty/models"
+)
+
+var (
+	kafkaConsumerGroupLagGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_kafka_consumer_group_lag",
+			Help: "The number of messages a consumer group is behind on a partition",
+		},
+		[]string{"brokers", "group", "topic", "partition"},
+	)
+	kafkaConsumerGroupTotalLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_kafka_consumer_group_total_lag",
+			Help: "The number of messages a consumer group is behind across all its partitions",
+		},
+		[]string{"brokers", "group"},
+	)
+	kafkaConsumerGroupMaxLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_kafka_consumer_group_max_lag",
+			Help: "The highest number of messages a consumer group is behind on any of its partitions",
+		},
+		[]string{"brokers", "group"},
+	)
+	kafkaUnderReplicatedPartitions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_kafka_under_replicated_partitions",
+			Help: "The number of partitions with fewer in sync replicas than replicas",
+		},
+		[]string{"brokers"},
+	)
+	kafkaOfflinePartitions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_kafka_offline_partitions",
+			Help: "The number of partitions without a leader",
+		},
+		[]string{"brokers"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(kafkaConsumerGroupLagGauge, kafkaConsumerGroupTotalLag, kafkaConsumerGroupMaxLag,
+		kafkaUnderReplicatedPartitions, kafkaOfflinePartitions)
+}
+
+type KafkaChecker struct{}
+
+// Type: returns checker type
+func (c *KafkaChecker) Type() string {
+	return "kafka"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *KafkaChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.Kafka {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// KafkaCheckResult is the data of a kafka check, partitions are identified as topic/partition
+type KafkaCheckResult struct {
+	Brokers         int                               `json:"brokers"`
+	Topics          int                               `json:"topics"`
+	Partitions      int                               `json:"partitions"`
+	UnderReplicated []string                          `json:"underReplicated"`
+	Offline         []string                          `json:"offline"`
+	ConsumerGroups  map[string]*KafkaConsumerGroupLag `json:"consumerGroups,omitempty"`
+}
+
+type KafkaConsumerGroupLag struct {
+	TotalLag int64 `json:"totalLag"`
+	MaxLag   int64 `json:"maxLag"`
+	// Partitions is the lag of every partition the group has committed an offset to
+	Partitions map[string]int64 `json:"partitions"`
+}
+
+func (c *KafkaChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.KafkaCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	brokers := check.Brokers
+	if len(brokers) == 0 {
+		brokers = kafkaBrokers(connection.URL)
+	}
+	if len(brokers) == 0 {
+		return results.Invalidf("brokers or a url is required")
+	}
+	timeout, err := check.RequestTimeout.GetDurationOr(30 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid request timeout %s", check.RequestTimeout)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	config, err := newKafkaConfig(ctx, check, connection, timeout)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if proxy != nil {
+		config.Net.Proxy.Enable = true
+		config.Net.Proxy.Dialer = kafkaDialer{ctx: ctx, proxy: proxy}
+	}
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return results.Failf("failed to connect to %s: %v", strings.Join(brokers, ","), err)
+	}
+	// closing the admin closes the client
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return results.Failf("failed to create the cluster admin: %v", err)
+	}
+	defer admin.Close()
+
+	data := KafkaCheckResult{Brokers: len(client.Brokers())}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	endpoint := check.GetEndpoint()
+
+	// an empty list of topics requests all of them
+	metadata, err := admin.DescribeTopics(check.Topics)
+	if err != nil {
+		return results.Failf("failed to describe topics: %v", err)
+	}
+	var failures []string
+	for _, topic := range metadata {
+		if len(check.Topics) == 0 && topic.IsInternal {
+			continue
+		}
+		if topic.Err != sarama.ErrNoError {
+			failures = append(failures, fmt.Sprintf("topic %s: %v", topic.Name, topic.Err))
+			continue
+		}
+		data.Topics++
+		data.addPartitions(topic)
+	}
+	kafkaUnderReplicatedPartitions.WithLabelValues(endpoint).Set(float64(len(data.UnderReplicated)))
+	kafkaOfflinePartitions.WithLabelValues(endpoint).Set(float64(len(data.Offline)))
+	if len(data.Offline) > 0 {
+		failures = append(failures, fmt.Sprintf("%d offline partitions: %s", len(data.Offline), strings.Join(data.Offline, ", ")))
+	}
+	if len(data.UnderReplicated) > 0 {
+		failures = append(failures, fmt.Sprintf("%d under replicated partitions: %s", len(data.UnderReplicated), strings.Join(data.UnderReplicated, ", ")))
+	}
+
+	for _, group := range check.ConsumerGroups {
+		lag, err := kafkaConsumerGroupLag(client, admin, group)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("consumer group %s: %v", group, err))
+			continue
+		}
+		if data.ConsumerGroups == nil {
+			data.ConsumerGroups = map[string]*KafkaConsumerGroupLag{}
+		}
+		data.ConsumerGroups[group] = lag
+		kafkaConsumerGroupTotalLag.WithLabelValues(endpoint, group).Set(float64(lag.TotalLag))
+		kafkaConsumerGroupMaxLag.WithLabelValues(endpoint, group).Set(float64(lag.MaxLag))
+		for partition, partitionLag := range lag.Partitions {
+			i := strings.LastIndex(partition, "/")
+			kafkaConsumerGroupLagGauge.WithLabelValues(endpoint, group, partition[:i], partition[i+1:]).Set(float64(partitionLag))
+		}
+
+		if check.MaxTotalLag > 0 && lag.TotalLag > check.MaxTotalLag {
+			failures = append(failures, fmt.Sprintf("consumer group %s has a total lag of %d > %d", group, lag.TotalLag, check.MaxTotalLag))
+		}
+		if check.MaxPartitionLag > 0 && lag.MaxLag > check.MaxPartitionLag {
+			failures = append(failures, fmt.Sprintf("consumer group %s has a partition lag of %d > %d", group, lag.MaxLag, check.MaxPartitionLag))
+		}
+	}
+
+	if len(failures) > 0 {
+		return results.Failf("%s", strings.Join(failures, "; "))
+	}
+	return results
+}
+
+// kafkaBrokers returns the brokers of a comma separated url, with or without a kafka:// scheme
+func kafkaBrokers(url string) []string {
+	var brokers []string
+	for _, broker := range strings.Split(url, ",") {
+		broker = strings.TrimPrefix(strings.TrimSpace(broker), "kafka://")
+		if broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+func newKafkaConfig(ctx *context.Context, check v1.KafkaCheck, connection *models.Connection, timeout time.Duration) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	config.ClientID = "canary-checker"
+	config.Net.DialTimeout = timeout
+	config.Net.ReadTimeout = timeout
+	config.Net.WriteTimeout = timeout
+	config.Admin.Timeout = timeout
+	config.Metadata.Retry.Max = 1
+
+	if check.TLSConfig.Enabled() {
+		tlsConfig, err := check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls config: %w", err)
+		}
+		tlsConfig.MinVersion = tls.VersionTLS12
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if connection.Username == "" {
+		if check.SASLMechanism != "" {
+			return nil, fmt.Errorf("a username is required for %s", check.SASLMechanism)
+		}
+		return config, nil
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = connection.Username
+	config.Net.SASL.Password = connection.Password
+	switch mechanism := sarama.SASLMechanism(strings.ToUpper(check.SASLMechanism)); mechanism {
+	case "", sarama.SASLTypePlaintext:
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		hash := scram.SHA256
+		if mechanism == sarama.SASLTypeSCRAMSHA512 {
+			hash = scram.SHA512
+		}
+		config.Net.SASL.Mechanism = mechanism
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &kafkaSCRAMClient{hash: hash}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sasl mechanism %s, expected one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", check.SASLMechanism)
+	}
+	return config, nil
+}
+
+// addPartitions counts the partitions of the topic, recording those without a leader or with replicas
+// out of sync
+func (data *KafkaCheckResult) addPartitions(topic *sarama.TopicMetadata) {
+	slices.SortFunc(topic.Partitions, func(a, b *sarama.PartitionMetadata) int {
+		return int(a.ID - b.ID)
+	})
+	for _, partition := range topic.Partitions {
+		data.Partitions++
+		name := fmt.Sprintf("%s/%d", topic.Name, partition.ID)
+		if partition.Leader < 0 || partition.Err == sarama.ErrLeaderNotAvailable {
+			data.Offline = append(data.Offline, name)
+		} else if len(partition.Isr) < len(partition.Replicas) || len(partition.OfflineReplicas) > 0 {
+			data.UnderReplicated = append(data.UnderReplicated, name)
+		}
+	}
+}
+
+// kafkaConsumerGroupLag returns the lag of the group on the partitions it has committed offsets to
+func kafkaConsumerGroupLag(client sarama.Client, admin sarama.ClusterAdmin, group string) (*KafkaConsumerGroupLag, error) {
+	offsets, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offsets.Err != sarama.ErrNoError {
+		return nil, offsets.Err
+	}
+
+	lag := &KafkaConsumerGroupLag{Partitions: map[string]int64{}}
+	for topic, partitions := range offsets.Blocks {
+		for partition, block := range partitions {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("%s/%d: %w", topic, partition, block.Err)
+			}
+			// -1 is returned for partitions without a committed offset
+			if block.Offset < 0 {
+				continue
+			}
+			newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the offset of %s/%d: %w", topic, partition, err)
+			}
+			partitionLag := max(newest-block.Offset, 0)
+			lag.Partitions[fmt.Sprintf("%s/%d", topic, partition)] = partitionLag
+			lag.TotalLag += partitionLag
+			lag.MaxLag = max(lag.MaxLag, partitionLag)
+		}
+	}
+	if len(lag.Partitions) == 0 {
+		return nil, errors.New("no committed offsets")
+	}
+	return lag, nil
+}
+
+// kafkaDialer connects to the brokers through the proxy
+type kafkaDialer struct {
+	ctx   gocontext.Context
+	proxy *checkProxy
+}
+
+func (d kafkaDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.proxy.DialContext(d.ctx, network, addr)
+}
+
+type kafkaSCRAMClient struct {
+	hash         scram.HashGeneratorFcn
+	conversation *scram.ClientConversation
+}
+
+func (c *kafkaSCRAMClient) Begin(username, password, authzID string) error {
+	client, err := c.hash.NewClient(username, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *kafkaSCRAMClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *kafkaSCRAMClient) Done() bool {
+	return c.conversation.Done()
+}
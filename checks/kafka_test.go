@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func newTestKafkaBroker(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker).
+			SetCoordinator(sarama.CoordinatorGroup, "unknown", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("billing", "orders", 0, 90, "", sarama.ErrNoError).
+			SetOffset("billing", "orders", 1, 40, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("orders", 0, sarama.OffsetNewest, 100).
+			SetOffset("orders", 1, sarama.OffsetNewest, 100),
+	})
+	return broker
+}
+
+func TestKafkaChecker(t *testing.T) {
+	broker := newTestKafkaBroker(t)
+	check := v1.KafkaCheck{
+		Description:    v1.Description{Name: "kafka"},
+		Connection:     v1.Connection{URL: "kafka://" + broker.Addr()},
+		ConsumerGroups: []string{"billing"},
+	}
+	results := (&KafkaChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["brokers"] != float64(1) || data["topics"] != float64(1) || data["partitions"] != float64(2) {
+		t.Errorf("expected 1 broker with 1 topic and 2 partitions, got %v", data)
+	}
+	lag := data["consumerGroups"].(map[string]any)["billing"].(map[string]any)
+	if lag["totalLag"] != float64(70) || lag["maxLag"] != float64(60) {
+		t.Errorf("expected a total lag of 70 and max lag of 60, got %v", lag)
+	}
+
+	check.MaxTotalLag = 50
+	check.MaxPartitionLag = 60
+	results = (&KafkaChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "consumer group billing has a total lag of 70 > 50" {
+		t.Errorf("expected the total lag to fail, got %s", results[0].Error)
+	}
+
+	check.MaxTotalLag = 0
+	check.Topics = []string{"missing"}
+	check.ConsumerGroups = []string{"billing", "unknown"}
+	results = (&KafkaChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "topic missing") || !strings.Contains(results[0].Error, "consumer group unknown: no committed offsets") {
+		t.Errorf("expected the missing topic and group to fail, got %s", results[0].Error)
+	}
+}
+
+func TestKafkaPartitionHealth(t *testing.T) {
+	var data KafkaCheckResult
+	data.addPartitions(&sarama.TopicMetadata{Name: "orders", Partitions: []*sarama.PartitionMetadata{
+		{ID: 2, Leader: -1, Replicas: []int32{1, 2}, Err: sarama.ErrLeaderNotAvailable},
+		{ID: 1, Leader: 2, Replicas: []int32{1, 2, 3}, Isr: []int32{2, 3}},
+		{ID: 0, Leader: 1, Replicas: []int32{1, 2}, Isr: []int32{1, 2}},
+		{ID: 3, Leader: 1, Replicas: []int32{1, 2}, Isr: []int32{1, 2}, OfflineReplicas: []int32{2}},
+	}})
+	if data.Partitions != 4 || !slices.Equal(data.Offline, []string{"orders/2"}) || !slices.Equal(data.UnderReplicated, []string{"orders/1", "orders/3"}) {
+		t.Errorf("expected orders/2 offline and orders/1 and orders/3 under replicated, got %+v", data)
+	}
+}
+
+func TestKafkaBrokers(t *testing.T) {
+	brokers := kafkaBrokers("kafka://kafka-0:9092, kafka-1:9092,")
+	if !slices.Equal(brokers, []string{"kafka-0:9092", "kafka-1:9092"}) {
+		t.Errorf("unexpected brokers %v", brokers)
+	}
+}
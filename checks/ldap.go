@@ -1,7 +1,11 @@
 package checks
 
 import (
+	gocontext "context"
 	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
 
 	"github.com/flanksource/canary-checker/api/context"
 
@@ -47,10 +51,16 @@ func (c *LdapChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 		return results.Failf("Must specify a connection or URL")
 	}
 
-	ld, err := ldap.DialURL(connection.URL, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: check.SkipTLSVerify}))
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+
+	ld, err := dialLDAP(ctx, proxy, connection.URL, &tls.Config{InsecureSkipVerify: check.SkipTLSVerify})
 	if err != nil {
 		return results.Failf("Failed to connect %v", err)
 	}
+	defer ld.Close()
 
 	if err := ld.Bind(connection.Username, connection.Password); err != nil {
 		return results.Failf("Failed to bind using %s %v", connection.Username, err)
@@ -72,3 +82,43 @@ func (c *LdapChecker) Check(ctx *context.Context, extConfig external.Check) pkg.
 
 	return results
 }
+
+// dialLDAP connects to an ldap:// or ldaps:// url through the proxy, other urls are only supported
+// without a proxy
+func dialLDAP(ctx gocontext.Context, proxy *checkProxy, rawURL string, tlsConfig *tls.Config) (*ldap.Conn, error) {
+	if proxy == nil {
+		return ldap.DialURL(rawURL, ldap.DialWithTLSConfig(tlsConfig))
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = ldap.DefaultLdapPort
+		}
+	case "ldaps":
+		if port == "" {
+			port = ldap.DefaultLdapsPort
+		}
+	default:
+		return nil, fmt.Errorf("%s urls cannot be connected to through a proxy", u.Scheme)
+	}
+
+	dialCtx, cancel := gocontext.WithTimeout(ctx, ldap.DefaultTimeout)
+	defer cancel()
+	if u.Scheme == "ldap" {
+		tlsConfig = nil
+	}
+	conn, err := proxy.DialTLSContext(dialCtx, "tcp", net.JoinHostPort(u.Hostname(), port), tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ld := ldap.NewConn(conn, u.Scheme == "ldaps")
+	ld.Start()
+	return ld, nil
+}
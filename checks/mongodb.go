@@ -2,18 +2,46 @@ package checks
 
 import (
 	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/flanksource/canary-checker/api/context"
 	"github.com/flanksource/canary-checker/api/external"
+	"github.com/prometheus/client_golang/prometheus"
 
 	v1 "github.com/flanksource/canary-checker/api/v1"
 	"github.com/flanksource/canary-checker/pkg"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
+var (
+	mongoReplicationLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_mongodb_replication_lag_seconds",
+			Help: "The number of seconds a secondary is behind the primary of its replica set",
+		},
+		[]string{"replicaSet", "member"},
+	)
+	mongoMemberHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_mongodb_member_healthy",
+			Help: "1 if a replica set member is a healthy primary, secondary or arbiter, 0 otherwise",
+		},
+		[]string{"replicaSet", "member"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(mongoReplicationLag, mongoMemberHealthy)
+}
+
 type MongoDBChecker struct {
 }
 
@@ -29,6 +57,43 @@ func (c *MongoDBChecker) Run(ctx *context.Context) pkg.Results {
 	return results
 }
 
+// MongoDBDetails are the results of a mongodb check
+type MongoDBDetails struct {
+	Documents  []map[string]any        `json:"documents,omitempty"`
+	Count      int                     `json:"count"`
+	ReplicaSet *MongoReplicaSetDetails `json:"replicaSet,omitempty"`
+}
+
+type MongoReplicaSetDetails struct {
+	Name         string                  `json:"name"`
+	Primary      string                  `json:"primary,omitempty"`
+	ElectionTime *time.Time              `json:"electionTime,omitempty"`
+	Members      []MongoReplicaSetMember `json:"members"`
+}
+
+type MongoReplicaSetMember struct {
+	Name string `json:"name"`
+	// State is the state of the member, e.g. PRIMARY, SECONDARY or RECOVERING
+	State   string `json:"state"`
+	Healthy bool   `json:"healthy"`
+	// LagSeconds is how far a secondary is behind the primary
+	LagSeconds float64 `json:"lagSeconds,omitempty"`
+}
+
+// mongoReplSetStatus is the part of the replSetGetStatus response the check uses
+type mongoReplSetStatus struct {
+	Set     string               `bson:"set"`
+	Members []mongoReplSetMember `bson:"members"`
+}
+
+type mongoReplSetMember struct {
+	Name         string    `bson:"name"`
+	Health       float64   `bson:"health"`
+	StateStr     string    `bson:"stateStr"`
+	OptimeDate   time.Time `bson:"optimeDate"`
+	ElectionDate time.Time `bson:"electionDate"`
+}
+
 func (c *MongoDBChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
 	check := extConfig.(v1.MongoDBCheck)
 	result := pkg.Success(check, ctx.Canary)
@@ -41,12 +106,57 @@ func (c *MongoDBChecker) Check(ctx *context.Context, extConfig external.Check) p
 		return results.Failf("error getting connection: %v", err)
 	}
 
+	connectTimeout, err := check.ConnectTimeout.GetDurationOr(3 * time.Second)
+	if err != nil || connectTimeout <= 0 {
+		return results.Invalidf("invalid connect timeout %s", check.ConnectTimeout)
+	}
+	timeout, err := check.QueryTimeout.GetDurationOr(5 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid query timeout %s", check.QueryTimeout)
+	}
+	var maxLag, minElectionAge time.Duration
+	if check.ReplicaSet != nil {
+		if maxLag, err = check.ReplicaSet.MaxReplicationLag.GetDurationOr(0); err != nil {
+			return results.Invalidf("invalid max replication lag %s", check.ReplicaSet.MaxReplicationLag)
+		}
+		if minElectionAge, err = check.ReplicaSet.MinElectionAge.GetDurationOr(0); err != nil {
+			return results.Invalidf("invalid min election age %s", check.ReplicaSet.MinElectionAge)
+		}
+	}
+
+	database := check.Database
+	if database == "" {
+		if cs, err := connstring.Parse(connection.URL); err == nil {
+			database = cs.Database
+		}
+	}
+	filter, pipeline, err := parseMongoQuery(check)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if (filter != nil || pipeline != nil) && database == "" {
+		return results.Invalidf("database is required to query")
+	}
+	limit := check.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	pipeline = limitMongoPipeline(pipeline, limit)
+
 	opts := options.Client().
 		ApplyURI(connection.URL).
-		SetConnectTimeout(3 * time.Second).
-		SetSocketTimeout(3 * time.Second)
+		SetConnectTimeout(connectTimeout).
+		SetSocketTimeout(timeout)
 
-	_ctx, cancel := gocontext.WithTimeout(ctx, 5*time.Second)
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	if proxy != nil {
+		opts.SetDialer(proxy)
+	}
+
+	_ctx, cancel := gocontext.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	client, err := mongo.Connect(_ctx, opts)
@@ -60,5 +170,180 @@ func (c *MongoDBChecker) Check(ctx *context.Context, extConfig external.Check) p
 		return results.ErrorMessage(err)
 	}
 
+	if filter == nil && pipeline == nil && check.ReplicaSet == nil {
+		return results
+	}
+	var details MongoDBDetails
+	defer func() {
+		result.AddDetails(details)
+	}()
+
+	if filter != nil || pipeline != nil {
+		var cursor *mongo.Cursor
+		db := client.Database(database)
+		switch {
+		case pipeline != nil && check.Collection == "":
+			cursor, err = db.Aggregate(_ctx, pipeline)
+		case pipeline != nil:
+			cursor, err = db.Collection(check.Collection).Aggregate(_ctx, pipeline)
+		default:
+			cursor, err = db.Collection(check.Collection).Find(_ctx, filter, options.Find().SetLimit(limit))
+		}
+		if err != nil {
+			return results.Failf("failed to query %s: %v", database, err)
+		}
+		var documents []bson.Raw
+		if err := cursor.All(_ctx, &documents); err != nil {
+			return results.Failf("failed to read the documents: %v", err)
+		}
+		if details.Documents, err = mongoDocuments(documents); err != nil {
+			return results.ErrorMessage(err)
+		}
+		details.Count = len(details.Documents)
+	}
+
+	if check.ReplicaSet != nil {
+		var status mongoReplSetStatus
+		if err := client.Database("admin").RunCommand(_ctx, bson.D{{Key: "replSetGetStatus", Value: 1}}).Decode(&status); err != nil {
+			return results.Failf("failed to get the replica set status: %v", err)
+		}
+		details.ReplicaSet = status.details()
+		for _, member := range details.ReplicaSet.Members {
+			var healthy float64
+			if member.Healthy {
+				healthy = 1
+			}
+			mongoMemberHealthy.WithLabelValues(status.Set, member.Name).Set(healthy)
+			if member.State == "SECONDARY" {
+				mongoReplicationLag.WithLabelValues(status.Set, member.Name).Set(member.LagSeconds)
+			}
+		}
+		if failures := details.ReplicaSet.failures(check.ReplicaSet.MinHealthyMembers, maxLag, minElectionAge, time.Now()); len(failures) > 0 {
+			return results.Failf("%s", strings.Join(failures, "; "))
+		}
+	}
+
 	return results
 }
+
+// parseMongoQuery returns the filter of find or the pipeline of aggregate, both are nil without a query
+func parseMongoQuery(check v1.MongoDBCheck) (bson.D, []bson.D, error) {
+	switch {
+	case check.Find != "" && check.Aggregate != "":
+		return nil, nil, errors.New("only one of find or aggregate can be run")
+	case check.Find != "":
+		if check.Collection == "" {
+			return nil, nil, errors.New("collection is required to find documents")
+		}
+		var filter bson.D
+		if err := bson.UnmarshalExtJSON([]byte(check.Find), false, &filter); err != nil {
+			return nil, nil, fmt.Errorf("invalid find: %w", err)
+		}
+		if filter == nil {
+			filter = bson.D{}
+		}
+		return filter, nil, nil
+	case check.Aggregate != "":
+		// extended JSON can only be unmarshalled into a document, so the stages are wrapped in one
+		var wrapper struct {
+			Pipeline []bson.D `bson:"pipeline"`
+		}
+		if err := bson.UnmarshalExtJSON([]byte(`{"pipeline": `+check.Aggregate+`}`), false, &wrapper); err != nil {
+			return nil, nil, fmt.Errorf("invalid aggregate, expected an array of stages: %w", err)
+		}
+		if wrapper.Pipeline == nil {
+			wrapper.Pipeline = []bson.D{}
+		}
+		return nil, wrapper.Pipeline, nil
+	}
+	return nil, nil, nil
+}
+
+// limitMongoPipeline appends a $limit stage, unless the pipeline writes its documents to a collection
+func limitMongoPipeline(pipeline []bson.D, limit int64) []bson.D {
+	if pipeline == nil {
+		return nil
+	}
+	if n := len(pipeline); n > 0 && len(pipeline[n-1]) > 0 {
+		if stage := pipeline[n-1][0].Key; stage == "$out" || stage == "$merge" {
+			return pipeline
+		}
+	}
+	return append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+}
+
+// mongoDocuments converts documents to relaxed extended JSON, so that they can be used in tests
+func mongoDocuments(documents []bson.Raw) ([]map[string]any, error) {
+	out := make([]map[string]any, 0, len(documents))
+	for _, document := range documents {
+		data, err := bson.MarshalExtJSON(document, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert a document to json: %w", err)
+		}
+		var value map[string]any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to convert a document to json: %w", err)
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+func (s mongoReplSetStatus) details() *MongoReplicaSetDetails {
+	details := &MongoReplicaSetDetails{Name: s.Set, Members: []MongoReplicaSetMember{}}
+	var primaryOptime time.Time
+	for _, member := range s.Members {
+		if member.StateStr == "PRIMARY" {
+			details.Primary = member.Name
+			primaryOptime = member.OptimeDate
+			if !member.ElectionDate.IsZero() {
+				electionTime := member.ElectionDate
+				details.ElectionTime = &electionTime
+			}
+		}
+	}
+	for _, member := range s.Members {
+		healthy := member.Health == 1 && (member.StateStr == "PRIMARY" || member.StateStr == "SECONDARY" || member.StateStr == "ARBITER")
+		m := MongoReplicaSetMember{Name: member.Name, State: member.StateStr, Healthy: healthy}
+		if member.StateStr == "SECONDARY" && details.Primary != "" && primaryOptime.After(member.OptimeDate) {
+			m.LagSeconds = primaryOptime.Sub(member.OptimeDate).Seconds()
+		}
+		details.Members = append(details.Members, m)
+	}
+	return details
+}
+
+// failures returns why the replica set is not healthy, minHealthy defaults to all members
+func (d *MongoReplicaSetDetails) failures(minHealthy int, maxLag, minElectionAge time.Duration, now time.Time) []string {
+	var failures []string
+	if d.Primary == "" {
+		failures = append(failures, fmt.Sprintf("replica set %s has no primary", d.Name))
+	}
+
+	var healthy int
+	var unhealthy []string
+	for _, member := range d.Members {
+		if member.Healthy {
+			healthy++
+		} else {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is %s", member.Name, member.State))
+		}
+		lag := time.Duration(member.LagSeconds * float64(time.Second))
+		if maxLag > 0 && lag > maxLag {
+			failures = append(failures, fmt.Sprintf("%s is %s behind the primary, more than %s", member.Name, lag, maxLag))
+		}
+	}
+	if minHealthy == 0 {
+		minHealthy = len(d.Members)
+	}
+	if healthy < minHealthy {
+		failures = append(failures, fmt.Sprintf("%d of %d members are healthy, expected at least %d: %s", healthy, len(d.Members), minHealthy, strings.Join(unhealthy, ", ")))
+	}
+
+	if minElectionAge > 0 && d.ElectionTime != nil {
+		if age := now.Sub(*d.ElectionTime); age < minElectionAge {
+			failures = append(failures, fmt.Sprintf("%s was elected primary %s ago, less than %s", d.Primary, age.Round(time.Second), minElectionAge))
+		}
+	}
+	return failures
+}
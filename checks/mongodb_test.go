@@ -0,0 +1,104 @@
+package checks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func TestParseMongoQuery(t *testing.T) {
+	filter, pipeline, err := parseMongoQuery(v1.MongoDBCheck{Collection: "orders", Find: `{"status": "open", "total": {"$gt": 10}}`})
+	if err != nil || pipeline != nil || len(filter) != 2 || filter[0].Key != "status" {
+		t.Errorf("expected a filter on status and total, got %v, %v", filter, err)
+	}
+	filter, pipeline, err = parseMongoQuery(v1.MongoDBCheck{Aggregate: `[{"$match": {"status": "open"}}, {"$count": "open"}]`})
+	if err != nil || filter != nil || len(pipeline) != 2 || pipeline[1][0].Key != "$count" {
+		t.Errorf("expected a pipeline of 2 stages, got %v, %v", pipeline, err)
+	}
+	if filter, pipeline, err := parseMongoQuery(v1.MongoDBCheck{}); err != nil || filter != nil || pipeline != nil {
+		t.Errorf("expected no query, got %v, %v, %v", filter, pipeline, err)
+	}
+
+	for check, expected := range map[*v1.MongoDBCheck]string{
+		{Find: "{}"}:                                        "collection is required",
+		{Collection: "orders", Find: "{"}:                   "invalid find",
+		{Aggregate: `{"$match": {}}`}:                       "expected an array of stages",
+		{Collection: "orders", Find: "{}", Aggregate: "[]"}: "only one of find or aggregate",
+	} {
+		if _, _, err := parseMongoQuery(*check); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %+v to fail with %s, got %v", *check, expected, err)
+		}
+	}
+}
+
+func TestLimitMongoPipeline(t *testing.T) {
+	pipeline := limitMongoPipeline([]bson.D{{{Key: "$match", Value: bson.D{}}}}, 100)
+	if len(pipeline) != 2 || pipeline[1][0].Key != "$limit" || pipeline[1][0].Value != int64(100) {
+		t.Errorf("expected a $limit stage to be appended, got %v", pipeline)
+	}
+	if pipeline := limitMongoPipeline([]bson.D{{{Key: "$out", Value: "copy"}}}, 100); len(pipeline) != 1 {
+		t.Errorf("expected no stage after $out, got %v", pipeline)
+	}
+	if pipeline := limitMongoPipeline(nil, 100); pipeline != nil {
+		t.Errorf("expected find to not get a pipeline, got %v", pipeline)
+	}
+}
+
+func TestMongoDocuments(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw, err := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "count", Value: int64(3)}, {Key: "tags", Value: bson.A{"a", "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	documents, err := mongoDocuments([]bson.Raw{raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := documents[0]
+	if document["count"] != float64(3) || len(document["tags"].([]any)) != 2 {
+		t.Errorf("expected numbers and arrays to be plain json, got %v", document)
+	}
+	if document["_id"].(map[string]any)["$oid"] != id.Hex() {
+		t.Errorf("expected the object id in extended json, got %v", document["_id"])
+	}
+}
+
+func TestMongoReplicaSetFailures(t *testing.T) {
+	now := time.Now()
+	status := mongoReplSetStatus{Set: "rs0", Members: []mongoReplSetMember{
+		{Name: "mongo-0:27017", Health: 1, StateStr: "PRIMARY", OptimeDate: now, ElectionDate: now.Add(-time.Hour)},
+		{Name: "mongo-1:27017", Health: 1, StateStr: "SECONDARY", OptimeDate: now.Add(-30 * time.Second)},
+		{Name: "mongo-2:27017", StateStr: "(not reachable/healthy)"},
+	}}
+
+	details := status.details()
+	if details.Primary != "mongo-0:27017" || details.ElectionTime == nil || details.Members[1].LagSeconds != 30 {
+		t.Fatalf("expected the primary, election time and lag to be reported, got %+v", details)
+	}
+	if details.Members[0].LagSeconds != 0 || !details.Members[1].Healthy || details.Members[2].Healthy {
+		t.Errorf("unexpected members %+v", details.Members)
+	}
+
+	if failures := details.failures(2, time.Minute, 30*time.Minute, now); len(failures) != 0 {
+		t.Errorf("expected 2 healthy members within the thresholds to pass, got %v", failures)
+	}
+	failures := details.failures(0, 10*time.Second, 2*time.Hour, now)
+	expected := []string{
+		"mongo-1:27017 is 30s behind the primary, more than 10s",
+		"2 of 3 members are healthy, expected at least 3: mongo-2:27017 is (not reachable/healthy)",
+		"mongo-0:27017 was elected primary 1h0m0s ago, less than 2h0m0s",
+	}
+	if strings.Join(failures, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("expected %v, got %v", expected, failures)
+	}
+
+	status.Members[0].StateStr = "SECONDARY"
+	if failures := status.details().failures(1, 0, 0, now); len(failures) != 1 || failures[0] != "replica set rs0 has no primary" {
+		t.Errorf("expected a replica set without a primary to fail, got %v", failures)
+	}
+}
@@ -0,0 +1,274 @@
+package checks
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+var (
+	mqttConnectSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_mqtt_connect_seconds",
+			Help: "The number of seconds to connect to the broker",
+		},
+		[]string{"url"},
+	)
+	mqttRoundTripSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_mqtt_round_trip_seconds",
+			Help: "The number of seconds between publishing the probe message and receiving it",
+		},
+		[]string{"url", "topic", "qos"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(mqttConnectSeconds, mqttRoundTripSeconds)
+}
+
+type MQTTChecker struct{}
+
+// Type: returns checker type
+func (c *MQTTChecker) Type() string {
+	return "mqtt"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *MQTTChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.MQTT {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// MQTTCheckResult is the data of an mqtt check
+type MQTTCheckResult struct {
+	ConnectMillis int64 `json:"connectMillis"`
+	// PublishMillis is the time for the broker to acknowledge the probe message, for QoS 0 it is only
+	// the time to write it
+	PublishMillis int64 `json:"publishMillis"`
+	// ReceiveMillis is the time between publishing the probe message and receiving it
+	ReceiveMillis int64 `json:"receiveMillis"`
+	// QoS is the maximum QoS granted by the broker for the subscription
+	QoS int `json:"qos"`
+	// Retained is true when the broker delivered a retained message on subscribing to the topic
+	Retained        bool   `json:"retained"`
+	RetainedMessage string `json:"retainedMessage,omitempty"`
+}
+
+func (c *MQTTChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.MQTTCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	broker, err := mqttBrokerURL(connection.URL)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if check.Topic == "" || strings.ContainsAny(check.Topic, "+#") {
+		return results.Invalidf("a topic without wildcards is required")
+	}
+	qos := lo.FromPtrOr(check.QoS, 1)
+	if qos < 0 || qos > 2 {
+		return results.Invalidf("invalid qos %d, expected 0, 1 or 2", qos)
+	}
+	timeout, err := check.ProbeTimeout.GetDurationOr(30 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid probe timeout %s", check.ProbeTimeout)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	tlsConfig := &tls.Config{}
+	if check.TLSConfig != nil {
+		if tlsConfig, err = check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace()); err != nil {
+			return results.Invalidf("invalid tls config: %v", err)
+		}
+	}
+	tlsConfig.ServerName = broker.Hostname()
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	clientID := check.ClientID
+	if clientID == "" {
+		clientID = "canary-checker-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(broker.String()).
+		SetClientID(clientID).
+		SetUsername(connection.Username).
+		SetPassword(connection.Password).
+		SetTLSConfig(tlsConfig).
+		SetCleanSession(true).
+		SetAutoReconnect(false).
+		SetOrderMatters(false).
+		SetConnectTimeout(timeout).
+		SetWriteTimeout(timeout).
+		SetCustomOpenConnectionFn(func(uri *url.URL, _ mqtt.ClientOptions) (net.Conn, error) {
+			return openMQTTConnection(ctx, proxy, uri, tlsConfig, timeout)
+		})
+
+	var data MQTTCheckResult
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	endpoint := v1.SanitizeEndpoints(connection.URL)
+	start := time.Now()
+
+	client := mqtt.NewClient(options)
+	if err := waitMQTT(client.Connect(), timeout); err != nil {
+		return results.Failf("failed to connect to %s: %v", endpoint, err)
+	}
+	defer client.Disconnect(100)
+	connected := time.Since(start)
+	data.ConnectMillis = connected.Milliseconds()
+	mqttConnectSeconds.WithLabelValues(endpoint).Set(connected.Seconds())
+
+	// retained messages are delivered on subscribing, before the probe message is published
+	var retained struct {
+		sync.Mutex
+		message *string
+	}
+	payload := "canary-checker " + uuid.NewString()
+	received := make(chan time.Time, 1)
+	subscription := client.Subscribe(check.Topic, byte(qos), func(_ mqtt.Client, message mqtt.Message) {
+		if message.Retained() {
+			retained.Lock()
+			retained.message = lo.ToPtr(string(message.Payload()))
+			retained.Unlock()
+		} else if string(message.Payload()) == payload {
+			select {
+			case received <- time.Now():
+			default:
+			}
+		}
+	})
+	if err := waitMQTT(subscription, timeout); err != nil {
+		return results.Failf("failed to subscribe to %s: %v", check.Topic, err)
+	}
+	granted := subscription.(*mqtt.SubscribeToken).Result()[check.Topic]
+	if granted > 2 {
+		return results.Failf("subscription to %s was rejected", check.Topic)
+	}
+	data.QoS = int(granted)
+
+	published := time.Now()
+	if err := waitMQTT(client.Publish(check.Topic, byte(qos), false, payload), timeout); err != nil {
+		return results.Failf("failed to publish to %s: %v", check.Topic, err)
+	}
+	data.PublishMillis = time.Since(published).Milliseconds()
+
+	var roundTrip time.Duration
+	select {
+	case at := <-received:
+		roundTrip = at.Sub(published)
+	case <-time.After(timeout):
+		return results.Failf("probe message was not received on %s within %s", check.Topic, timeout)
+	case <-ctx.Done():
+		return results.Failf("probe message was not received on %s: %v", check.Topic, ctx.Err())
+	}
+	data.ReceiveMillis = roundTrip.Milliseconds()
+	mqttRoundTripSeconds.WithLabelValues(endpoint, check.Topic, strconv.Itoa(qos)).Set(roundTrip.Seconds())
+
+	retained.Lock()
+	if retained.message != nil {
+		data.Retained, data.RetainedMessage = true, *retained.message
+	}
+	retained.Unlock()
+
+	result.Duration = time.Since(start).Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(data.ReceiveMillis) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(roundTrip), check.ThresholdMillis)
+	}
+	return results
+}
+
+// mqttBrokerURL returns the url of the broker with the default port of its scheme, host[:port]
+// defaults to tcp://
+func mqttBrokerURL(endpoint string) (*url.URL, error) {
+	if endpoint == "" {
+		return nil, errors.New("a url or connection is required")
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "tcp://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %s", v1.SanitizeEndpoints(endpoint))
+	}
+	u.User = nil
+	var port string
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		port = "1883"
+	case "ssl", "tls", "mqtts":
+		port = "8883"
+	case "ws", "wss":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s, expected one of tcp, ssl, ws or wss", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
+
+// openMQTTConnection opens the connection to the broker through the proxy
+func openMQTTConnection(ctx gocontext.Context, proxy *checkProxy, uri *url.URL, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	switch uri.Scheme {
+	case "ws":
+		return mqtt.NewWebsocket(uri.String(), nil, timeout, nil, &mqtt.WebsocketOptions{Proxy: proxy.ProxyFunc})
+	case "wss":
+		return mqtt.NewWebsocket(uri.String(), tlsConfig, timeout, nil, &mqtt.WebsocketOptions{Proxy: proxy.ProxyFunc})
+	}
+
+	dialCtx, cancel := gocontext.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := proxy.DialContext(dialCtx, "tcp", uri.Host)
+	if err != nil {
+		return nil, err
+	}
+	if uri.Scheme == "tcp" || uri.Scheme == "mqtt" {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+func waitMQTT(token mqtt.Token, timeout time.Duration) error {
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return token.Error()
+}
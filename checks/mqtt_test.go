@@ -0,0 +1,233 @@
+package checks
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/flanksource/duty/types"
+	"github.com/gorilla/websocket"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// serveTestMQTT is a broker for a single client that receives its own messages, it accepts user:pass,
+// rejects subscriptions to denied, drops messages to blackhole and has a retained message on status
+func serveTestMQTT(conn io.ReadWriter) {
+	subscribed := map[string]bool{}
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		var reply packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			if p.Username != "user" || string(p.Password) != "pass" {
+				connack.ReturnCode = packets.ErrRefusedNotAuthorised
+			}
+			reply = connack
+		case *packets.SubscribePacket:
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			for i, topic := range p.Topics {
+				if topic == "denied" {
+					suback.ReturnCodes = append(suback.ReturnCodes, 0x80)
+					continue
+				}
+				subscribed[topic] = true
+				suback.ReturnCodes = append(suback.ReturnCodes, p.Qoss[i])
+			}
+			if suback.Write(conn) != nil {
+				return
+			}
+			if subscribed["status"] {
+				retained := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+				retained.TopicName, retained.Retain, retained.Payload = "status", true, []byte("online")
+				reply = retained
+			}
+		case *packets.PublishPacket:
+			switch p.Qos {
+			case 1:
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				reply = puback
+			case 2:
+				pubrec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				pubrec.MessageID = p.MessageID
+				reply = pubrec
+			}
+			if reply != nil && reply.Write(conn) != nil {
+				return
+			}
+			reply = nil
+			if subscribed[p.TopicName] && p.TopicName != "blackhole" {
+				delivery := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+				delivery.TopicName, delivery.Payload = p.TopicName, p.Payload
+				reply = delivery
+			}
+		case *packets.PubrelPacket:
+			pubcomp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			pubcomp.MessageID = p.MessageID
+			reply = pubcomp
+		case *packets.PingreqPacket:
+			reply = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if reply != nil && reply.Write(conn) != nil {
+			return
+		}
+	}
+}
+
+// testWebsocketConn reads and writes mqtt packets as binary websocket messages
+type testWebsocketConn struct {
+	*websocket.Conn
+	reader io.Reader
+}
+
+func (c *testWebsocketConn) Read(p []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			_, reader, err := c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			c.reader = reader
+		}
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *testWebsocketConn) Write(p []byte) (int, error) {
+	return len(p), c.WriteMessage(websocket.BinaryMessage, p)
+}
+
+func newTestMQTTCheck(url string) v1.MQTTCheck {
+	return v1.MQTTCheck{
+		Description: v1.Description{Name: "mqtt"},
+		Connection: v1.Connection{URL: url, Authentication: types.Authentication{
+			Username: types.EnvVar{ValueStatic: "user"},
+			Password: types.EnvVar{ValueStatic: "pass"},
+		}},
+		Topic:        "canary",
+		ProbeTimeout: "1s",
+	}
+}
+
+func TestMQTTChecker(t *testing.T) {
+	tlsServer, ca := newTLSTestServer(t)
+	tcp := newTestMailServer(t, func(conn net.Conn) { serveTestMQTT(conn) })
+	ssl := newTestMailServer(t, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, tlsServer.TLS)
+		defer tlsConn.Close()
+		serveTestMQTT(tlsConn)
+	})
+	upgrader := websocket.Upgrader{Subprotocols: []string{"mqtt"}}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveTestMQTT(&testWebsocketConn{Conn: conn})
+	}))
+	t.Cleanup(ws.Close)
+
+	check := newTestMQTTCheck("tcp://" + tcp)
+	check.Topic = "status"
+	results := (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["qos"] != float64(1) || data["retained"] != true || data["retainedMessage"] != "online" {
+		t.Errorf("expected qos 1 and the retained message, got %v", data)
+	}
+
+	qos2 := newTestMQTTCheck(tcp)
+	qos2.QoS = lo.ToPtr(2)
+	secure := newTestMQTTCheck("ssl://" + ssl)
+	secure.TLSConfig = &v1.TLSConfig{CA: ca}
+	websocketCheck := newTestMQTTCheck(strings.Replace(ws.URL, "http://", "ws://", 1) + "/mqtt")
+	websocketCheck.QoS = lo.ToPtr(0)
+	for name, check := range map[string]v1.MQTTCheck{"qos 2": qos2, "ssl": secure, "websocket": websocketCheck} {
+		results := (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+		if !results[0].Pass {
+			t.Errorf("%s: expected the check to pass, got %s", name, results[0].Error)
+			continue
+		}
+		qos := lo.FromPtrOr(check.QoS, 1)
+		if data := results[0].Data; data["qos"] != float64(qos) || data["retained"] != false {
+			t.Errorf("%s: expected qos %d without a retained message, got %v", name, qos, data)
+		}
+	}
+
+	secure.TLSConfig = nil
+	results = (&MQTTChecker{}).Check(newRetryTestContext(nil), secure)
+	if results[0].Pass || !strings.Contains(results[0].Error, "tls handshake failed") {
+		t.Errorf("expected the certificate to be untrusted, got %s", results[0].Error)
+	}
+}
+
+func TestMQTTCheckerFailures(t *testing.T) {
+	tcp := newTestMailServer(t, func(conn net.Conn) { serveTestMQTT(conn) })
+	check := newTestMQTTCheck(tcp)
+	check.Password = types.EnvVar{ValueStatic: "wrong"}
+	results := (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "not Authorized") {
+		t.Errorf("expected the credentials to be rejected, got %s", results[0].Error)
+	}
+
+	check = newTestMQTTCheck(tcp)
+	check.Topic = "denied"
+	results = (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "subscription to denied was rejected" {
+		t.Errorf("expected the subscription to be rejected, got %s", results[0].Error)
+	}
+
+	check.Topic, check.ProbeTimeout = "blackhole", "100ms"
+	results = (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "probe message was not received on blackhole within 100ms" {
+		t.Errorf("expected the probe message to be lost, got %s", results[0].Error)
+	}
+
+	check.Topic = "sensors/#"
+	results = (&MQTTChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Invalid {
+		t.Errorf("expected a wildcard topic to be invalid, got %s", results[0].Error)
+	}
+}
+
+func TestMQTTBrokerURL(t *testing.T) {
+	tests := map[string]string{
+		"broker.example.com":             "tcp://broker.example.com:1883",
+		"mqtt://broker.example.com:1884": "mqtt://broker.example.com:1884",
+		"ssl://user:pass@[2001:db8::1]":  "ssl://[2001:db8::1]:8883",
+		"wss://broker.example.com/mqtt":  "wss://broker.example.com/mqtt",
+	}
+	for endpoint, expected := range tests {
+		if u, err := mqttBrokerURL(endpoint); err != nil || u.String() != expected {
+			t.Errorf("mqttBrokerURL(%s) = %v, %v, expected %s", endpoint, u, err, expected)
+		}
+	}
+	if _, err := mqttBrokerURL("http://broker.example.com"); err == nil {
+		t.Error("expected an http url to be rejected")
+	}
+}
@@ -0,0 +1,167 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/metrics"
+)
+
+type NTPChecker struct{}
+
+// Type: returns checker type
+func (c *NTPChecker) Type() string {
+	return "ntp"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *NTPChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.NTP {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// NTPCheckResult is the data of an ntp check, durations are in milliseconds
+type NTPCheckResult struct {
+	Servers []NTPServerResult `json:"servers"`
+	// MaxOffset is the largest absolute offset of the local clock from a server
+	MaxOffset float64 `json:"maxOffset"`
+	// Disagreement is the difference between the highest and lowest offset of the servers
+	Disagreement float64 `json:"disagreement"`
+}
+
+type NTPServerResult struct {
+	Server string `json:"server"`
+	// Offset of the local clock from the server, positive when the local clock is behind
+	Offset      float64 `json:"offset"`
+	Delay       float64 `json:"delay"`
+	Stratum     uint8   `json:"stratum"`
+	Leap        string  `json:"leap"`
+	ReferenceID string  `json:"referenceId,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+func (c *NTPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.NTPCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	if len(check.Servers) == 0 {
+		return results.Invalidf("at least one server is required")
+	}
+	timeout, err := check.QueryTimeout.GetDurationOr(5 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid query timeout %s", check.QueryTimeout)
+	}
+	maxOffset, err := check.MaxOffset.GetDurationOr(0)
+	if err != nil || maxOffset < 0 {
+		return results.Invalidf("invalid maxOffset %s", check.MaxOffset)
+	}
+	maxDisagreement, err := check.MaxDisagreement.GetDurationOr(0)
+	if err != nil || maxDisagreement < 0 {
+		return results.Invalidf("invalid maxDisagreement %s", check.MaxDisagreement)
+	}
+
+	data := NTPCheckResult{Servers: make([]NTPServerResult, len(check.Servers))}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+
+	responses := make([]*ntp.Response, len(check.Servers))
+	var wg sync.WaitGroup
+	for i, server := range check.Servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data.Servers[i] = NTPServerResult{Server: server}
+			response, err := ntp.QueryWithOptions(server, ntp.QueryOptions{Timeout: timeout})
+			if err == nil {
+				err = response.Validate()
+			}
+			if response != nil {
+				data.Servers[i].setResponse(response)
+			}
+			if err != nil {
+				data.Servers[i].Error = err.Error()
+				return
+			}
+			responses[i] = response
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	var minOffset, maxServerOffset, maxAbsOffset time.Duration
+	var synced int
+	for i, response := range responses {
+		server := check.Servers[i]
+		if response == nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", server, data.Servers[i].Error))
+			continue
+		}
+		for name, value := range map[string]float64{
+			"canary_check_ntp_offset_seconds": response.ClockOffset.Seconds(),
+			"canary_check_ntp_delay_seconds":  response.RTT.Seconds(),
+			"canary_check_ntp_stratum":        float64(response.Stratum),
+		} {
+			result.AddMetric(pkg.Metric{
+				Name:   name,
+				Type:   metrics.GaugeType,
+				Labels: map[string]string{"server": server},
+				Value:  value,
+			})
+		}
+
+		offset := response.ClockOffset
+		if synced == 0 || offset < minOffset {
+			minOffset = offset
+		}
+		if synced == 0 || offset > maxServerOffset {
+			maxServerOffset = offset
+		}
+		maxAbsOffset = max(maxAbsOffset, offset.Abs())
+		synced++
+		if maxOffset > 0 && offset.Abs() > maxOffset {
+			failures = append(failures, fmt.Sprintf("%s: clock offset of %s exceeds %s", server, offset, maxOffset))
+		}
+	}
+	data.MaxOffset = millis(maxAbsOffset)
+	data.Disagreement = millis(maxServerOffset - minOffset)
+	if maxDisagreement > 0 && maxServerOffset-minOffset > maxDisagreement {
+		failures = append(failures, fmt.Sprintf("servers disagree by %s, more than %s", maxServerOffset-minOffset, maxDisagreement))
+	}
+
+	if len(failures) > 0 {
+		return results.Failf("%s", strings.Join(failures, "; "))
+	}
+	return results
+}
+
+func (r *NTPServerResult) setResponse(response *ntp.Response) {
+	r.Offset = millis(response.ClockOffset)
+	r.Delay = millis(response.RTT)
+	r.Stratum = response.Stratum
+	r.ReferenceID = response.ReferenceString()
+	switch response.Leap {
+	case ntp.LeapNoWarning:
+		r.Leap = "none"
+	case ntp.LeapAddSecond:
+		r.Leap = "add"
+	case ntp.LeapDelSecond:
+		r.Leap = "delete"
+	default:
+		r.Leap = "unsynchronized"
+	}
+}
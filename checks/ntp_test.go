@@ -0,0 +1,92 @@
+package checks
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func toTestNTPTime(t time.Time) uint64 {
+	d := t.Sub(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+	seconds := uint64(d / time.Second)
+	fraction := uint64(d%time.Second) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// newTestNTPServer replies with a clock that is offset from the local one, leap is the leap indicator
+func newTestNTPServer(t *testing.T, offset time.Duration, leap byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		request := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			now := time.Now().Add(offset)
+			reply := make([]byte, 48)
+			reply[0] = leap<<6 | 4<<3 | 4 // version 4, server mode
+			reply[1] = 2                  // stratum
+			reply[3] = 0xec               // precision
+			copy(reply[12:16], []byte{127, 0, 0, 1})
+			binary.BigEndian.PutUint64(reply[16:], toTestNTPTime(now.Add(-time.Minute)))
+			copy(reply[24:32], request[40:48])
+			binary.BigEndian.PutUint64(reply[32:], toTestNTPTime(now))
+			binary.BigEndian.PutUint64(reply[40:], toTestNTPTime(now))
+			_, _ = conn.WriteTo(reply, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNTPChecker(t *testing.T) {
+	synced := newTestNTPServer(t, 0, 0)
+	ahead := newTestNTPServer(t, 300*time.Millisecond, 0)
+	check := v1.NTPCheck{
+		Description:     v1.Description{Name: "ntp"},
+		Servers:         []string{synced, ahead},
+		MaxOffset:       "1s",
+		MaxDisagreement: "1s",
+	}
+	results := (&NTPChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	servers := data["servers"].([]any)
+	offset := servers[1].(map[string]any)["offset"].(float64)
+	if math.Abs(offset-300) > 50 || servers[1].(map[string]any)["stratum"] != float64(2) || servers[1].(map[string]any)["leap"] != "none" {
+		t.Errorf("expected an offset of 300ms at stratum 2, got %v", servers[1])
+	}
+	if math.Abs(data["disagreement"].(float64)-300) > 50 || data["maxOffset"] != offset {
+		t.Errorf("expected the servers to disagree by 300ms, got %v", data)
+	}
+	if len(results[0].Metrics) != 6 {
+		t.Errorf("expected an offset, delay and stratum gauge for each server, got %v", results[0].Metrics)
+	}
+
+	check.MaxOffset, check.MaxDisagreement = "100ms", "100ms"
+	results = (&NTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.HasPrefix(results[0].Error, ahead+": clock offset of") || !strings.Contains(results[0].Error, "exceeds 100ms; servers disagree by") {
+		t.Errorf("expected the offset and disagreement to exceed their thresholds, got %s", results[0].Error)
+	}
+
+	check.Servers = []string{synced, newTestNTPServer(t, 0, 3)}
+	check.MaxOffset, check.MaxDisagreement = "", ""
+	results = (&NTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "invalid leap second") {
+		t.Errorf("expected the unsynchronized server to fail, got %s", results[0].Error)
+	}
+	if leap := results[0].Data["servers"].([]any)[1].(map[string]any)["leap"]; leap != "unsynchronized" {
+		t.Errorf("expected the leap status to be reported, got %v", leap)
+	}
+}
@@ -48,6 +48,14 @@ func (t *OpenSearchChecker) Check(ctx *context.Context, extConfig external.Check
 		Addresses: []string{connection.URL},
 	}
 
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	if proxy != nil {
+		cfg.Transport = proxy.Transport()
+	}
+
 	osClient, err := opensearch.NewClient(cfg)
 	if err != nil {
 		return results.Failf("error creating the openSearch client: %v", err)
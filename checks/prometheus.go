@@ -50,7 +50,12 @@ func (c *PrometheusChecker) Check(ctx *context.Context, extConfig external.Check
 		return results.Failf("Must specify a URL")
 	}
 
-	promClient, err := prometheus.NewPrometheusAPI(ctx.Context, check.PrometheusConnection)
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+
+	promClient, err := prometheus.NewPrometheusAPI(ctx.Context, check.PrometheusConnection, proxy.ForURL(check.URL))
 	if err != nil {
 		return results.ErrorMessage(err)
 	}
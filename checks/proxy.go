@@ -0,0 +1,238 @@
+package checks
+
+import (
+	"bufio"
+	gocontext "context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	netHTTP "net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+
+	"github.com/flanksource/canary-checker/api/context"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// checkProxy is a resolved proxy configuration, a nil *checkProxy connects directly
+type checkProxy struct {
+	url     *url.URL
+	proxyFn func(*url.URL) (*url.URL, error)
+}
+
+// getProxy returns the proxy of the check, falling back to the proxy of the canary and then
+// the proxy.url and proxy.noProxy properties. It returns nil if no proxy is configured.
+func getProxy(ctx *context.Context, config *v1.Proxy) (*checkProxy, error) {
+	if config == nil {
+		config = ctx.Canary.Spec.Proxy
+	}
+	if config == nil {
+		if proxyURL := ctx.Properties().String("proxy.url", ""); proxyURL != "" {
+			config = &v1.Proxy{URL: proxyURL}
+			if noProxy := ctx.Properties().String("proxy.noProxy", ""); noProxy != "" {
+				config.NoProxy = strings.Split(noProxy, ",")
+			}
+		}
+	}
+	if config == nil || config.URL == "" {
+		return nil, nil
+	}
+	return newCheckProxy(ctx, *config)
+}
+
+func newCheckProxy(ctx *context.Context, config v1.Proxy) (*checkProxy, error) {
+	proxyURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %s: %w", config.URL, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s, expected one of http, https, socks5 or socks5h", proxyURL.Scheme)
+	}
+
+	username, err := ctx.GetEnvValueFromCache(config.Username, ctx.GetNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxy username: %w", err)
+	}
+	password, err := ctx.GetEnvValueFromCache(config.Password, ctx.GetNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxy password: %w", err)
+	}
+	if username != "" {
+		proxyURL.User = url.UserPassword(username, password)
+	}
+
+	return &checkProxy{
+		url: proxyURL,
+		proxyFn: (&httpproxy.Config{
+			HTTPProxy:  proxyURL.String(),
+			HTTPSProxy: proxyURL.String(),
+			NoProxy:    strings.Join(config.NoProxy, ","),
+		}).ProxyFunc(),
+	}, nil
+}
+
+// ForURL returns the proxy to use for the url, or nil if it should be connected to directly
+func (p *checkProxy) ForURL(target string) *url.URL {
+	if p == nil {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return p.url
+	}
+	proxyURL, _ := p.proxyFn(u)
+	return proxyURL
+}
+
+// ForAddress returns the proxy to use for a host:port, or nil if it should be connected to directly
+func (p *checkProxy) ForAddress(addr string) *url.URL {
+	return p.ForURL("https://" + addr)
+}
+
+// ProxyFunc can be used as the Proxy of an http.Transport
+func (p *checkProxy) ProxyFunc(r *netHTTP.Request) (*url.URL, error) {
+	return p.ForURL(r.URL.String()), nil
+}
+
+// Transport returns a clone of the default transport that uses the proxy
+func (p *checkProxy) Transport() *netHTTP.Transport {
+	transport := netHTTP.DefaultTransport.(*netHTTP.Transport).Clone()
+	transport.Proxy = p.ProxyFunc
+	return transport
+}
+
+// DialContext opens a tcp connection to addr through the proxy, using CONNECT for http(s) proxies
+func (p *checkProxy) DialContext(ctx gocontext.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	proxyURL := p.ForAddress(addr)
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, &dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	default:
+		return dialHTTPConnect(ctx, &dialer, proxyURL, addr)
+	}
+}
+
+// DialTLSContext is DialContext followed by a TLS handshake with addr when tlsConfig is set, for clients
+// that leave TLS to their dialer
+func (p *checkProxy) DialTLSContext(ctx gocontext.Context, network, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	conn, err := p.DialContext(ctx, network, addr)
+	if err != nil || tlsConfig == nil {
+		return conn, err
+	}
+	config := tlsConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// unsupportedProxy returns an error when a proxy applies to addr, but the client of the check cannot
+// connect through it, so that the check is not silently run without the proxy. The address is an
+// empty string when it cannot be determined, which is treated as going through the proxy.
+func unsupportedProxy(ctx *context.Context, checkType, addr string) error {
+	proxy, err := getProxy(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("invalid proxy: %w", err)
+	}
+	if proxy == nil {
+		return nil
+	}
+	if addr == "" || proxy.ForAddress(addr) != nil {
+		return fmt.Errorf("%s checks cannot connect through a proxy, add the host to the noProxy of the proxy", checkType)
+	}
+	return nil
+}
+
+func dialHTTPConnect(ctx gocontext.Context, dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyAddr, err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy tls handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+
+	request := &netHTTP.Request{
+		Method: netHTTP.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: netHTTP.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := request.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to write CONNECT to proxy: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := netHTTP.ReadResponse(reader, request)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy: %w", err)
+	}
+	// the body is not closed, as that would drain the tunnelled connection
+	if response.StatusCode != netHTTP.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, response.Status)
+	}
+
+	// the server may have sent data (e.g. a banner) that was buffered while reading the response
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -1,18 +1,44 @@
 package checks
 
 import (
+	"bytes"
+	gocontext "context"
 	"fmt"
+	"net/url"
+	"path"
+	"strings"
 	"time"
 
+	raw "cloud.google.com/go/pubsub/apiv1"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
 	"github.com/flanksource/canary-checker/api/context"
 	"github.com/flanksource/canary-checker/api/external"
 	v1 "github.com/flanksource/canary-checker/api/v1"
 	"github.com/flanksource/canary-checker/pkg"
 	"github.com/flanksource/commons/properties"
 	"github.com/flanksource/duty/pubsub"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	gocloudpubsub "gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/awssnssqs"
+	"gocloud.dev/pubsub/gcppubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+var pubsubRoundTripSeconds = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "canary_check_pubsub_round_trip_seconds",
+		Help: "The number of seconds for a published message to be received on the subscription",
+	},
+	[]string{"queue"},
 )
 
+func init() {
+	prometheus.MustRegister(pubsubRoundTripSeconds)
+}
+
 type PubSubChecker struct {
 }
 
@@ -34,6 +60,28 @@ func (c *PubSubChecker) Check(ctx *context.Context, extConfig external.Check) pk
 	result := pkg.Success(check, ctx.Canary)
 	results = append(results, result)
 
+	var topic *gocloudpubsub.Topic
+	var timeout, duplicateWindow time.Duration
+	if check.RoundTrip != nil {
+		var err error
+		if check.RoundTrip.Topic == "" {
+			return results.Invalidf("roundTrip.topic is required")
+		}
+		if timeout, err = check.RoundTrip.Timeout.GetDurationOr(30 * time.Second); err != nil || timeout <= 0 {
+			return results.Invalidf("invalid roundTrip.timeout %s", check.RoundTrip.Timeout)
+		}
+		if duplicateWindow, err = check.RoundTrip.DuplicateWindow.GetDurationOr(5 * time.Second); err != nil {
+			return results.Invalidf("invalid roundTrip.duplicateWindow %s", check.RoundTrip.DuplicateWindow)
+		}
+		// in memory topics must exist before subscribing, and have no credentials to share
+		if strings.HasPrefix(check.RoundTrip.Topic, mempubsub.Scheme+"://") {
+			if topic, err = gocloudpubsub.OpenTopic(ctx, check.RoundTrip.Topic); err != nil {
+				return results.ErrorMessage(fmt.Errorf("error opening topic %s: %w", check.RoundTrip.Topic, err))
+			}
+			defer topic.Shutdown(ctx) //nolint:errcheck
+		}
+	}
+
 	subscription, err := pubsub.Subscribe(ctx.Context, check.QueueConfig)
 	if err != nil {
 		return results.ErrorMessage(fmt.Errorf("error opening subscription for %s: %w", check.GetQueue(), err))
@@ -41,6 +89,28 @@ func (c *PubSubChecker) Check(ctx *context.Context, extConfig external.Check) pk
 
 	defer subscription.Shutdown(ctx) //nolint:errcheck
 
+	if check.RoundTrip != nil {
+		if topic == nil {
+			if topic, err = openPubSubTopic(ctx, subscription, check.RoundTrip.Topic); err != nil {
+				return results.ErrorMessage(fmt.Errorf("error opening topic %s: %w", v1.SanitizeEndpoints(check.RoundTrip.Topic), err))
+			}
+			defer topic.Shutdown(ctx) //nolint:errcheck
+		}
+		roundTrip, err := PubSubRoundTrip(ctx, topic, subscription, check.RoundTrip.Body, timeout, duplicateWindow)
+		result.AddDetails(PubSubResults{RoundTrip: roundTrip})
+		if err != nil {
+			return results.ErrorMessage(err)
+		}
+		if roundTrip.Received == 0 {
+			return results.Failf("message %s was not received within %s", roundTrip.ID, timeout)
+		}
+		pubsubRoundTripSeconds.WithLabelValues(check.GetQueue().String()).Set(float64(roundTrip.LatencyMillis) / 1000)
+		if roundTrip.Received > 1 {
+			return results.Failf("message %s was received %d times", roundTrip.ID, roundTrip.Received)
+		}
+		return results
+	}
+
 	limit := properties.Int(1000, "pubsub.max_messages")
 	msgs, err := ListenWithTimeout(ctx, subscription, 10*time.Second, limit)
 	if err != nil {
@@ -52,7 +122,102 @@ func (c *PubSubChecker) Check(ctx *context.Context, extConfig external.Check) pk
 }
 
 type PubSubResults struct {
-	Messages []string `json:"messages"`
+	Messages  []string               `json:"messages"`
+	RoundTrip *PubSubRoundTripResult `json:"roundTrip,omitempty"`
+}
+
+type PubSubRoundTripResult struct {
+	ID            string `json:"id"`
+	LatencyMillis int64  `json:"latencyMillis"`
+	// Received is the number of times the message was received, more than once is a duplicate
+	Received int `json:"received"`
+	// Skipped is the number of other messages that were acknowledged and dropped
+	Skipped int `json:"skipped"`
+}
+
+// openPubSubTopic opens the topic with the client of the subscription, so that messages are published
+// with the connection and credentials of the queue config. Topics of other drivers are opened by url.
+func openPubSubTopic(ctx gocontext.Context, subscription *gocloudpubsub.Subscription, topicURL string) (*gocloudpubsub.Topic, error) {
+	u, err := url.Parse(topicURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqsClient *sqs.Client
+	var subscriber *raw.SubscriberClient
+	switch {
+	case u.Scheme == awssnssqs.SQSScheme && subscription.As(&sqsClient):
+		return awssnssqs.OpenSQSTopic(ctx, sqsClient, "https://"+path.Join(u.Host, u.Path), nil), nil
+	case u.Scheme == awssnssqs.SNSScheme && subscription.As(&sqsClient):
+		options := sqsClient.Options()
+		snsClient := sns.New(sns.Options{
+			Region:       options.Region,
+			Credentials:  options.Credentials,
+			HTTPClient:   options.HTTPClient,
+			BaseEndpoint: options.BaseEndpoint,
+		})
+		return awssnssqs.OpenSNSTopic(ctx, snsClient, strings.TrimPrefix(path.Join(u.Host, u.Path), "/"), nil), nil
+	case u.Scheme == gcppubsub.Scheme && subscription.As(&subscriber):
+		//nolint:staticcheck // the publisher shares the grpc connection of the subscriber
+		publisher, err := gcppubsub.PublisherClient(ctx, subscriber.Connection())
+		if err != nil {
+			return nil, err
+		}
+		topicPath := path.Join(u.Host, u.Path)
+		if !strings.HasPrefix(topicPath, "projects/") {
+			// the shortened gcppubsub://project/topic form
+			topicPath = fmt.Sprintf("projects/%s/topics/%s", u.Host, strings.TrimPrefix(u.Path, "/"))
+		}
+		return gcppubsub.OpenTopicByPath(publisher, topicPath, nil)
+	}
+	return gocloudpubsub.OpenTopic(ctx, topicURL)
+}
+
+// pubsubRoundTripID is the metadata key with the unique id of a round trip message
+const pubsubRoundTripID = "canary-checker-id"
+
+// PubSubRoundTrip publishes a message with a unique id and receives from the subscription until it
+// arrives, then keeps receiving for the duplicate window to detect redeliveries
+func PubSubRoundTrip(ctx gocontext.Context, topic *gocloudpubsub.Topic, subscription *gocloudpubsub.Subscription, body string, timeout, duplicateWindow time.Duration) (*PubSubRoundTripResult, error) {
+	result := &PubSubRoundTripResult{ID: uuid.NewString()}
+	if body == "" {
+		body = result.ID
+	}
+	sent := time.Now()
+	// the id is also matched in the body for drivers without metadata such as raw sqs messages
+	if err := topic.Send(ctx, &gocloudpubsub.Message{Body: []byte(body), Metadata: map[string]string{pubsubRoundTripID: result.ID}}); err != nil {
+		return result, fmt.Errorf("error publishing message: %w", err)
+	}
+
+	deadline := sent.Add(timeout)
+	for {
+		receiveCtx, cancel := gocontext.WithDeadline(ctx, deadline)
+		msg, err := subscription.Receive(receiveCtx)
+		expired := receiveCtx.Err() != nil
+		cancel()
+		if err != nil && expired {
+			return result, nil
+		} else if err != nil {
+			return result, fmt.Errorf("error receiving message: %w", err)
+		}
+		if msg.Metadata[pubsubRoundTripID] != result.ID && !bytes.Contains(msg.Body, []byte(result.ID)) {
+			// the subscription is dedicated to the check, so other messages are left over from earlier
+			// runs and are dropped, as nacking them would redeliver them straight away
+			result.Skipped++
+			msg.Ack()
+			continue
+		}
+		msg.Ack()
+		result.Received++
+		if result.Received > 1 {
+			continue
+		}
+		result.LatencyMillis = time.Since(sent).Milliseconds()
+		if duplicateWindow <= 0 {
+			return result, nil
+		}
+		deadline = time.Now().Add(duplicateWindow)
+	}
 }
 
 func ListenWithTimeout(ctx *context.Context, subscription *gocloudpubsub.Subscription, timeout time.Duration, limit int) ([]string, error) {
@@ -0,0 +1,61 @@
+package checks
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	gocloudpubsub "gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+)
+
+func TestPubSubRoundTrip(t *testing.T) {
+	ctx := gocontext.Background()
+	topic := mempubsub.NewTopic()
+	defer topic.Shutdown(ctx) //nolint:errcheck
+	subscription := mempubsub.NewSubscription(topic, time.Minute)
+	defer subscription.Shutdown(ctx) //nolint:errcheck
+
+	// a message already on the subscription is acknowledged once instead of being redelivered
+	if err := topic.Send(ctx, &gocloudpubsub.Message{Body: []byte("other")}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := PubSubRoundTrip(ctx, topic, subscription, "", time.Second, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Received != 1 || result.Skipped != 1 {
+		t.Errorf("expected the message to be received once after skipping the other, got %+v", result)
+	}
+}
+
+func TestPubSubRoundTripFailures(t *testing.T) {
+	ctx := gocontext.Background()
+	topic := mempubsub.NewTopic()
+	defer topic.Shutdown(ctx) //nolint:errcheck
+
+	// a second topic receives nothing
+	result, err := PubSubRoundTrip(ctx, topic, mempubsub.NewSubscription(mempubsub.NewTopic(), time.Minute), "", 50*time.Millisecond, 0)
+	if err != nil || result.Received != 0 {
+		t.Errorf("expected the message to be lost, got %+v, %v", result, err)
+	}
+
+	// a relay that delivers the message twice
+	relayed := mempubsub.NewTopic()
+	defer relayed.Shutdown(ctx) //nolint:errcheck
+	source := mempubsub.NewSubscription(topic, time.Minute)
+	go func() {
+		msg, err := source.Receive(ctx)
+		if err != nil {
+			return
+		}
+		msg.Ack()
+		for i := 0; i < 2; i++ {
+			_ = relayed.Send(ctx, &gocloudpubsub.Message{Body: msg.Body, Metadata: msg.Metadata})
+		}
+	}()
+	result, err = PubSubRoundTrip(ctx, topic, mempubsub.NewSubscription(relayed, time.Minute), "", time.Second, 200*time.Millisecond)
+	if err != nil || result.Received != 2 {
+		t.Errorf("expected the message to be received twice, got %+v, %v", result, err)
+	}
+}
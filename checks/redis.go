@@ -1,7 +1,9 @@
 package checks
 
 import (
+	gocontext "context"
 	"crypto/tls"
+	"net"
 	"strconv"
 
 	"github.com/flanksource/canary-checker/api/context"
@@ -81,6 +83,22 @@ func (c *RedisChecker) Check(ctx *context.Context, extConfig external.Check) pkg
 		}
 	}
 
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	if proxy != nil {
+		// go-redis only does the TLS handshake in its default dialer
+		redisOpts.Dialer = func(dialCtx gocontext.Context, network, addr string) (net.Conn, error) {
+			if redisOpts.DialTimeout > 0 {
+				var cancel gocontext.CancelFunc
+				dialCtx, cancel = gocontext.WithTimeout(dialCtx, redisOpts.DialTimeout)
+				defer cancel()
+			}
+			return proxy.DialTLSContext(dialCtx, network, addr, redisOpts.TLSConfig)
+		}
+	}
+
 	rdb := redis.NewClient(redisOpts)
 	queryResult, err := rdb.Ping(ctx).Result()
 	if err != nil {
@@ -0,0 +1,309 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	netHTTP "net/http"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+var registryPhaseDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "canary_check_registry_phase_duration",
+		Help:    "A histogram of the duration in milliseconds of each phase of registry checks.",
+		Buckets: []float64{10, 50, 100, 250, 500, 1000, 3000, 10000, 30000},
+	},
+	[]string{"phase", "registry"},
+)
+
+func init() {
+	prometheus.MustRegister(registryPhaseDuration)
+}
+
+type RegistryChecker struct{}
+
+// Type: returns checker type
+func (c *RegistryChecker) Type() string {
+	return "registry"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *RegistryChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.Registry {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// RegistryCheckResult is the data of a registry check
+type RegistryCheckResult struct {
+	Image     string `json:"image"`
+	Digest    string `json:"digest,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+	Layers    int    `json:"layers"`
+	// Size is the number of bytes of the config and layers that were pulled
+	Size int64 `json:"size"`
+	// Pushed is the digest of the generated image that was pushed
+	Pushed string `json:"pushed,omitempty"`
+	// Timings are the durations in milliseconds of the resolve, manifest, layers, push and delete phases
+	Timings map[string]int64 `json:"timings"`
+}
+
+func (c *RegistryChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.RegistryCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	image, err := reference.ParseDockerRef(check.Image)
+	if err != nil {
+		return results.Invalidf("invalid image %s: %v", check.Image, err)
+	}
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+
+	httpClient, err := newProxiedHTTPClient(ctx, check.Proxy, check.TLSConfig)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+
+	pull, err := newRegistryClient(ctx, check, connection.URL, connection.Username, connection.Password, image, httpClient)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+
+	data := RegistryCheckResult{Image: image.String(), Timings: map[string]int64{}}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	phase := func(name string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		elapsed := time.Since(start)
+		data.Timings[name] = elapsed.Milliseconds()
+		registryPhaseDuration.WithLabelValues(name, reference.Domain(image)).Observe(float64(elapsed.Microseconds()) / 1000)
+		return err
+	}
+
+	start := time.Now()
+
+	var ref string
+	if digested, ok := image.(reference.Digested); ok {
+		ref = digested.Digest().String()
+	} else {
+		ref = image.(reference.Tagged).Tag()
+	}
+	var manifestDigest digest.Digest
+	if err := phase("resolve", func() (err error) {
+		manifestDigest, err = pull.resolve(ctx, ref)
+		return err
+	}); err != nil {
+		return results.Failf("%v", err)
+	}
+	data.Digest = manifestDigest.String()
+	if check.ExpectedDigest != "" && check.ExpectedDigest != data.Digest {
+		return results.Failf("expected digest %s, got %s", check.ExpectedDigest, data.Digest)
+	}
+
+	var manifest ocispec.Manifest
+	if err := phase("manifest", func() error {
+		var err error
+		manifest, data.MediaType, err = pullImageManifest(ctx, pull, manifestDigest, check.Platform)
+		return err
+	}); err != nil {
+		return results.Failf("%v", err)
+	}
+
+	blobs := []ocispec.Descriptor{manifest.Config}
+	if !check.SkipLayers {
+		blobs = append(blobs, manifest.Layers...)
+	}
+	data.Layers = len(manifest.Layers)
+	if err := phase("layers", func() error {
+		for _, blob := range blobs {
+			n, err := pull.blob(ctx, blob)
+			data.Size += n
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return results.Failf("%v", err)
+	}
+
+	if check.Push != nil {
+		pushImage, tag, err := registryPushImage(check, image)
+		if err != nil {
+			return results.Invalidf("%v", err)
+		}
+		push := pull
+		if reference.Domain(pushImage) != reference.Domain(image) {
+			// the connection is for the registry of the pulled image
+			push, err = newRegistryClient(ctx, check, "", "", "", pushImage, httpClient)
+		} else if reference.Path(pushImage) != reference.Path(image) {
+			push, err = newRegistryClient(ctx, check, connection.URL, connection.Username, connection.Password, pushImage, httpClient)
+		}
+		if err != nil {
+			return results.Invalidf("%v", err)
+		}
+
+		var pushed digest.Digest
+		if err := phase("push", func() (err error) {
+			pushed, err = push.pushTestImage(ctx, tag)
+			return err
+		}); err != nil {
+			return results.Failf("push to %s failed: %v", pushImage, err)
+		}
+		data.Pushed = pushed.String()
+
+		if !check.Push.SkipDelete {
+			if err := phase("delete", func() error {
+				return push.deleteManifest(ctx, pushed)
+			}); err != nil {
+				return results.Failf("failed to delete %s@%s: %v", pushImage.Name(), pushed, err)
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// newProxiedHTTPClient returns a client that uses the proxy and tls config of a check
+func newProxiedHTTPClient(ctx *context.Context, proxyConfig *v1.Proxy, tlsConfig *v1.TLSConfig) (*netHTTP.Client, error) {
+	proxy, err := getProxy(ctx, proxyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	transport := netHTTP.DefaultTransport.(*netHTTP.Transport).Clone()
+	if proxy != nil {
+		transport = proxy.Transport()
+	}
+	if tlsConfig != nil {
+		if transport.TLSClientConfig, err = tlsConfig.ToTLSConfig(ctx, ctx.GetNamespace()); err != nil {
+			return nil, fmt.Errorf("invalid tls config: %w", err)
+		}
+	}
+	return &netHTTP.Client{Transport: transport}, nil
+}
+
+// newRegistryClient returns a client for the repository of image, the registry is overridden by
+// baseURL if set, and the credentials default to those in the docker config
+func newRegistryClient(ctx *context.Context, check v1.RegistryCheck, baseURL, username, password string, image reference.Named, httpClient *netHTTP.Client) (*registryClient, error) {
+	domain := reference.Domain(image)
+	if baseURL == "" {
+		baseURL = "https://" + domain
+		if domain == "docker.io" {
+			baseURL = "https://registry-1.docker.io"
+		}
+	}
+
+	if username == "" && check.DockerConfig != nil {
+		config, err := ctx.GetEnvValueFromCache(*check.DockerConfig, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get docker config: %w", err)
+		}
+		if username, password, err = dockerConfigAuth(config, domain); err != nil {
+			return nil, err
+		}
+	}
+
+	return &registryClient{
+		client:     httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		repository: reference.Path(image),
+		username:   username,
+		password:   password,
+	}, nil
+}
+
+// registryPushImage returns the repository and tag the test image is pushed to
+func registryPushImage(check v1.RegistryCheck, image reference.Named) (reference.Named, string, error) {
+	if check.Push.Image == "" {
+		pushImage, err := reference.WithTag(reference.TrimNamed(image), "canary-checker")
+		return pushImage, "canary-checker", err
+	}
+	pushImage, err := reference.ParseDockerRef(check.Push.Image)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid push image %s: %w", check.Push.Image, err)
+	}
+	tagged, ok := pushImage.(reference.Tagged)
+	if !ok {
+		return nil, "", fmt.Errorf("push image %s must have a tag", check.Push.Image)
+	}
+	return pushImage, tagged.Tag(), nil
+}
+
+// pullImageManifest pulls the manifest of an image, selecting the platform from a multi-platform index
+func pullImageManifest(ctx *context.Context, client *registryClient, d digest.Digest, platform string) (ocispec.Manifest, string, error) {
+	var manifest ocispec.Manifest
+	body, mediaType, err := client.manifest(ctx, d)
+	if err != nil {
+		return manifest, "", err
+	}
+
+	if mediaType == ocispec.MediaTypeImageIndex || mediaType == dockerManifestListType {
+		var index ocispec.Index
+		if err := json.Unmarshal(body, &index); err != nil {
+			return manifest, mediaType, fmt.Errorf("invalid index: %w", err)
+		}
+		desc, err := selectPlatform(index, platform)
+		if err != nil {
+			return manifest, mediaType, err
+		}
+		if body, mediaType, err = client.manifest(ctx, desc.Digest); err != nil {
+			return manifest, mediaType, err
+		}
+	}
+
+	if mediaType != ocispec.MediaTypeImageManifest && mediaType != dockerManifestType {
+		return manifest, mediaType, fmt.Errorf("unsupported manifest type %s", mediaType)
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return manifest, mediaType, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, mediaType, nil
+}
+
+// selectPlatform returns the manifest in the index for a platform in the form os/arch[/variant]
+func selectPlatform(index ocispec.Index, platform string) (ocispec.Descriptor, error) {
+	if platform == "" {
+		platform = "linux/amd64"
+	}
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ocispec.Descriptor{}, fmt.Errorf("invalid platform %s, expected os/arch[/variant]", platform)
+	}
+	for _, desc := range index.Manifests {
+		if desc.Platform == nil || desc.Platform.OS != parts[0] || desc.Platform.Architecture != parts[1] {
+			continue
+		}
+		if len(parts) == 3 && desc.Platform.Variant != parts[2] {
+			continue
+		}
+		return desc, nil
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("no manifest found for platform %s", platform)
+}
@@ -0,0 +1,532 @@
+package checks
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	gocontext "context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	netHTTP "net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	dockerManifestType     = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestListType = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// maxManifestSize limits the size of the manifests that are read into memory
+	maxManifestSize = 4 << 20
+)
+
+// registryManifestTypes are the media types of the manifests accepted when pulling
+var registryManifestTypes = strings.Join([]string{
+	ocispec.MediaTypeImageManifest,
+	ocispec.MediaTypeImageIndex,
+	dockerManifestType,
+	dockerManifestListType,
+}, ", ")
+
+var authChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryClient is a minimal client of the OCI distribution API for a single repository
+type registryClient struct {
+	client     *netHTTP.Client
+	baseURL    string
+	repository string
+	username   string
+	password   string
+
+	// token is the bearer token, basic is set if the registry uses basic authentication instead
+	token string
+	basic bool
+}
+
+func (r *registryClient) url(kind, ref string) string {
+	return fmt.Sprintf("%s/v2/%s/%s/%s", r.baseURL, r.repository, kind, ref)
+}
+
+// do sends a request, authenticating using the challenge of the registry if it responds with a 401
+func (r *registryClient) do(ctx gocontext.Context, method, uri string, header netHTTP.Header, body []byte) (*netHTTP.Response, error) {
+	resp, err := r.send(ctx, method, uri, header, body)
+	if err != nil || resp.StatusCode != netHTTP.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	drainBody(resp)
+	if err := r.authenticate(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return r.send(ctx, method, uri, header, body)
+}
+
+func (r *registryClient) send(ctx gocontext.Context, method, uri string, header netHTTP.Header, body []byte) (*netHTTP.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := netHTTP.NewRequestWithContext(ctx, method, uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	} else if r.basic {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	return r.client.Do(req)
+}
+
+func (r *registryClient) authenticate(ctx gocontext.Context, challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.username == "" {
+			return fmt.Errorf("registry requires credentials")
+		}
+		r.basic = true
+		return nil
+	case "bearer":
+		token, err := r.fetchToken(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+		r.token = token
+		return nil
+	}
+	return fmt.Errorf("unsupported authentication challenge: %q", challenge)
+}
+
+func (r *registryClient) fetchToken(ctx gocontext.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := netHTTP.NewRequestWithContext(ctx, netHTTP.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusOK {
+		return "", registryError(resp)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token in response")
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for _, match := range authChallengeParam.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	return scheme, params
+}
+
+// resolve returns the digest a tag or digest refers to
+func (r *registryClient) resolve(ctx gocontext.Context, ref string) (digest.Digest, error) {
+	if d, err := digest.Parse(ref); err == nil {
+		return d, nil
+	}
+	header := netHTTP.Header{"Accept": []string{registryManifestTypes}}
+	resp, err := r.do(ctx, netHTTP.MethodHead, r.url("manifests", ref), header, nil)
+	if err != nil {
+		return "", err
+	}
+	drainBody(resp)
+	if resp.StatusCode != netHTTP.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s: %s", ref, resp.Status)
+	}
+	if d, err := digest.Parse(resp.Header.Get("Docker-Content-Digest")); err == nil {
+		return d, nil
+	}
+
+	// registries are not required to return the digest, in which case it is calculated from the manifest
+	resp, err = r.do(ctx, netHTTP.MethodGet, r.url("manifests", ref), header, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusOK {
+		return "", registryError(resp)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(body), nil
+}
+
+// manifest pulls a manifest by digest, verifying its content matches the digest
+func (r *registryClient) manifest(ctx gocontext.Context, d digest.Digest) ([]byte, string, error) {
+	header := netHTTP.Header{"Accept": []string{registryManifestTypes}}
+	resp, err := r.do(ctx, netHTTP.MethodGet, r.url("manifests", d.String()), header, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusOK {
+		return nil, "", registryError(resp)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, "", err
+	}
+	if actual := d.Algorithm().FromBytes(body); actual != d {
+		return nil, "", fmt.Errorf("manifest digest mismatch, expected %s, got %s", d, actual)
+	}
+
+	var versioned struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(body, &versioned); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest: %w", err)
+	}
+	mediaType := versioned.MediaType
+	if mediaType == "" {
+		mediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	}
+	return body, mediaType, nil
+}
+
+// blob pulls a blob, verifying its size and digest, and returns the number of bytes read
+func (r *registryClient) blob(ctx gocontext.Context, desc ocispec.Descriptor) (int64, error) {
+	return r.copyBlob(ctx, desc, io.Discard)
+}
+
+// readBlob pulls a blob of at most limit bytes into memory, verifying its size and digest
+func (r *registryClient) readBlob(ctx gocontext.Context, desc ocispec.Descriptor, limit int64) ([]byte, error) {
+	if desc.Size > limit {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", desc.Digest, limit)
+	}
+	var buf bytes.Buffer
+	_, err := r.copyBlob(ctx, desc, &limitedWriter{w: &buf, n: limit})
+	return buf.Bytes(), err
+}
+
+func (r *registryClient) copyBlob(ctx gocontext.Context, desc ocispec.Descriptor, w io.Writer) (int64, error) {
+	resp, err := r.do(ctx, netHTTP.MethodGet, r.url("blobs", desc.Digest.String()), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusOK {
+		return 0, registryError(resp)
+	}
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(verifier, w), resp.Body)
+	if err != nil {
+		return n, err
+	}
+	if desc.Size > 0 && n != desc.Size {
+		return n, fmt.Errorf("blob %s size mismatch, expected %d, got %d", desc.Digest, desc.Size, n)
+	}
+	if !verifier.Verified() {
+		return n, fmt.Errorf("blob %s digest mismatch", desc.Digest)
+	}
+	return n, nil
+}
+
+// tags lists the tags of the repository, following the pagination links of the registry
+func (r *registryClient) tags(ctx gocontext.Context) ([]string, error) {
+	var tags []string
+	uri := fmt.Sprintf("%s/v2/%s/tags/list", r.baseURL, r.repository)
+	for uri != "" {
+		resp, err := r.do(ctx, netHTTP.MethodGet, uri, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != netHTTP.StatusOK {
+			err := registryError(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tags response: %w", err)
+		}
+		tags = append(tags, page.Tags...)
+
+		uri = ""
+		if next := nextLink(resp.Header.Get("Link")); next != "" {
+			u, err := resp.Request.URL.Parse(next)
+			if err != nil {
+				return nil, fmt.Errorf("invalid link %s: %w", next, err)
+			}
+			uri = u.String()
+		}
+	}
+	return tags, nil
+}
+
+// nextLink returns the url of the rel="next" link in a Link header
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
+
+// limitedWriter fails writes beyond n bytes
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		return 0, fmt.Errorf("blob is larger than the limit")
+	}
+	l.n -= int64(len(p))
+	return l.w.Write(p)
+}
+
+// pushBlob uploads a blob in a single request, unless the registry already has it
+func (r *registryClient) pushBlob(ctx gocontext.Context, mediaType string, data []byte) (ocispec.Descriptor, error) {
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+
+	resp, err := r.do(ctx, netHTTP.MethodHead, r.url("blobs", desc.Digest.String()), nil, nil)
+	if err != nil {
+		return desc, err
+	}
+	drainBody(resp)
+	if resp.StatusCode == netHTTP.StatusOK {
+		return desc, nil
+	}
+
+	resp, err = r.do(ctx, netHTTP.MethodPost, r.url("blobs", "uploads/"), nil, []byte{})
+	if err != nil {
+		return desc, err
+	}
+	drainBody(resp)
+	if resp.StatusCode != netHTTP.StatusAccepted {
+		return desc, fmt.Errorf("failed to start upload: %s", resp.Status)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return desc, fmt.Errorf("invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest.String())
+	location.RawQuery = query.Encode()
+
+	header := netHTTP.Header{"Content-Type": []string{"application/octet-stream"}}
+	resp, err = r.do(ctx, netHTTP.MethodPut, location.String(), header, data)
+	if err != nil {
+		return desc, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusCreated {
+		return desc, registryError(resp)
+	}
+	return desc, nil
+}
+
+func (r *registryClient) pushManifest(ctx gocontext.Context, tag, mediaType string, manifest []byte) (digest.Digest, error) {
+	header := netHTTP.Header{"Content-Type": []string{mediaType}}
+	resp, err := r.do(ctx, netHTTP.MethodPut, r.url("manifests", tag), header, manifest)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusCreated {
+		return "", registryError(resp)
+	}
+	return digest.FromBytes(manifest), nil
+}
+
+func (r *registryClient) deleteManifest(ctx gocontext.Context, d digest.Digest) error {
+	resp, err := r.do(ctx, netHTTP.MethodDelete, r.url("manifests", d.String()), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != netHTTP.StatusAccepted && resp.StatusCode != netHTTP.StatusOK {
+		return registryError(resp)
+	}
+	return nil
+}
+
+// pushTestImage pushes a single layer image that contains the time it was created
+func (r *registryClient) pushTestImage(ctx gocontext.Context, tag string) (digest.Digest, error) {
+	now := time.Now().UTC()
+	layer, diffID, err := testImageLayer(now)
+	if err != nil {
+		return "", err
+	}
+	layerDesc, err := r.pushBlob(ctx, ocispec.MediaTypeImageLayerGzip, layer)
+	if err != nil {
+		return "", fmt.Errorf("failed to push layer: %w", err)
+	}
+
+	config, err := json.Marshal(ocispec.Image{
+		Created:  &now,
+		Platform: ocispec.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS:   ocispec.RootFS{Type: "layers", DiffIDs: []digest.Digest{diffID}},
+	})
+	if err != nil {
+		return "", err
+	}
+	configDesc, err := r.pushBlob(ctx, ocispec.MediaTypeImageConfig, config)
+	if err != nil {
+		return "", fmt.Errorf("failed to push config: %w", err)
+	}
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		return "", err
+	}
+	return r.pushManifest(ctx, tag, ocispec.MediaTypeImageManifest, manifest)
+}
+
+// testImageLayer returns a gzipped tar with a single file and the digest of the uncompressed tar
+func testImageLayer(created time.Time) ([]byte, digest.Digest, error) {
+	content := []byte(created.Format(time.RFC3339Nano) + "\n")
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.WriteHeader(&tar.Header{Name: "canary-checker", Mode: 0o644, Size: int64(len(content)), ModTime: created}); err != nil {
+		return nil, "", err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, "", err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(tarball.Bytes()); err != nil {
+		return nil, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+	return compressed.Bytes(), digest.FromBytes(tarball.Bytes()), nil
+}
+
+// registryError returns the errors in the body of a failed response
+func registryError(resp *netHTTP.Response) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil || len(body.Errors) == 0 {
+		return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+	}
+	var messages []string
+	for _, e := range body.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+	}
+	return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL.Path, strings.Join(messages, ", "))
+}
+
+func drainBody(resp *netHTTP.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
+
+// dockerConfigAuth returns the credentials for a registry host from a .dockerconfigjson
+func dockerConfigAuth(config, host string) (string, string, error) {
+	var dockerConfig struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal([]byte(config), &dockerConfig); err != nil {
+		return "", "", fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	hosts := []string{host}
+	if host == "docker.io" {
+		hosts = append(hosts, "index.docker.io", "registry-1.docker.io")
+	}
+	for key, auth := range dockerConfig.Auths {
+		// keys can be urls such as https://index.docker.io/v1/
+		keyHost := key
+		if u, err := url.Parse(key); err == nil && u.Host != "" {
+			keyHost = u.Host
+		}
+		if !matchesHost(hosts, keyHost) {
+			continue
+		}
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid auth for %s: %w", key, err)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", nil
+}
+
+func matchesHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
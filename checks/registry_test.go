@@ -0,0 +1,214 @@
+package checks
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testRegistry is an in-memory registry that requires a bearer token, which it issues for user:pass
+type testRegistry struct {
+	sync.Mutex
+	blobs     map[digest.Digest][]byte
+	manifests map[string][]byte
+}
+
+func newTestRegistry() (*testRegistry, *httptest.Server) {
+	registry := &testRegistry{blobs: map[digest.Digest][]byte{}, manifests: map[string][]byte{}}
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:canary/test:pull,push"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		registry.ServeHTTP(w, r)
+	}))
+	return registry, server
+}
+
+func (t *testRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.Lock()
+	defer t.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/canary/test/")
+	switch {
+	case r.Method == http.MethodGet && path == "tags/list":
+		tags := []string{}
+		for ref := range t.manifests {
+			if !strings.HasPrefix(ref, "sha256:") {
+				tags = append(tags, ref)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": "canary/test", "tags": tags})
+	case r.Method == http.MethodPost && path == "blobs/uploads/":
+		w.Header().Set("Location", "/v2/canary/test/blobs/uploads/1")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "blobs/uploads/"):
+		body, _ := io.ReadAll(r.Body)
+		d := digest.Digest(r.URL.Query().Get("digest"))
+		if digest.FromBytes(body) != d {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		t.blobs[d] = body
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "blobs/"):
+		blob, ok := t.blobs[digest.Digest(strings.TrimPrefix(path, "blobs/"))]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(blob)
+		}
+	case strings.HasPrefix(path, "manifests/"):
+		ref := strings.TrimPrefix(path, "manifests/")
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			t.manifests[ref] = body
+			t.manifests[digest.FromBytes(body).String()] = body
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			if _, ok := t.manifests[ref]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			for k, v := range t.manifests {
+				if digest.FromBytes(v).String() == ref {
+					delete(t.manifests, k)
+				}
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			manifest, ok := t.manifests[ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifest).String())
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(manifest)
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRegistryClientPushPullDelete(t *testing.T) {
+	registry, server := newTestRegistry()
+	defer server.Close()
+
+	ctx := newRetryTestContext(nil)
+	client := &registryClient{client: server.Client(), baseURL: server.URL, repository: "canary/test", username: "user", password: "pass"}
+
+	pushed, err := client.pushTestImage(ctx, "v1")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+
+	resolved, err := client.resolve(ctx, "v1")
+	if err != nil || resolved != pushed {
+		t.Fatalf("expected v1 to resolve to %s, got %s, %v", pushed, resolved, err)
+	}
+
+	manifest, mediaType, err := pullImageManifest(ctx, client, resolved, "")
+	if err != nil {
+		t.Fatalf("failed to pull manifest: %v", err)
+	}
+	if mediaType != ocispec.MediaTypeImageManifest || len(manifest.Layers) != 1 {
+		t.Fatalf("unexpected manifest %s: %+v", mediaType, manifest)
+	}
+	for _, blob := range append(manifest.Layers, manifest.Config) {
+		if _, err := client.blob(ctx, blob); err != nil {
+			t.Fatalf("failed to pull blob %s: %v", blob.Digest, err)
+		}
+	}
+
+	// a corrupted layer fails the digest verification
+	registry.blobs[manifest.Layers[0].Digest] = []byte(strings.Repeat("x", int(manifest.Layers[0].Size)))
+	if _, err := client.blob(ctx, manifest.Layers[0]); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+
+	if err := client.deleteManifest(ctx, pushed); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := client.resolve(ctx, "v1"); err == nil {
+		t.Error("expected the deleted image not to resolve")
+	}
+
+	unauthorized := &registryClient{client: server.Client(), baseURL: server.URL, repository: "canary/test"}
+	if _, err := unauthorized.resolve(ctx, "v1"); err == nil || !strings.Contains(err.Error(), "failed to get token") {
+		t.Errorf("expected the token request to fail without credentials, got %v", err)
+	}
+}
+
+func TestSelectPlatform(t *testing.T) {
+	index := ocispec.Index{Manifests: []ocispec.Descriptor{
+		{Digest: "sha256:amd64", Platform: &ocispec.Platform{OS: "linux", Architecture: "amd64"}},
+		{Digest: "sha256:arm64", Platform: &ocispec.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+	}}
+	tests := map[string]digest.Digest{
+		"":               "sha256:amd64",
+		"linux/arm64":    "sha256:arm64",
+		"linux/arm64/v8": "sha256:arm64",
+	}
+	for platform, expected := range tests {
+		desc, err := selectPlatform(index, platform)
+		if err != nil || desc.Digest != expected {
+			t.Errorf("selectPlatform(%s) = %s, %v; expected %s", platform, desc.Digest, err, expected)
+		}
+	}
+	if _, err := selectPlatform(index, "windows/amd64"); err == nil {
+		t.Error("expected no manifest for windows/amd64")
+	}
+}
+
+func TestDockerConfigAuth(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pa:ss"))
+	config := `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "` + auth + `"},
+		"ghcr.io": {"username": "bot", "password": "token"}
+	}}`
+
+	tests := map[string][2]string{
+		"docker.io":       {"user", "pa:ss"},
+		"ghcr.io":         {"bot", "token"},
+		"quay.io":         {"", ""},
+		"GHCR.io":         {"bot", "token"},
+		"index.docker.io": {"user", "pa:ss"},
+	}
+	for host, expected := range tests {
+		username, password, err := dockerConfigAuth(config, host)
+		if err != nil || username != expected[0] || password != expected[1] {
+			t.Errorf("dockerConfigAuth(%s) = %s, %s, %v; expected %v", host, username, password, err, expected)
+		}
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/alpine:pull" {
+		t.Errorf("unexpected challenge %s %v", scheme, params)
+	}
+}
@@ -0,0 +1,151 @@
+package checks
+
+import (
+	gocontext "context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/flanksource/canary-checker/pkg"
+)
+
+// pinnedAddresses returns the addresses host is pinned to by resolve, or nil if it is looked up with DNS
+func pinnedAddresses(resolve map[string][]string, host string) ([]string, error) {
+	addrs, ok := resolve[host]
+	if !ok {
+		return nil, nil
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses to resolve %s to", host)
+	}
+	for _, addr := range addrs {
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("invalid address %s for %s, expected an ip", addr, host)
+		}
+	}
+	return addrs, nil
+}
+
+// pinHostPort replaces the host of a host:port with the first address it is pinned to
+func pinHostPort(resolve map[string][]string, hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+	if addrs, _ := pinnedAddresses(resolve, host); len(addrs) > 0 {
+		return net.JoinHostPort(addrs[0], port)
+	}
+	return hostport
+}
+
+// pinToAddress returns a copy of resolve with host pinned to the single addr
+func pinToAddress(resolve map[string][]string, host, addr string) map[string][]string {
+	pinned := make(map[string][]string, len(resolve))
+	for k, v := range resolve {
+		pinned[k] = v
+	}
+	pinned[host] = []string{addr}
+	return pinned
+}
+
+// checkEachAddress runs a check once for every address, the name of each check is suffixed with
+// its address so that every address is reported as a separate check
+func checkEachAddress(name string, addrs []string, check func(name, addr string) pkg.Results) pkg.Results {
+	var results pkg.Results
+	for _, addr := range addrs {
+		results = append(results, check(fmt.Sprintf("%s (%s)", name, addr), addr)...)
+	}
+	return results
+}
+
+const (
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+	ipFamilyBoth = "both"
+)
+
+// ipFamilies returns the address families a check is run for, or nil when the family is not restricted
+func ipFamilies(family string) ([]string, error) {
+	switch strings.ToLower(family) {
+	case "":
+		return nil, nil
+	case ipFamilyIPv4:
+		return []string{ipFamilyIPv4}, nil
+	case ipFamilyIPv6:
+		return []string{ipFamilyIPv6}, nil
+	case ipFamilyBoth:
+		return []string{ipFamilyIPv4, ipFamilyIPv6}, nil
+	}
+	return nil, fmt.Errorf("invalid ipFamily %s, expected ipv4, ipv6 or both", family)
+}
+
+// checkEachFamily runs a check once for every address family, the name of each check is suffixed
+// with its family so that a family that is unreachable is not hidden by the other one
+func checkEachFamily(name string, families []string, check func(name, family string) pkg.Results) pkg.Results {
+	var results pkg.Results
+	for _, family := range families {
+		results = append(results, check(fmt.Sprintf("%s (%s)", name, family), family)...)
+	}
+	return results
+}
+
+// familyNetwork returns the network restricted to the family, e.g. tcp4 for tcp and ipv4
+func familyNetwork(network, family string) string {
+	switch family {
+	case ipFamilyIPv4:
+		return network + "4"
+	case ipFamilyIPv6:
+		return network + "6"
+	}
+	return network
+}
+
+// isFamily returns whether ip is an address of the family
+func isFamily(ip net.IP, family string) bool {
+	switch family {
+	case ipFamilyIPv4:
+		return ip.To4() != nil
+	case ipFamilyIPv6:
+		return ip != nil && ip.To4() == nil
+	}
+	return ip != nil
+}
+
+// resolveFamily returns a copy of resolve with host pinned to addresses of the family. The addresses
+// host is already pinned to are filtered, otherwise host is pinned to the first address it resolves to.
+func resolveFamily(ctx gocontext.Context, resolve map[string][]string, host, family string) (map[string][]string, error) {
+	if family == "" {
+		return resolve, nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !isFamily(ip, family) {
+			return nil, fmt.Errorf("%s is not an %s address", host, family)
+		}
+		return resolve, nil
+	}
+
+	addrs, err := pinnedAddresses(resolve, host)
+	if err != nil {
+		return nil, err
+	}
+	var matching []string
+	if addrs != nil {
+		for _, addr := range addrs {
+			if isFamily(net.ParseIP(addr), family) {
+				matching = append(matching, addr)
+			}
+		}
+	} else {
+		ips, err := net.DefaultResolver.LookupIP(ctx, familyNetwork("ip", family), host)
+		if err == nil && len(ips) > 0 {
+			matching = []string{ips[0].String()}
+		}
+	}
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("%s has no %s address", host, family)
+	}
+
+	pinned := pinToAddress(resolve, host, matching[0])
+	pinned[host] = matching
+	return pinned, nil
+}
@@ -0,0 +1,173 @@
+package checks
+
+import (
+	gocontext "context"
+	"net"
+	"strings"
+	"testing"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+func TestTCPCheckerResolve(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	check := v1.TCPCheck{
+		Description: v1.Description{Name: "backend"},
+		Endpoint:    "backend.invalid:" + port,
+		Resolve:     map[string][]string{"backend.invalid": {"127.0.0.1"}},
+	}
+	results := NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if len(results) != 1 || !results[0].Pass {
+		t.Fatalf("expected the pinned address to be used, got %v", results[0].Error)
+	}
+
+	// nothing listens on 127.0.0.2, so only the first address passes
+	check.Resolve["backend.invalid"] = []string{"127.0.0.1", "127.0.0.2"}
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if len(results) != 2 {
+		t.Fatalf("expected one result per address, got %d", len(results))
+	}
+	if name := results[0].Check.GetName(); name != "backend (127.0.0.1)" || !results[0].Pass {
+		t.Errorf("expected %s to pass, got %v", name, results[0].Error)
+	}
+	if name := results[1].Check.GetName(); name != "backend (127.0.0.2)" || results[1].Pass {
+		t.Errorf("expected %s to fail", name)
+	}
+}
+
+func TestPinnedAddresses(t *testing.T) {
+	resolve := map[string][]string{
+		"a.example.com": {"10.0.0.1", "2001:db8::1"},
+		"b.example.com": {"not-an-ip"},
+		"c.example.com": {},
+	}
+
+	if addrs, err := pinnedAddresses(resolve, "other.example.com"); err != nil || addrs != nil {
+		t.Errorf("expected hosts that are not pinned to be looked up, got %v, %v", addrs, err)
+	}
+	if addrs, err := pinnedAddresses(resolve, "a.example.com"); err != nil || len(addrs) != 2 {
+		t.Errorf("expected 2 addresses, got %v, %v", addrs, err)
+	}
+	if _, err := pinnedAddresses(resolve, "b.example.com"); err == nil || !strings.Contains(err.Error(), "expected an ip") {
+		t.Errorf("expected an invalid address to be rejected, got %v", err)
+	}
+	if _, err := pinnedAddresses(resolve, "c.example.com"); err == nil {
+		t.Error("expected a host without addresses to be rejected")
+	}
+
+	tests := map[string]string{
+		"a.example.com:443":     "10.0.0.1:443",
+		"other.example.com:443": "other.example.com:443",
+	}
+	for hostport, expected := range tests {
+		if out := pinHostPort(resolve, hostport); out != expected {
+			t.Errorf("pinHostPort(%s) = %s, expected %s", hostport, out, expected)
+		}
+	}
+	if out := pinHostPort(pinToAddress(resolve, "a.example.com", "2001:db8::1"), "a.example.com:443"); out != "[2001:db8::1]:443" {
+		t.Errorf("expected the ipv6 address to be bracketed, got %s", out)
+	}
+}
+
+func TestTCPCheckerIPFamily(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Skipf("ipv6 is not available: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	check := v1.TCPCheck{
+		Description: v1.Description{Name: "backend"},
+		Endpoint:    "backend.invalid:" + port,
+		Resolve:     map[string][]string{"backend.invalid": {"127.0.0.1", "::1"}},
+		IPFamily:    "both",
+	}
+	results := NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if len(results) != 2 {
+		t.Fatalf("expected one result per family, got %d", len(results))
+	}
+	for i, name := range []string{"backend (ipv4)", "backend (ipv6)"} {
+		if results[i].Check.GetName() != name || !results[i].Pass {
+			t.Errorf("expected %s to pass, got %s: %v", name, results[i].Check.GetName(), results[i].Error)
+		}
+	}
+
+	check.Resolve = map[string][]string{"backend.invalid": {"::1"}}
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if len(results) != 2 || results[0].Pass || results[0].Error != "backend.invalid has no ipv4 address" || !results[1].Pass {
+		t.Errorf("expected only the unreachable family to fail, got %v", results)
+	}
+
+	check.Endpoint, check.Resolve, check.IPFamily = "[::1]:"+port, nil, ""
+	if results := NewTCPChecker().Check(newRetryTestContext(nil), check); !results[0].Pass {
+		t.Errorf("expected the ipv6 literal to be parsed, got %v", results[0].Error)
+	}
+	check.IPFamily = "ipv4"
+	if results := NewTCPChecker().Check(newRetryTestContext(nil), check); results[0].Pass || results[0].Error != "::1 is not an ipv4 address" {
+		t.Errorf("expected the ipv6 literal to fail for ipv4, got %v", results[0].Error)
+	}
+	check.IPFamily = "ipv5"
+	if results := NewTCPChecker().Check(newRetryTestContext(nil), check); results[0].Pass || !results[0].Invalid {
+		t.Errorf("expected an unknown family to be invalid, got %v", results[0].Error)
+	}
+}
+
+func TestResolveFamily(t *testing.T) {
+	resolve := map[string][]string{"a.example.com": {"10.0.0.1", "10.0.0.2", "2001:db8::1"}}
+
+	if pinned, err := resolveFamily(gocontext.TODO(), resolve, "a.example.com", ipFamilyIPv4); err != nil || strings.Join(pinned["a.example.com"], ",") != "10.0.0.1,10.0.0.2" {
+		t.Errorf("expected the ipv4 addresses to be kept, got %v, %v", pinned, err)
+	}
+	if pinned, err := resolveFamily(gocontext.TODO(), resolve, "a.example.com", ipFamilyIPv6); err != nil || strings.Join(pinned["a.example.com"], ",") != "2001:db8::1" {
+		t.Errorf("expected the ipv6 address to be kept, got %v, %v", pinned, err)
+	}
+	if len(resolve["a.example.com"]) != 3 {
+		t.Errorf("expected resolve to not be modified, got %v", resolve)
+	}
+	resolve["a.example.com"] = []string{"10.0.0.1"}
+	if _, err := resolveFamily(gocontext.TODO(), resolve, "a.example.com", ipFamilyIPv6); err == nil || err.Error() != "a.example.com has no ipv6 address" {
+		t.Errorf("expected a host without ipv6 addresses to fail, got %v", err)
+	}
+	if pinned, err := resolveFamily(gocontext.TODO(), nil, "localhost", ipFamilyIPv4); err != nil || strings.Join(pinned["localhost"], ",") != "127.0.0.1" {
+		t.Errorf("expected localhost to be pinned to its ipv4 address, got %v, %v", pinned, err)
+	}
+
+	tests := map[string][2]string{
+		"127.0.0.1:80":   {"127.0.0.1", "80"},
+		"[::1]:80":       {"::1", "80"},
+		"example.com:80": {"example.com", "80"},
+	}
+	for endpoint, expected := range tests {
+		if addr, port, err := extractAddrAndPort(endpoint); err != nil || addr != expected[0] || port != expected[1] {
+			t.Errorf("extractAddrAndPort(%s) = %s, %s, %v", endpoint, addr, port, err)
+		}
+	}
+	if _, _, err := extractAddrAndPort("::1:80"); err == nil {
+		t.Error("expected an ipv6 address without brackets to be rejected")
+	}
+}
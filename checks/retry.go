@@ -1,9 +1,14 @@
 package checks
 
 import (
+	gocontext "context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/flanksource/canary-checker/api/context"
 	"github.com/flanksource/canary-checker/api/external"
 	v1 "github.com/flanksource/canary-checker/api/v1"
@@ -12,6 +17,21 @@ import (
 
 const defaultCheckRetryInterval = time.Second
 
+// checkTimeoutErrorType is the errorType of results that failed because the check exceeded its timeout
+const checkTimeoutErrorType = "timeout"
+
+var abandonedChecks = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "canary_check_abandoned",
+		Help: "The number of checks that exceeded their timeout and are still running in the background",
+	},
+	[]string{"type"},
+)
+
+func init() {
+	prometheus.MustRegister(abandonedChecks)
+}
+
 type retriesProvider interface {
 	GetRetries() *v1.CheckRetries
 }
@@ -20,6 +40,10 @@ type internalRetryHandler interface {
 	HandlesRetriesInternally() bool
 }
 
+type timeoutProvider interface {
+	GetCheckTimeout() (time.Duration, error)
+}
+
 type retryPolicy struct {
 	configured bool
 	disabled   bool
@@ -85,12 +109,118 @@ func runCheckAttempt(ctx *context.Context, checker Checker, check external.Check
 		return pkg.Invalid(check, ctx.Canary, fmt.Sprintf("checker %s does not implement SingleCheckRunner", checker.Type())), 0
 	}
 
-	result := singleRunner.Check(ctx, check)
+	timeout, err := getCheckTimeout(ctx, checker, check)
+	if err != nil {
+		return pkg.Invalid(check, ctx.Canary, fmt.Sprintf("invalid timeout: %v", err)), 0
+	}
+
+	result := runCheckWithTimeout(ctx, singleRunner, check, timeout)
 	transformedResults := TransformResults(ctx, result)
 	skippedCount, filteredResults := filterSecretLookupRateLimitedResults(ctx, transformedResults)
 	return filteredResults, skippedCount
 }
 
+// getCheckTimeout returns the timeout of a single run of the check, falling back to the
+// canary default when the check does not specify one. Checkers that handle retries
+// internally manage their own waits and are only bound by an explicit timeout.
+func getCheckTimeout(ctx *context.Context, checker Checker, check external.Check) (time.Duration, error) {
+	if provider, ok := check.(timeoutProvider); ok {
+		timeout, err := provider.GetCheckTimeout()
+		if err != nil {
+			return 0, err
+		}
+		if timeout < 0 {
+			return 0, fmt.Errorf("timeout cannot be negative")
+		}
+		if timeout > 0 {
+			return timeout, nil
+		}
+	}
+
+	if handler, ok := checker.(internalRetryHandler); ok && handler.HandlesRetriesInternally() {
+		return 0, nil
+	}
+	return GetCanaryTimeout(ctx.Canary), nil
+}
+
+// runCheckWithTimeout runs the check with a context that is cancelled once the timeout expires.
+// Checkers that do not return by then are abandoned and reported as timed out. Only checkers
+// that honour the context actually stop, the others (e.g. drivers that take no context) keep
+// running in the background until they return, and are counted by canary_check_abandoned.
+func runCheckWithTimeout(ctx *context.Context, runner SingleCheckRunner, check external.Check, timeout time.Duration) pkg.Results {
+	if timeout <= 0 {
+		return runner.Check(ctx, check)
+	}
+
+	timeoutCtx, cancel := ctx.WithTimeout(timeout)
+	defer cancel()
+	checkCtx := ctx.Clone().WithDutyContext(timeoutCtx)
+
+	const (
+		running int32 = iota
+		finished
+		abandoned
+	)
+	var state atomic.Int32
+
+	start := time.Now()
+	done := make(chan pkg.Results, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- pkg.Invalid(check, ctx.Canary, fmt.Sprintf("panic: %v", r))
+			}
+			if !state.CompareAndSwap(running, finished) {
+				abandonedChecks.WithLabelValues(check.GetType()).Dec()
+			}
+		}()
+		done <- runner.Check(checkCtx, check)
+	}()
+
+	select {
+	case results := <-done:
+		if errors.Is(timeoutCtx.Err(), gocontext.DeadlineExceeded) {
+			// the checker gave up because of the deadline, classify its failures as timeouts
+			for _, result := range results {
+				if result != nil && !result.Pass && !result.Invalid {
+					markTimedOut(result)
+				}
+			}
+		}
+		return results
+
+	case <-timeoutCtx.Done():
+		abandonedChecks.WithLabelValues(check.GetType()).Inc()
+		if !state.CompareAndSwap(running, abandoned) {
+			// the check returned just as the timeout expired
+			abandonedChecks.WithLabelValues(check.GetType()).Dec()
+		}
+
+		if !errors.Is(timeoutCtx.Err(), gocontext.DeadlineExceeded) {
+			return pkg.Invalid(check, ctx.Canary, timeoutCtx.Err().Error())
+		}
+		if ctx.Err() != nil {
+			return canaryDeadlineExceeded(ctx, check).StartTime(start).ToSlice()
+		}
+		result := pkg.New(check, ctx.Canary).StartTime(start)
+		result.Failf("timed out after %s", timeout)
+		return markTimedOut(result).ToSlice()
+	}
+}
+
+// canaryDeadlineExceeded returns the result of a check that was cut short, or not started,
+// because the canary run it belongs to exceeded its deadline
+func canaryDeadlineExceeded(ctx *context.Context, check external.Check) *pkg.CheckResult {
+	result := pkg.New(check, ctx.Canary)
+	result.Failf("canary deadline exceeded")
+	return markTimedOut(result)
+}
+
+func markTimedOut(result *pkg.CheckResult) *pkg.CheckResult {
+	result.TimedOut = true
+	return result.AddData(map[string]interface{}{"errorType": checkTimeoutErrorType})
+}
+
 func getRetryPolicy(ctx *context.Context, check external.Check) (retryPolicy, error) {
 	return newRetryPolicy(mergeCheckRetries(ctx.Canary.Spec.Retries, getRetries(check)))
 }
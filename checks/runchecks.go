@@ -187,18 +187,22 @@ func RunChecksNoPersistence(ctx *context.Context) ([]*pkg.CheckResult, error) {
 		return nil, fmt.Errorf("error getting disabled checks: %v", err)
 	}
 
+	ctx, cancel := withCanaryDeadline(ctx)
+	defer cancel()
+
 	checks := ctx.Canary.Spec.GetAllChecks()
 	for _, c := range All {
-		// FIXME: this doesn't work correct with DNS,
-		// t := GetDeadline(ctx.Canary)
-		// ctx, cancel := ctx.WithDeadline(t)
-		// defer cancel()
-
 		if _, ok := disabledChecks[c.Type()]; ok {
 			continue
 		}
 
 		for _, check := range checksOfType(checks, c.Type()) {
+			if ctx.Err() != nil {
+				filteredResults := canaryDeadlineExceeded(ctx, check).ToSlice()
+				results = append(results, filteredResults...)
+				ExportCheckMetrics(ctx, filteredResults, false)
+				continue
+			}
 			filteredResults, _ := runCheckWithRetries(ctx, c, check)
 			results = append(results, filteredResults...)
 			ExportCheckMetrics(ctx, filteredResults, false)
@@ -252,49 +256,23 @@ func RunChecks(ctx *context.Context) ([]*pkg.CheckResult, RunChecksMeta, error)
 
 	ctx.Debugf("running %s, %d checks", ctx.Canary.Pretty().ANSI(), len(checks))
 
-	if hasDependencies(checks) {
-		sortedChecks, err := sortChecksByDependency(checks)
-		if err != nil {
-			return nil, meta, fmt.Errorf("failed to sort checks: %v", err)
-		}
-
-		for _, check := range sortedChecks {
-			if _, ok := disabledChecks[check.GetType()]; ok {
-				continue
-			}
-
-			checker := getCheckerForType(check.GetType())
-			if checker == nil {
-				ctx.Warnf("no checker found for type %s", check.GetType())
-				continue
-			}
-
-			if len(ctx.Outputs) > 0 {
-				ctx.Environment["outputs"] = ctx.GetOutputs()
-			}
+	tasks, err := getCheckTasks(ctx, checks, disabledChecks)
+	if err != nil {
+		return nil, meta, err
+	}
 
-			filteredResults, skippedCount := runCheckWithRetries(ctx, checker, check)
-			meta.SecretLookupRateLimitSkipped += skippedCount
-			results = append(results, filteredResults...)
-			ExportCheckMetrics(ctx, filteredResults, true)
+	withOutputs := hasDependencies(checks)
+	taskResults := runCheckTasks(ctx, tasks, getCheckConcurrency(ctx), func(task checkTask, result checkTaskResult) {
+		meta.SecretLookupRateLimitSkipped += result.skipped
+		ExportCheckMetrics(ctx, result.results, true)
 
-			if check.GetName() != "" && len(filteredResults) > 0 && filteredResults[0].Pass {
-				ctx.SetOutput(check.GetName(), filteredResults[0])
-			}
+		if withOutputs && task.check.GetName() != "" && len(result.results) > 0 && result.results[0].Pass {
+			ctx.SetOutput(task.check.GetName(), result.results[0])
 		}
-	} else {
-		for _, c := range All {
-			if _, ok := disabledChecks[c.Type()]; ok {
-				continue
-			}
+	})
 
-			for _, check := range checksOfType(checks, c.Type()) {
-				filteredResults, skippedCount := runCheckWithRetries(ctx, c, check)
-				meta.SecretLookupRateLimitSkipped += skippedCount
-				results = append(results, filteredResults...)
-				ExportCheckMetrics(ctx, filteredResults, true)
-			}
-		}
+	for _, taskResult := range taskResults {
+		results = append(results, taskResult.results...)
 	}
 
 	if err := saveArtifacts(ctx, results); err != nil {
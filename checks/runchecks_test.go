@@ -1,14 +1,18 @@
 package checks
 
 import (
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	canaryContext "github.com/flanksource/canary-checker/api/context"
 	"github.com/flanksource/canary-checker/api/external"
 	v1 "github.com/flanksource/canary-checker/api/v1"
 	"github.com/flanksource/canary-checker/pkg"
 	dutyContext "github.com/flanksource/duty/context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sTypes "k8s.io/apimachinery/pkg/types"
 )
@@ -420,3 +424,276 @@ func TestAllCheckersImplementSingleCheckRunner(t *testing.T) {
 		}
 	}
 }
+
+type concurrencyTestChecker struct {
+	delay       time.Duration
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	finished    []string
+	// startedAfter records the checks that had finished when each check started
+	startedAfter map[string][]string
+}
+
+func (c *concurrencyTestChecker) Type() string { return "http" }
+func (c *concurrencyTestChecker) Run(ctx *canaryContext.Context) pkg.Results {
+	return nil
+}
+func (c *concurrencyTestChecker) Check(ctx *canaryContext.Context, check external.Check) pkg.Results {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.startedAfter[check.GetName()] = append([]string{}, c.finished...)
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.finished = append(c.finished, check.GetName())
+	c.mu.Unlock()
+	return pkg.Results{pkg.Success(check, ctx.Canary)}
+}
+
+func newConcurrencyTestChecker() *concurrencyTestChecker {
+	return &concurrencyTestChecker{delay: 20 * time.Millisecond, startedAfter: map[string][]string{}}
+}
+
+func TestRunCheckTasksRespectsConcurrency(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	checker := newConcurrencyTestChecker()
+
+	var tasks []checkTask
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		tasks = append(tasks, checkTask{checker: checker, check: v1.HTTPCheck{Description: v1.Description{Name: name}}})
+	}
+
+	completed := 0
+	results := runCheckTasks(ctx, tasks, 2, func(task checkTask, result checkTaskResult) {
+		completed++
+	})
+
+	if completed != len(tasks) {
+		t.Fatalf("expected onComplete to be called %d times, got %d", len(tasks), completed)
+	}
+	if checker.maxInFlight > 2 {
+		t.Fatalf("expected at most 2 checks in flight, got %d", checker.maxInFlight)
+	}
+	for i, result := range results {
+		if len(result.results) != 1 || result.results[0].Check.GetName() != tasks[i].check.GetName() {
+			t.Fatalf("expected result %d to belong to check %q, got %#v", i, tasks[i].check.GetName(), result.results)
+		}
+	}
+}
+
+func TestRunCheckTasksRunsIndependentChecksInParallel(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	checker := newConcurrencyTestChecker()
+
+	var tasks []checkTask
+	for _, name := range []string{"a", "b", "c", "d"} {
+		tasks = append(tasks, checkTask{checker: checker, check: v1.HTTPCheck{Description: v1.Description{Name: name}}})
+	}
+
+	_ = runCheckTasks(ctx, tasks, 4, nil)
+	if checker.maxInFlight < 2 {
+		t.Fatalf("expected checks to run in parallel, max in flight was %d", checker.maxInFlight)
+	}
+}
+
+func TestRunCheckTasksSequentialByDefault(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	checker := newConcurrencyTestChecker()
+	checker.delay = time.Millisecond
+
+	var tasks []checkTask
+	for _, name := range []string{"a", "b", "c"} {
+		tasks = append(tasks, checkTask{checker: checker, check: v1.HTTPCheck{Description: v1.Description{Name: name}}})
+	}
+
+	_ = runCheckTasks(ctx, tasks, 1, nil)
+	if checker.maxInFlight != 1 {
+		t.Fatalf("expected sequential execution, max in flight was %d", checker.maxInFlight)
+	}
+	if strings.Join(checker.finished, ",") != "a,b,c" {
+		t.Fatalf("expected checks to run in order, got %v", checker.finished)
+	}
+}
+
+func TestRunCheckTasksWaitsForDependencies(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	checker := newConcurrencyTestChecker()
+
+	checks := []external.Check{
+		v1.HTTPCheck{Description: v1.Description{Name: "c", DependsOn: []string{"a", "b"}}},
+		v1.HTTPCheck{Description: v1.Description{Name: "a"}},
+		v1.HTTPCheck{Description: v1.Description{Name: "b", DependsOn: []string{"a"}}},
+		v1.HTTPCheck{Description: v1.Description{Name: "d"}},
+	}
+
+	tasks, err := getCheckTasks(ctx, checks, map[string]struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range tasks {
+		tasks[i].checker = checker
+	}
+
+	var outputs []string
+	_ = runCheckTasks(ctx, tasks, 4, func(task checkTask, result checkTaskResult) {
+		ctx.SetOutput(task.check.GetName(), result.results[0])
+		outputs = append(outputs, task.check.GetName())
+	})
+
+	if len(outputs) != 4 {
+		t.Fatalf("expected 4 completed checks, got %v", outputs)
+	}
+	if !slices.Contains(checker.startedAfter["b"], "a") {
+		t.Fatalf("expected b to start after a completed, got %v", checker.startedAfter["b"])
+	}
+	if !slices.Contains(checker.startedAfter["c"], "a") || !slices.Contains(checker.startedAfter["c"], "b") {
+		t.Fatalf("expected c to start after a and b completed, got %v", checker.startedAfter["c"])
+	}
+}
+
+type timeoutTestChecker struct {
+	// honourContext returns a failure once the context is cancelled instead of hanging
+	honourContext bool
+	release       chan struct{}
+}
+
+func (c *timeoutTestChecker) Type() string { return "http" }
+func (c *timeoutTestChecker) Run(ctx *canaryContext.Context) pkg.Results {
+	return nil
+}
+func (c *timeoutTestChecker) Check(ctx *canaryContext.Context, check external.Check) pkg.Results {
+	result := pkg.Success(check, ctx.Canary)
+	if c.honourContext {
+		<-ctx.Done()
+		return result.Failf("%v", ctx.Err()).ToSlice()
+	}
+	<-c.release
+	return result.ToSlice()
+}
+
+func TestRunCheckWithRetriesTimesOutHungCheck(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	check := v1.HTTPCheck{Description: v1.Description{Name: "hung", CheckTimeout: "20ms"}}
+	checker := &timeoutTestChecker{release: make(chan struct{})}
+	defer close(checker.release)
+
+	start := time.Now()
+	results, _ := runCheckWithRetries(ctx, checker, check)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the check to be abandoned after its timeout, took %s", elapsed)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+	if results[0].Pass || results[0].Invalid || !results[0].TimedOut {
+		t.Fatalf("expected a timed out failure, got pass=%v invalid=%v timedOut=%v", results[0].Pass, results[0].Invalid, results[0].TimedOut)
+	}
+	if results[0].Data["errorType"] != checkTimeoutErrorType {
+		t.Fatalf("expected errorType %q, got %v", checkTimeoutErrorType, results[0].Data["errorType"])
+	}
+}
+
+func TestRunCheckWithRetriesCountsAbandonedChecks(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	check := v1.TCPCheck{Description: v1.Description{Name: "abandoned", CheckTimeout: "20ms"}}
+	checker := &timeoutTestChecker{release: make(chan struct{})}
+	gauge := abandonedChecks.WithLabelValues(check.GetType())
+
+	_, _ = runCheckWithRetries(ctx, checker, check)
+	if abandoned := testutil.ToFloat64(gauge); abandoned != 1 {
+		t.Fatalf("expected 1 abandoned check, got %v", abandoned)
+	}
+
+	close(checker.release)
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(gauge) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the abandoned check to be uncounted once it returns, got %v", testutil.ToFloat64(gauge))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunCheckWithRetriesClassifiesDeadlineFailures(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	check := v1.HTTPCheck{Description: v1.Description{Name: "cancelled", CheckTimeout: "20ms"}}
+	checker := &timeoutTestChecker{honourContext: true}
+
+	results, _ := runCheckWithRetries(ctx, checker, check)
+	if len(results) != 1 || !results[0].TimedOut {
+		t.Fatalf("expected a single timed out result, got %v", results)
+	}
+	if !strings.Contains(results[0].Error, "deadline exceeded") {
+		t.Fatalf("expected the error of the checker to be kept, got %q", results[0].Error)
+	}
+}
+
+func TestRunCheckWithRetriesRejectsInvalidTimeout(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	check := v1.HTTPCheck{Description: v1.Description{Name: "invalid", CheckTimeout: "soon"}}
+	checker := &retryTestChecker{passOnAttempt: 1}
+
+	results, _ := runCheckWithRetries(ctx, checker, check)
+	if len(results) != 1 || !results[0].Invalid {
+		t.Fatalf("expected an invalid result, got %v", results)
+	}
+	if checker.attempts != 0 {
+		t.Fatalf("expected the check not to run, got %d attempts", checker.attempts)
+	}
+}
+
+func TestRunCheckTasksStopsAtCanaryDeadline(t *testing.T) {
+	ctx := newRetryTestContext(nil)
+	ctx.Canary.Spec.Interval = 1
+	checker := &timeoutTestChecker{release: make(chan struct{})}
+	defer close(checker.release)
+
+	var tasks []checkTask
+	for _, name := range []string{"hung", "never-started"} {
+		tasks = append(tasks, checkTask{checker: checker, check: v1.HTTPCheck{Description: v1.Description{Name: name, CheckTimeout: "1m"}}})
+	}
+
+	start := time.Now()
+	results := runCheckTasks(ctx, tasks, 1, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the canary run to stop at its deadline, took %s", elapsed)
+	}
+	for i, result := range results {
+		if len(result.results) != 1 {
+			t.Fatalf("expected one result for %s, got %d", tasks[i].check.GetName(), len(result.results))
+		}
+		r := result.results[0]
+		if r.Pass || !r.TimedOut || r.Error != "canary deadline exceeded" {
+			t.Fatalf("expected %s to fail with the canary deadline, got pass=%v timedOut=%v error=%q", tasks[i].check.GetName(), r.Pass, r.TimedOut, r.Error)
+		}
+	}
+}
+
+func TestGetCanaryTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1.CanarySpec
+		want time.Duration
+	}{
+		{name: "cron schedule", spec: v1.CanarySpec{Schedule: "*/5 * * * *"}, want: 5 * time.Minute},
+		{name: "every descriptor", spec: v1.CanarySpec{Schedule: "@every 30s"}, want: 30 * time.Second},
+		{name: "interval", spec: v1.CanarySpec{Interval: 60}, want: time.Minute},
+		{name: "unscheduled", spec: v1.CanarySpec{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetCanaryTimeout(v1.Canary{Spec: tt.spec}); got != tt.want {
+				t.Fatalf("GetCanaryTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if !GetDeadline(v1.Canary{}).IsZero() {
+		t.Fatalf("expected unscheduled canaries to have no deadline")
+	}
+}
@@ -0,0 +1,166 @@
+package checks
+
+import (
+	"fmt"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	"github.com/flanksource/canary-checker/pkg"
+)
+
+const defaultCheckConcurrency = 1
+
+// checkTask is a single check scheduled as part of a canary run
+type checkTask struct {
+	checker Checker
+	check   external.Check
+	// dependsOn holds the indexes of the tasks that must complete before this one is started
+	dependsOn []int
+}
+
+type checkTaskResult struct {
+	results pkg.Results
+	skipped int
+}
+
+// getCheckConcurrency returns the maximum number of checks of a canary that can run at once
+func getCheckConcurrency(ctx *context.Context) int {
+	if ctx.Canary.Spec.Concurrency > 0 {
+		return ctx.Canary.Spec.Concurrency
+	}
+	return max(ctx.Properties().Int("check.concurrency", defaultCheckConcurrency), 1)
+}
+
+// getCheckTasks returns the checks to run in execution order, with dependsOn references
+// resolved to task indexes. Checks of a disabled type or without a checker are skipped.
+func getCheckTasks(ctx *context.Context, checks []external.Check, disabledChecks map[string]struct{}) ([]checkTask, error) {
+	var tasks []checkTask
+
+	if !hasDependencies(checks) {
+		for _, c := range All {
+			if _, ok := disabledChecks[c.Type()]; ok {
+				continue
+			}
+
+			for _, check := range checksOfType(checks, c.Type()) {
+				tasks = append(tasks, checkTask{checker: c, check: check})
+			}
+		}
+		return tasks, nil
+	}
+
+	sortedChecks, err := sortChecksByDependency(checks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sort checks: %v", err)
+	}
+
+	taskIndex := make(map[string]int)
+	for _, check := range sortedChecks {
+		if _, ok := disabledChecks[check.GetType()]; ok {
+			continue
+		}
+
+		checker := getCheckerForType(check.GetType())
+		if checker == nil {
+			ctx.Warnf("no checker found for type %s", check.GetType())
+			continue
+		}
+
+		task := checkTask{checker: checker, check: check}
+		for _, dep := range check.GetDependsOn() {
+			// dependencies that were skipped do not block the check
+			if i, ok := taskIndex[dep]; ok {
+				task.dependsOn = append(task.dependsOn, i)
+			}
+		}
+
+		if check.GetName() != "" {
+			taskIndex[check.GetName()] = len(tasks)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// runCheckTasks runs the tasks with at most concurrency of them in flight at any time.
+// A task is started only once all of its dependencies have completed, and earlier tasks
+// are always started first, so a concurrency of 1 runs the tasks sequentially in order.
+//
+// onComplete is called from the calling goroutine as each task finishes, while the
+// returned results are in task order regardless of the order in which they completed.
+//
+// The tasks share the deadline of the canary run, tasks that have not started by then
+// are reported as timed out without running.
+func runCheckTasks(ctx *context.Context, tasks []checkTask, concurrency int, onComplete func(task checkTask, result checkTaskResult)) []checkTaskResult {
+	ctx, cancel := withCanaryDeadline(ctx)
+	defer cancel()
+
+	results := make([]checkTaskResult, len(tasks))
+	started := make([]bool, len(tasks))
+	completed := make([]bool, len(tasks))
+	done := make(chan int)
+
+	isReady := func(i int) bool {
+		for _, dep := range tasks[i].dependsOn {
+			if !completed[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	running, remaining := 0, len(tasks)
+	for remaining > 0 {
+		for i := range tasks {
+			if running >= concurrency {
+				break
+			}
+			if started[i] || !isReady(i) {
+				continue
+			}
+
+			started[i] = true
+			running++
+
+			// every task gets its own copy of the environment and a snapshot of the
+			// outputs of the checks that have completed so far
+			taskCtx := ctx.Clone()
+			if len(taskCtx.Outputs) > 0 {
+				taskCtx.Environment["outputs"] = taskCtx.GetOutputs()
+			}
+
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						results[i] = checkTaskResult{results: pkg.Invalid(tasks[i].check, taskCtx.Canary, fmt.Sprintf("panic: %v", r))}
+					}
+					done <- i
+				}()
+
+				if taskCtx.Err() != nil {
+					results[i] = checkTaskResult{results: canaryDeadlineExceeded(taskCtx, tasks[i].check).ToSlice()}
+					return
+				}
+
+				res, skipped := runCheckWithRetries(taskCtx, tasks[i].checker, tasks[i].check)
+				results[i] = checkTaskResult{results: res, skipped: skipped}
+			}()
+		}
+
+		if running == 0 {
+			// nothing is in flight and nothing can be started, waiting would block forever
+			break
+		}
+
+		i := <-done
+		running--
+		remaining--
+		completed[i] = true
+		if onComplete != nil {
+			onComplete(tasks[i], results[i])
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,671 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+var smtpDeliverySeconds = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "canary_check_smtp_delivery_seconds",
+		Help: "The number of seconds for the test message to arrive in the mailbox",
+	},
+	[]string{"url", "mailbox"},
+)
+
+func init() {
+	prometheus.MustRegister(smtpDeliverySeconds)
+}
+
+type SMTPChecker struct{}
+
+// Type: returns checker type
+func (c *SMTPChecker) Type() string {
+	return "smtp"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *SMTPChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.SMTP {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// SMTPReply is the reply of the server to a command
+type SMTPReply struct {
+	Command string `json:"command"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// SMTPCheckResult is the data of an smtp check
+type SMTPCheckResult struct {
+	// Codes is the last reply code to each command e.g. connect, ehlo, starttls, auth, mail, rcpt, data,
+	// message and quit
+	Codes          map[string]int `json:"codes"`
+	Replies        []SMTPReply    `json:"replies"`
+	Extensions     []string       `json:"extensions,omitempty"`
+	TLS            string         `json:"tls,omitempty"`
+	MessageID      string         `json:"messageId,omitempty"`
+	DeliveryMillis int64          `json:"deliveryMillis,omitempty"`
+}
+
+func (c *SMTPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.SMTPCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	addr, implicitTLS, err := mailServerAddress(connection.URL, "smtp", 25, 465)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	tlsConfig, err := mailTLSConfig(ctx, check.TLSConfig, addr)
+	if err != nil {
+		return results.Invalidf("invalid tls config: %v", err)
+	}
+	timeout, err := check.SessionTimeout.GetDurationOr(time.Minute)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid session timeout %s", check.SessionTimeout)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+
+	var from string
+	var to []string
+	if check.Mail != nil {
+		if from, to, err = smtpEnvelope(*check.Mail); err != nil {
+			return results.Invalidf("%v", err)
+		}
+	} else if check.RoundTrip != nil {
+		return results.Invalidf("roundTrip requires a mail to send")
+	}
+
+	data := SMTPCheckResult{Codes: map[string]int{}}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	start := time.Now()
+
+	// the mailbox is opened before sending so that a message is not sent when it cannot be received
+	var mailbox *imapMailbox
+	if check.RoundTrip != nil {
+		if mailbox, err = openSMTPRoundTrip(ctx, proxy, *check.RoundTrip, timeout); err != nil {
+			if errors.Is(err, errInvalidRoundTrip) {
+				return results.Invalidf("%v", err)
+			}
+			return results.Failf("%v", err)
+		}
+		defer mailbox.close()
+	}
+
+	timeoutCtx, cancel := gocontext.WithTimeout(ctx, timeout)
+	defer cancel()
+	session, err := dialSMTP(timeoutCtx, proxy, addr, implicitTLS, tlsConfig, &data)
+	if err != nil {
+		return results.Failf("failed to connect to %s: %v", addr, err)
+	}
+	defer session.close()
+
+	hello := check.Hello
+	if hello == "" {
+		hello = "localhost"
+	}
+	if err := session.hello(hello); err != nil {
+		return results.Failf("%v", err)
+	}
+	if !implicitTLS {
+		if _, ok := session.extensions["STARTTLS"]; ok {
+			if err := session.startTLS(timeoutCtx, tlsConfig); err != nil {
+				return results.Failf("%v", err)
+			}
+			if err := session.hello(hello); err != nil {
+				return results.Failf("%v", err)
+			}
+		} else if !check.SkipStartTLS {
+			return results.Failf("%s does not support STARTTLS", addr)
+		}
+	}
+	data.Extensions = session.extensionNames()
+
+	if connection.Username != "" || connection.Password != "" {
+		if data.TLS == "" {
+			return results.Failf("refusing to authenticate over an unencrypted connection to %s", addr)
+		}
+		if err := session.auth(connection.Username, connection.Password); err != nil {
+			return results.Failf("%v", err)
+		}
+	}
+
+	var sent time.Time
+	if check.Mail != nil {
+		data.MessageID = fmt.Sprintf("<%s@canary-checker>", uuid.New())
+		subject := check.Mail.Subject
+		if subject == "" {
+			subject = check.GetName()
+		}
+		message := newSMTPMessage(*check.Mail, subject, data.MessageID, time.Now())
+		if err := session.send(from, to, message); err != nil {
+			return results.Failf("%v", err)
+		}
+		sent = time.Now()
+	}
+	if err := session.quit(); err != nil {
+		return results.Failf("%v", err)
+	}
+
+	if mailbox != nil {
+		delivery, err := mailbox.waitFor(ctx, data.MessageID, sent)
+		if err != nil {
+			return results.Failf("%v", err)
+		}
+		data.DeliveryMillis = delivery.Milliseconds()
+		smtpDeliverySeconds.WithLabelValues(v1.SanitizeEndpoints(connection.URL), mailbox.name).Set(delivery.Seconds())
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// mailServerAddress returns the host:port of a url of the form scheme://host[:port], or host[:port] for
+// the plaintext scheme, and whether the tlsScheme with implicit TLS was used
+func mailServerAddress(endpoint, scheme string, port, tlsPort int) (string, bool, error) {
+	if endpoint == "" {
+		return "", false, errors.New("a url or connection is required")
+	}
+	host, implicitTLS := endpoint, false
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != scheme && u.Scheme != scheme+"s") {
+			return "", false, fmt.Errorf("invalid url %s, expected %s://host[:port] or %ss://host[:port]", v1.SanitizeEndpoints(endpoint), scheme, scheme)
+		}
+		host, implicitTLS = u.Host, u.Scheme == scheme+"s"
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if implicitTLS {
+			port = tlsPort
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+	}
+	return host, implicitTLS, nil
+}
+
+func mailTLSConfig(ctx *context.Context, config *v1.TLSConfig, addr string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if config != nil {
+		var err error
+		if tlsConfig, err = config.ToTLSConfig(ctx, ctx.GetNamespace()); err != nil {
+			return nil, err
+		}
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConfig.ServerName = host
+	tlsConfig.MinVersion = tls.VersionTLS12
+	return tlsConfig, nil
+}
+
+// smtpEnvelope returns the addresses of the sender and the recipients
+func smtpEnvelope(mail v1.SMTPMail) (string, []string, error) {
+	if mail.From == "" || len(mail.To) == 0 {
+		return "", nil, errors.New("mail.from and mail.to are required")
+	}
+	from, err := netMailAddress(mail.From)
+	if err != nil {
+		return "", nil, err
+	}
+	var to []string
+	for _, recipient := range mail.To {
+		address, err := netMailAddress(recipient)
+		if err != nil {
+			return "", nil, err
+		}
+		to = append(to, address)
+	}
+	return from, to, nil
+}
+
+func netMailAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return parsed.Address, nil
+}
+
+// newSMTPMessage returns a plain text message, the line endings are converted by the dot writer
+func newSMTPMessage(mail v1.SMTPMail, subject, messageID string, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\n", mail.From)
+	fmt.Fprintf(&buf, "To: %s\n", strings.Join(mail.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\n", messageID)
+	buf.WriteString("MIME-Version: 1.0\nContent-Type: text/plain; charset=utf-8\n\n")
+	buf.WriteString(mail.Body)
+	if !strings.HasSuffix(mail.Body, "\n") {
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// smtpSession records the reply to every command in the check data
+type smtpSession struct {
+	conn       net.Conn
+	text       *textproto.Conn
+	data       *SMTPCheckResult
+	extensions map[string]string
+	stop       func() bool
+}
+
+func dialSMTP(ctx gocontext.Context, proxy *checkProxy, addr string, implicitTLS bool, tlsConfig *tls.Config, data *SMTPCheckResult) (*smtpSession, error) {
+	conn, err := proxy.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	session := &smtpSession{conn: conn, data: data}
+	// closing the connection interrupts the session when the timeout expires
+	session.stop = gocontext.AfterFunc(ctx, func() { _ = conn.Close() })
+	if implicitTLS {
+		if err := session.handshake(ctx, tlsConfig); err != nil {
+			session.close()
+			return nil, err
+		}
+	}
+	session.text = textproto.NewConn(session.conn)
+	if _, err := session.read("connect", 220); err != nil {
+		session.close()
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *smtpSession) handshake(ctx gocontext.Context, tlsConfig *tls.Config) error {
+	tlsConn := tls.Client(s.conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	s.conn = tlsConn
+	s.data.TLS = tls.VersionName(tlsConn.ConnectionState().Version)
+	return nil
+}
+
+func (s *smtpSession) read(command string, expectCode int) (string, error) {
+	code, message, err := s.text.ReadResponse(expectCode)
+	if code != 0 {
+		s.data.Codes[command] = code
+		s.data.Replies = append(s.data.Replies, SMTPReply{Command: command, Code: code, Message: message})
+	}
+	if err != nil {
+		return message, fmt.Errorf("%s failed: %w", command, err)
+	}
+	return message, nil
+}
+
+func (s *smtpSession) cmd(command string, expectCode int, format string, args ...any) (string, error) {
+	id, err := s.text.Cmd(format, args...)
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", command, err)
+	}
+	s.text.StartResponse(id)
+	defer s.text.EndResponse(id)
+	return s.read(command, expectCode)
+}
+
+func (s *smtpSession) hello(name string) error {
+	message, err := s.cmd("ehlo", 250, "EHLO %s", name)
+	if err != nil {
+		return err
+	}
+	s.extensions = map[string]string{}
+	for _, line := range strings.Split(message, "\n")[1:] {
+		keyword, params, _ := strings.Cut(strings.TrimSpace(line), " ")
+		s.extensions[strings.ToUpper(keyword)] = params
+	}
+	return nil
+}
+
+func (s *smtpSession) extensionNames() []string {
+	var names []string
+	for name := range s.extensions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *smtpSession) startTLS(ctx gocontext.Context, tlsConfig *tls.Config) error {
+	if _, err := s.cmd("starttls", 220, "STARTTLS"); err != nil {
+		return err
+	}
+	if err := s.handshake(ctx, tlsConfig); err != nil {
+		return err
+	}
+	s.text = textproto.NewConn(s.conn)
+	return nil
+}
+
+// auth authenticates with PLAIN, or LOGIN for servers that do not support it
+func (s *smtpSession) auth(username, password string) error {
+	mechanisms := strings.Fields(strings.ToUpper(s.extensions["AUTH"]))
+	encode := base64.StdEncoding.EncodeToString
+	switch {
+	case slices.Contains(mechanisms, "PLAIN"):
+		_, err := s.cmd("auth", 235, "AUTH PLAIN %s", encode([]byte("\x00"+username+"\x00"+password)))
+		return err
+	case slices.Contains(mechanisms, "LOGIN"):
+		if _, err := s.cmd("auth", 334, "AUTH LOGIN"); err != nil {
+			return err
+		}
+		if _, err := s.cmd("auth", 334, "%s", encode([]byte(username))); err != nil {
+			return err
+		}
+		_, err := s.cmd("auth", 235, "%s", encode([]byte(password)))
+		return err
+	case len(mechanisms) == 0:
+		return errors.New("the server does not support authentication")
+	default:
+		return fmt.Errorf("the server does not support AUTH PLAIN or LOGIN, only %s", strings.Join(mechanisms, ", "))
+	}
+}
+
+func (s *smtpSession) send(from string, to []string, message []byte) error {
+	if _, err := s.cmd("mail", 250, "MAIL FROM:<%s>", from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		// 251 is returned when the recipient is forwarded
+		if _, err := s.cmd("rcpt", 25, "RCPT TO:<%s>", recipient); err != nil {
+			return err
+		}
+	}
+	if _, err := s.cmd("data", 354, "DATA"); err != nil {
+		return err
+	}
+	w := s.text.DotWriter()
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	_, err := s.read("message", 250)
+	return err
+}
+
+func (s *smtpSession) quit() error {
+	_, err := s.cmd("quit", 221, "QUIT")
+	return err
+}
+
+func (s *smtpSession) close() {
+	s.stop()
+	_ = s.conn.Close()
+}
+
+var errInvalidRoundTrip = errors.New("invalid roundTrip")
+
+// imapMailbox is a minimal IMAP client to search for and delete the test message
+type imapMailbox struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	conn     net.Conn
+	reader   *bufio.Reader
+	tag      int
+	uidplus  bool
+	stop     func() bool
+}
+
+func openSMTPRoundTrip(ctx *context.Context, proxy *checkProxy, roundTrip v1.SMTPRoundTrip, smtpTimeout time.Duration) (*imapMailbox, error) {
+	connection, err := ctx.GetConnection(roundTrip.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("error getting roundTrip connection: %w", err)
+	}
+	addr, implicitTLS, err := mailServerAddress(connection.URL, "imap", 143, 993)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRoundTrip, err)
+	}
+	tlsConfig, err := mailTLSConfig(ctx, roundTrip.TLSConfig, addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid tls config: %v", errInvalidRoundTrip, err)
+	}
+	mailbox := &imapMailbox{name: roundTrip.Mailbox}
+	if mailbox.name == "" {
+		mailbox.name = "INBOX"
+	}
+	if mailbox.timeout, err = roundTrip.Timeout.GetDurationOr(5 * time.Minute); err != nil || mailbox.timeout <= 0 {
+		return nil, fmt.Errorf("%w: invalid timeout %s", errInvalidRoundTrip, roundTrip.Timeout)
+	}
+	if mailbox.interval, err = roundTrip.Interval.GetDurationOr(5 * time.Second); err != nil || mailbox.interval <= 0 {
+		return nil, fmt.Errorf("%w: invalid interval %s", errInvalidRoundTrip, roundTrip.Interval)
+	}
+	if connection.Username == "" || connection.Password == "" {
+		return nil, fmt.Errorf("%w: a username and password are required", errInvalidRoundTrip)
+	}
+
+	conn, err := proxy.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	mailbox.conn = conn
+	// the mailbox stays open while the message is sent and delivered
+	timeoutCtx, cancel := gocontext.WithTimeout(ctx, smtpTimeout+mailbox.timeout)
+	stop := gocontext.AfterFunc(timeoutCtx, func() { _ = conn.Close() })
+	mailbox.stop = func() bool {
+		cancel()
+		return stop()
+	}
+	if err := mailbox.login(timeoutCtx, implicitTLS, tlsConfig, connection.Username, connection.Password); err != nil {
+		mailbox.close()
+		return nil, fmt.Errorf("imap %s: %w", addr, err)
+	}
+	return mailbox, nil
+}
+
+func (m *imapMailbox) login(ctx gocontext.Context, implicitTLS bool, tlsConfig *tls.Config, username, password string) error {
+	if implicitTLS {
+		if err := m.handshake(ctx, tlsConfig); err != nil {
+			return err
+		}
+	}
+	m.reader = bufio.NewReader(m.conn)
+	greeting, err := m.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+	}
+	if !implicitTLS {
+		if _, err := m.cmd("STARTTLS"); err != nil {
+			return err
+		}
+		if err := m.handshake(ctx, tlsConfig); err != nil {
+			return err
+		}
+		m.reader = bufio.NewReader(m.conn)
+	}
+
+	if _, err := m.cmd("LOGIN %s %s", imapQuote(username), imapQuote(password)); err != nil {
+		return err
+	}
+	capabilities, err := m.cmd("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	for _, line := range capabilities {
+		if strings.HasPrefix(line, "CAPABILITY ") && slices.Contains(strings.Fields(line), "UIDPLUS") {
+			m.uidplus = true
+		}
+	}
+	_, err = m.cmd("SELECT %s", imapQuote(m.name))
+	return err
+}
+
+func (m *imapMailbox) handshake(ctx gocontext.Context, tlsConfig *tls.Config) error {
+	tlsConn := tls.Client(m.conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	m.conn = tlsConn
+	return nil
+}
+
+// cmd sends a tagged command and returns the untagged responses, without their "* " prefix
+func (m *imapMailbox) cmd(format string, args ...any) ([]string, error) {
+	m.tag++
+	tag := fmt.Sprintf("a%d", m.tag)
+	command := fmt.Sprintf(format, args...)
+	if _, err := fmt.Fprintf(m.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+	name, _, _ := strings.Cut(command, " ")
+	var untagged []string
+	for {
+		line, err := m.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return untagged, fmt.Errorf("%s failed: %s", name, rest)
+			}
+			return untagged, nil
+		}
+		if rest, ok := strings.CutPrefix(line, "* "); ok {
+			untagged = append(untagged, rest)
+		}
+	}
+}
+
+// readLine reads a response line, skipping the content of any literals
+func (m *imapMailbox) readLine() (string, error) {
+	var line strings.Builder
+	for {
+		part, err := m.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		line.WriteString(part)
+		open := strings.LastIndex(part, "{")
+		if open < 0 || !strings.HasSuffix(part, "}") {
+			return line.String(), nil
+		}
+		size, err := strconv.ParseInt(strings.TrimSuffix(part[open+1:], "}"), 10, 64)
+		if err != nil {
+			return line.String(), nil
+		}
+		if _, err := io.CopyN(io.Discard, m.reader, size); err != nil {
+			return "", err
+		}
+	}
+}
+
+// waitFor searches the mailbox until the message arrives and then deletes it, returning the time since
+// it was sent
+func (m *imapMailbox) waitFor(ctx gocontext.Context, messageID string, sent time.Time) (time.Duration, error) {
+	timeoutCtx, cancel := gocontext.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	for {
+		// NOOP lets the server report messages that arrived since the last search
+		if _, err := m.cmd("NOOP"); err != nil {
+			return 0, err
+		}
+		uids, err := m.search(messageID)
+		if err != nil {
+			return 0, err
+		}
+		if len(uids) > 0 {
+			delivery := time.Since(sent)
+			if err := m.delete(uids); err != nil {
+				return delivery, fmt.Errorf("failed to delete %s: %w", messageID, err)
+			}
+			return delivery, nil
+		}
+		select {
+		case <-timeoutCtx.Done():
+			return 0, fmt.Errorf("%s was not delivered to %s within %s", messageID, m.name, m.timeout)
+		case <-time.After(m.interval):
+		}
+	}
+}
+
+func (m *imapMailbox) search(messageID string) ([]string, error) {
+	untagged, err := m.cmd("UID SEARCH HEADER Message-ID %s", imapQuote(messageID))
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, line := range untagged {
+		if rest, ok := strings.CutPrefix(line, "SEARCH"); ok {
+			uids = append(uids, strings.Fields(rest)...)
+		}
+	}
+	return uids, nil
+}
+
+func (m *imapMailbox) delete(uids []string) error {
+	set := strings.Join(uids, ",")
+	if _, err := m.cmd(`UID STORE %s +FLAGS.SILENT (\Deleted)`, set); err != nil {
+		return err
+	}
+	// without UIDPLUS every message flagged as deleted in the mailbox is expunged
+	if m.uidplus {
+		_, err := m.cmd("UID EXPUNGE %s", set)
+		return err
+	}
+	_, err := m.cmd("EXPUNGE")
+	return err
+}
+
+func (m *imapMailbox) close() {
+	if m.reader != nil {
+		_, _ = m.cmd("LOGOUT")
+	}
+	m.stop()
+	_ = m.conn.Close()
+}
+
+// imapQuote returns s as an IMAP quoted string, quoted strings cannot contain line breaks
+func imapQuote(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,296 @@
+package checks
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flanksource/duty/types"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// testMailbox is shared by the test smtp and imap servers, messages to blackhole.example.com are dropped
+type testMailbox struct {
+	sync.Mutex
+	uid      int
+	messages map[int]string
+}
+
+func (m *testMailbox) deliver(message string) {
+	m.Lock()
+	defer m.Unlock()
+	m.uid++
+	m.messages[m.uid] = message
+}
+
+func (m *testMailbox) count() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.messages)
+}
+
+func newTestMailServer(t *testing.T, serve func(net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// serveTestSMTP accepts user:pass with AUTH PLAIN after STARTTLS when tlsConfig is set
+func serveTestSMTP(conn net.Conn, tlsConfig *tls.Config, mailbox *testMailbox) {
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 mail.example.com ESMTP")
+	secure := false
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if tlsConfig != nil && !secure {
+				_ = text.PrintfLine("250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS")
+			} else {
+				_ = text.PrintfLine("250-mail.example.com\r\n250-8BITMIME\r\n250 AUTH LOGIN PLAIN")
+			}
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, text, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			if arg == "PLAIN "+base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass")) {
+				_ = text.PrintfLine("235 2.7.0 authenticated")
+			} else {
+				_ = text.PrintfLine("535 5.7.8 authentication failed")
+			}
+		case "MAIL":
+			_ = text.PrintfLine("250 2.1.0 ok")
+		case "RCPT":
+			if strings.Contains(arg, "unknown@") {
+				_ = text.PrintfLine("550 5.1.1 no such user")
+			} else {
+				_ = text.PrintfLine("250 2.1.5 ok")
+			}
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			message, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if !strings.Contains(string(message), "blackhole.example.com") {
+				mailbox.deliver(string(message))
+			}
+			_ = text.PrintfLine("250 2.0.0 queued")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func serveTestIMAP(conn net.Conn, tlsConfig *tls.Config, mailbox *testMailbox) {
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+		args := strings.Fields(command)
+		reply := "OK done"
+		switch strings.ToUpper(args[0]) {
+		case "STARTTLS":
+			fmt.Fprintf(conn, "%s OK begin tls\r\n", tag)
+			tlsConn := tls.Server(conn, tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, reader = tlsConn, bufio.NewReader(tlsConn)
+			continue
+		case "LOGIN":
+			if args[1] != `"user"` || args[2] != `"pass"` {
+				reply = "NO [AUTHENTICATIONFAILED] invalid credentials"
+			}
+		case "CAPABILITY":
+			fmt.Fprint(conn, "* CAPABILITY IMAP4rev1 UIDPLUS\r\n")
+		case "SELECT":
+			fmt.Fprintf(conn, "* %d EXISTS\r\n", mailbox.count())
+		case "UID":
+			mailbox.Lock()
+			switch args[1] {
+			case "SEARCH":
+				messageID, _ := strconv.Unquote(args[4])
+				var uids []string
+				for uid, message := range mailbox.messages {
+					if strings.Contains(message, "Message-ID: "+messageID) {
+						uids = append(uids, strconv.Itoa(uid))
+					}
+				}
+				fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+			case "EXPUNGE":
+				for _, uid := range strings.Split(args[2], ",") {
+					id, _ := strconv.Atoi(uid)
+					delete(mailbox.messages, id)
+				}
+			}
+			mailbox.Unlock()
+		case "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK logged out\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s %s\r\n", tag, reply)
+	}
+}
+
+func newTestSMTPCheck(t *testing.T) (v1.SMTPCheck, *testMailbox) {
+	server, ca := newTLSTestServer(t)
+	mailbox := &testMailbox{messages: map[int]string{}}
+	smtpAddr := newTestMailServer(t, func(conn net.Conn) { serveTestSMTP(conn, server.TLS, mailbox) })
+	imapAddr := newTestMailServer(t, func(conn net.Conn) { serveTestIMAP(conn, server.TLS, mailbox) })
+
+	credentials := types.Authentication{
+		Username: types.EnvVar{ValueStatic: "user"},
+		Password: types.EnvVar{ValueStatic: "pass"},
+	}
+	return v1.SMTPCheck{
+		Description: v1.Description{Name: "smtp"},
+		Connection:  v1.Connection{URL: "smtp://" + smtpAddr, Authentication: credentials},
+		TLSConfig:   &v1.TLSConfig{CA: ca},
+		Mail: &v1.SMTPMail{
+			From: "Canary <canary@example.com>",
+			To:   []string{"monitoring@example.com"},
+			Body: "hello\n.\nworld",
+		},
+		RoundTrip: &v1.SMTPRoundTrip{
+			Connection: v1.Connection{URL: "imap://" + imapAddr, Authentication: credentials},
+			TLSConfig:  &v1.TLSConfig{CA: ca},
+			Interval:   "10ms",
+			Timeout:    "1s",
+		},
+	}, mailbox
+}
+
+func TestSMTPCheckerRoundTrip(t *testing.T) {
+	check, mailbox := newTestSMTPCheck(t)
+	results := (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	codes := data["codes"].(map[string]any)
+	for command, code := range map[string]float64{"connect": 220, "starttls": 220, "auth": 235, "rcpt": 250, "data": 354, "message": 250, "quit": 221} {
+		if codes[command] != code {
+			t.Errorf("expected %s to reply %v, got %v", command, code, codes[command])
+		}
+	}
+	if data["tls"] != "TLS 1.3" || data["messageId"] == nil {
+		t.Errorf("expected the tls version and message id, got %v", data)
+	}
+	if mailbox.count() != 0 {
+		t.Errorf("expected the test message to be deleted, %d remain", mailbox.count())
+	}
+
+	check.Mail.To = []string{"unknown@example.com"}
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "rcpt failed: 550") {
+		t.Errorf("expected the recipient to be rejected, got %s", results[0].Error)
+	}
+
+	check.Mail.To = []string{"monitoring@blackhole.example.com"}
+	check.RoundTrip.Timeout = "100ms"
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "was not delivered to INBOX within 100ms") {
+		t.Errorf("expected the message not to be delivered, got %s", results[0].Error)
+	}
+
+	check.RoundTrip.Password = types.EnvVar{ValueStatic: "wrong"}
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "AUTHENTICATIONFAILED") {
+		t.Errorf("expected the imap login to fail, got %s", results[0].Error)
+	}
+}
+
+func TestSMTPCheckerFailures(t *testing.T) {
+	check, _ := newTestSMTPCheck(t)
+	check.Mail, check.RoundTrip = nil, nil
+	check.Password = types.EnvVar{ValueStatic: "wrong"}
+	results := (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "auth failed: 535") {
+		t.Errorf("expected the authentication to fail, got %s", results[0].Error)
+	}
+
+	check.TLSConfig = nil
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "tls handshake failed") {
+		t.Errorf("expected the certificate to be untrusted, got %s", results[0].Error)
+	}
+
+	plain := newTestMailServer(t, func(conn net.Conn) { serveTestSMTP(conn, nil, nil) })
+	check.URL = plain
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "does not support STARTTLS") {
+		t.Errorf("expected STARTTLS to be required, got %s", results[0].Error)
+	}
+
+	check.SkipStartTLS = true
+	results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "refusing to authenticate") {
+		t.Errorf("expected the credentials not to be sent in plaintext, got %s", results[0].Error)
+	}
+
+	check.Username, check.Password = types.EnvVar{}, types.EnvVar{}
+	if results = (&SMTPChecker{}).Check(newRetryTestContext(nil), check); !results[0].Pass {
+		t.Errorf("expected an anonymous plaintext session to pass, got %s", results[0].Error)
+	}
+}
+
+func TestMailServerAddress(t *testing.T) {
+	tests := map[string]struct {
+		addr        string
+		implicitTLS bool
+	}{
+		"mail.example.com":          {"mail.example.com:25", false},
+		"mail.example.com:587":      {"mail.example.com:587", false},
+		"smtp://mail.example.com":   {"mail.example.com:25", false},
+		"smtps://mail.example.com":  {"mail.example.com:465", true},
+		"smtps://[2001:db8::1]:587": {"[2001:db8::1]:587", true},
+	}
+	for endpoint, expected := range tests {
+		addr, implicitTLS, err := mailServerAddress(endpoint, "smtp", 25, 465)
+		if err != nil || addr != expected.addr || implicitTLS != expected.implicitTLS {
+			t.Errorf("mailServerAddress(%s) = %s, %v, %v, expected %s, %v", endpoint, addr, implicitTLS, err, expected.addr, expected.implicitTLS)
+		}
+	}
+	if _, _, err := mailServerAddress("imaps://mail.example.com", "smtp", 25, 465); err == nil {
+		t.Error("expected an imap url to be rejected")
+	}
+}
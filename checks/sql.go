@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -156,6 +158,9 @@ func CheckSQL(ctx *context.Context, checker SQLChecker) pkg.Results { // nolint:
 	if err != nil {
 		return results.Failf("error getting connection: %v", err)
 	}
+	if err := unsupportedProxy(ctx, checker.GetCheck().GetType(), sqlAddress(connection.URL)); err != nil {
+		return results.Invalidf("%v", err)
+	}
 
 	query := check.GetQuery()
 	timout := check.GetQueryTimeout()
@@ -229,3 +234,16 @@ func getRowValues(columnTypes []*sql.ColumnType) []interface{} {
 
 	return rowValues
 }
+
+var mysqlAddress = regexp.MustCompile(`@\w+\(([^)]*)\)`)
+
+// sqlAddress returns the host:port of a url or mysql dsn, or an empty string if it cannot be parsed
+func sqlAddress(connection string) string {
+	if match := mysqlAddress.FindStringSubmatch(connection); match != nil {
+		return match[1]
+	}
+	if u, err := url.Parse(connection); err == nil {
+		return u.Host
+	}
+	return ""
+}
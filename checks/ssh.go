@@ -0,0 +1,303 @@
+package checks
+
+import (
+	"bytes"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/shell"
+)
+
+type SSHChecker struct{}
+
+// Type: returns checker type
+func (c *SSHChecker) Type() string {
+	return "ssh"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *SSHChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.SSH {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// SSHCheckResult is the data of an ssh check
+type SSHCheckResult struct {
+	ServerVersion      string `json:"serverVersion"`
+	HostKeyType        string `json:"hostKeyType"`
+	HostKeyFingerprint string `json:"hostKeyFingerprint"`
+}
+
+func (c *SSHChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.SSHCheck)
+	result := pkg.Success(check, ctx.Canary)
+	if check.Command != "" {
+		result.AddDetails(shell.ExecDetails{ExitCode: -1})
+	}
+	var results pkg.Results
+	results = append(results, result)
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	addr, user, err := sshAddress(connection.URL, connection.Username)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	auth, err := sshAuthMethods(ctx, check, connection)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	hostKeyCallback, err := sshHostKeyCallback(ctx, check)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	timeout, err := check.SessionTimeout.GetDurationOr(time.Minute)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid session timeout %s", check.SessionTimeout)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+
+	var data SSHCheckResult
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	config := &ssh.ClientConfig{
+		User: user,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			data.HostKeyType = key.Type()
+			data.HostKeyFingerprint = ssh.FingerprintSHA256(key)
+			return hostKeyCallback(hostname, remote, key)
+		},
+		Timeout: timeout,
+	}
+
+	timeoutCtx, cancel := gocontext.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+
+	conn, err := proxy.DialContext(timeoutCtx, "tcp", addr)
+	if err != nil {
+		return results.Failf("failed to connect to %s: %v", addr, err)
+	}
+	// closing the connection interrupts the handshake and the command when the timeout expires
+	stop := gocontext.AfterFunc(timeoutCtx, func() { _ = conn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return results.Failf("ssh handshake with %s failed: %v", addr, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+	data.ServerVersion = string(client.ServerVersion())
+
+	if check.Command != "" {
+		details, err := runSSHCommand(client, check.Command)
+		if timeoutCtx.Err() != nil {
+			return results.Failf("timed out after %s running %s", timeout, check.Command)
+		} else if err != nil {
+			return results.Failf("failed to run %s: %v", check.Command, err)
+		}
+		result.AddDetails(details)
+		if details.ExitCode != 0 {
+			if details.Stderr != "" {
+				return results.Failf("%s", details.Stderr)
+			}
+			return results.Failf("exit code %d", details.ExitCode)
+		}
+	}
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// sshAddress returns the host:port and user to connect with, from a url of the form host[:port] or
+// ssh://[user@]host[:port]
+func sshAddress(endpoint, username string) (string, string, error) {
+	if endpoint == "" {
+		return "", "", errors.New("a url or connection is required")
+	}
+	host := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Scheme != "ssh" || u.Host == "" {
+			return "", "", fmt.Errorf("invalid url %s, expected host[:port] or ssh://[user@]host[:port]", v1.SanitizeEndpoints(endpoint))
+		}
+		host = u.Host
+		if username == "" && u.User != nil {
+			username = u.User.Username()
+		}
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	if username == "" {
+		return "", "", errors.New("a username is required")
+	}
+	return host, username, nil
+}
+
+// sshAuthMethods authenticates with the private key if there is one, or else the password
+func sshAuthMethods(ctx *context.Context, check v1.SSHCheck, connection *models.Connection) ([]ssh.AuthMethod, error) {
+	key, err := ctx.GetEnvValueFromCache(check.PrivateKey, ctx.GetNamespace())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private key: %w", err)
+	}
+	if key == "" {
+		key = connection.Certificate
+	}
+
+	if key != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(key))
+		var passphraseMissing *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseMissing) {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(connection.Password))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+	if connection.Password == "" {
+		return nil, errors.New("a password or private key is required")
+	}
+	password := connection.Password
+	return []ssh.AuthMethod{
+		ssh.Password(password),
+		// servers that only allow keyboard-interactive authentication prompt for the password
+		ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = password
+			}
+			return answers, nil
+		}),
+	}, nil
+}
+
+// sshHostKeyCallback verifies the host key against the pinned host key or the known hosts
+func sshHostKeyCallback(ctx *context.Context, check v1.SSHCheck) (ssh.HostKeyCallback, error) {
+	if check.HostKey != "" {
+		return pinnedHostKeyCallback(check.HostKey)
+	}
+	if check.KnownHosts != nil {
+		knownHosts, err := ctx.GetEnvValueFromCache(*check.KnownHosts, ctx.GetNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get known hosts: %w", err)
+		}
+		if knownHosts != "" {
+			return knownHostsCallback(knownHosts)
+		}
+	}
+	if check.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	return nil, errors.New("one of hostKey, knownHosts or insecureIgnoreHostKey is required")
+}
+
+// pinnedHostKeyCallback accepts a host key with a SHA256: or MD5: fingerprint, or in the authorized_keys format
+func pinnedHostKeyCallback(pin string) (ssh.HostKeyCallback, error) {
+	pin = strings.TrimSpace(pin)
+	var fingerprint func(ssh.PublicKey) string
+	switch {
+	case strings.HasPrefix(pin, "SHA256:"):
+		fingerprint = ssh.FingerprintSHA256
+	case strings.HasPrefix(pin, "MD5:"):
+		fingerprint = func(key ssh.PublicKey) string {
+			return "MD5:" + ssh.FingerprintLegacyMD5(key)
+		}
+		pin = "MD5:" + strings.ToLower(strings.TrimPrefix(pin, "MD5:"))
+	default:
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pin))
+		if err != nil {
+			return nil, fmt.Errorf("invalid host key, expected a SHA256: or MD5: fingerprint or a public key: %w", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		if actual := fingerprint(key); actual != pin {
+			return fmt.Errorf("host key %s does not match %s", actual, pin)
+		}
+		return nil
+	}, nil
+}
+
+// knownHostsCallback verifies host keys against the entries of a known_hosts file
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(knownHosts); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	// the file is read when the callback is created
+	callback, err := knownhosts.New(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("invalid known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// runSSHCommand runs a command in a new session, a non-zero exit code is not an error
+func runSSHCommand(client *ssh.Client, command string) (shell.ExecDetails, error) {
+	details := shell.ExecDetails{ExitCode: -1}
+	session, err := client.NewSession()
+	if err != nil {
+		return details, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	err = session.Run(command)
+	details.Stdout = strings.TrimSpace(stdout.String())
+	details.Stderr = strings.TrimSpace(stderr.String())
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		details.ExitCode = exitErr.ExitStatus()
+		return details, nil
+	} else if err != nil {
+		return details, err
+	}
+	details.ExitCode = 0
+	return details, nil
+}
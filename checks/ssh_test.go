@@ -0,0 +1,196 @@
+package checks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/duty/shell"
+	"github.com/flanksource/duty/types"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// newTestSSHServer accepts canary:secret and replies to the hostname command, other commands exit with 127
+func newTestSSHServer(t *testing.T) (string, ssh.PublicKey) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "canary" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied for %s", c.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSSH(conn, config)
+		}
+	}()
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func serveTestSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var exec struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &exec)
+				_ = req.Reply(true, nil)
+				status := 0
+				if exec.Command == "hostname" {
+					fmt.Fprintln(channel, "bastion")
+				} else {
+					fmt.Fprintf(channel.Stderr(), "%s: command not found\n", exec.Command)
+					status = 127
+				}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+				return
+			}
+		}()
+	}
+}
+
+func TestSSHChecker(t *testing.T) {
+	addr, hostKey := newTestSSHServer(t)
+	check := v1.SSHCheck{
+		Description: v1.Description{Name: "ssh"},
+		Connection: v1.Connection{URL: "ssh://canary@" + addr, Authentication: types.Authentication{
+			Password: types.EnvVar{ValueStatic: "secret"},
+		}},
+		HostKey: ssh.FingerprintSHA256(hostKey),
+		Command: "hostname",
+	}
+	results := (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	if details := results[0].Detail.(shell.ExecDetails); details.Stdout != "bastion" || details.ExitCode != 0 {
+		t.Errorf("expected the hostname, got %+v", details)
+	}
+	if data := results[0].Data; data["hostKeyType"] != ssh.KeyAlgoED25519 || data["hostKeyFingerprint"] != check.HostKey {
+		t.Errorf("expected the host key in the data, got %v", data)
+	}
+
+	check.HostKey = "MD5:" + strings.ToUpper(ssh.FingerprintLegacyMD5(hostKey))
+	if results = (&SSHChecker{}).Check(newRetryTestContext(nil), check); !results[0].Pass {
+		t.Errorf("expected the MD5 fingerprint to match, got %s", results[0].Error)
+	}
+
+	check.HostKey = ""
+	check.KnownHosts = &types.EnvVar{ValueStatic: knownhosts.Line([]string{addr}, hostKey)}
+	if results = (&SSHChecker{}).Check(newRetryTestContext(nil), check); !results[0].Pass {
+		t.Errorf("expected the known hosts to match, got %s", results[0].Error)
+	}
+
+	check.Command = "missing"
+	results = (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "missing: command not found" {
+		t.Errorf("expected the command to fail, got %s", results[0].Error)
+	}
+	if details := results[0].Detail.(shell.ExecDetails); details.ExitCode != 127 {
+		t.Errorf("expected exit code 127, got %d", details.ExitCode)
+	}
+}
+
+func TestSSHCheckerHostKey(t *testing.T) {
+	addr, _ := newTestSSHServer(t)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewPublicKey(other.Public())
+
+	check := v1.SSHCheck{
+		Description: v1.Description{Name: "ssh"},
+		Connection: v1.Connection{URL: addr, Authentication: types.Authentication{
+			Username: types.EnvVar{ValueStatic: "canary"},
+			Password: types.EnvVar{ValueStatic: "secret"},
+		}},
+	}
+	results := (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "insecureIgnoreHostKey is required") {
+		t.Errorf("expected the host key to be required, got %s", results[0].Error)
+	}
+
+	check.HostKey = string(ssh.MarshalAuthorizedKey(otherKey))
+	results = (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "handshake") {
+		t.Errorf("expected another host key to be rejected, got %s", results[0].Error)
+	}
+
+	check.HostKey = ssh.FingerprintSHA256(otherKey)
+	results = (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "does not match") {
+		t.Errorf("expected another fingerprint to be rejected, got %s", results[0].Error)
+	}
+
+	check.HostKey = ""
+	check.InsecureIgnoreHostKey = true
+	check.Password = types.EnvVar{ValueStatic: "wrong"}
+	results = (&SSHChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "unable to authenticate") {
+		t.Errorf("expected the wrong password to fail, got %s", results[0].Error)
+	}
+}
+
+func TestSSHAddress(t *testing.T) {
+	tests := map[string][2]string{
+		"bastion":                  {"bastion:22", "root"},
+		"bastion:2222":             {"bastion:2222", "root"},
+		"ssh://git@bastion":        {"bastion:22", "git"},
+		"ssh://[2001:db8::1]:2222": {"[2001:db8::1]:2222", "root"},
+		"2001:db8::1":              {"[2001:db8::1]:22", "root"},
+	}
+	for url, expected := range tests {
+		username := "root"
+		if strings.Contains(url, "@") {
+			username = ""
+		}
+		addr, user, err := sshAddress(url, username)
+		if err != nil || addr != expected[0] || user != expected[1] {
+			t.Errorf("sshAddress(%s) = %s, %s, %v, expected %s, %s", url, addr, user, err, expected[0], expected[1])
+		}
+	}
+	if _, _, err := sshAddress("https://bastion", "root"); err == nil {
+		t.Error("expected an https url to be rejected")
+	}
+}
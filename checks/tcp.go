@@ -1,11 +1,16 @@
 package checks
 
 import (
+	gocontext "context"
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/flanksource/canary-checker/api/context"
 
@@ -31,6 +36,24 @@ func (t *TCPChecker) Run(ctx *context.Context) pkg.Results {
 	return results
 }
 
+// TCPCheckResult is the data of a tcp check with messages
+type TCPCheckResult struct {
+	Messages []TCPMessageResult `json:"messages"`
+}
+
+type TCPMessageResult struct {
+	// Response is the response that matched the expectation
+	Response string `json:"response,omitempty"`
+	// Hex is the hex encoding of a response that is not valid UTF-8
+	Hex             string `json:"hex,omitempty"`
+	RoundTripMillis int64  `json:"roundTripMillis,omitempty"`
+	// Skipped is the number of udp datagrams that did not match before the expected one
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// maxTCPResponse is the size of a tcp response after which waiting for it to match is given up
+const maxTCPResponse = 1 << 20
+
 // Check performs a single tcp check, returning a checkResult
 func (t *TCPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
 	c := extConfig.(v1.TCPCheck)
@@ -38,32 +61,229 @@ func (t *TCPChecker) Check(ctx *context.Context, extConfig external.Check) pkg.R
 	var results pkg.Results
 	results = append(results, result)
 
+	families, err := ipFamilies(c.IPFamily)
+	if err != nil {
+		return results.Invalidf("%v", err)
+	}
+	if len(families) > 1 {
+		return checkEachFamily(c.GetName(), families, func(name, family string) pkg.Results {
+			check := c
+			check.Name = name
+			check.IPFamily = family
+			return t.Check(ctx, check)
+		})
+	}
+	family := strings.ToLower(c.IPFamily)
+
+	protocol := strings.ToLower(c.Protocol)
+	switch protocol {
+	case "", "tcp":
+		protocol = "tcp"
+	case "udp":
+		if len(c.Messages) == 0 {
+			return results.Invalidf("udp checks require messages")
+		} else if c.TLSConfig.Enabled() {
+			return results.Invalidf("tls is not supported over udp")
+		} else if c.Proxy != nil {
+			return results.Invalidf("proxies are not supported over udp")
+		}
+	default:
+		return results.Invalidf("invalid protocol %s, expected tcp or udp", c.Protocol)
+	}
+	timeout, err := c.ResponseTimeout.GetDurationOr(10 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid response timeout %s", c.ResponseTimeout)
+	}
+	payloads := make([][]byte, len(c.Messages))
+	expectations := make([]*responseExpectation, len(c.Messages))
+	for i, message := range c.Messages {
+		if payloads[i], err = tcpPayload(message); err != nil {
+			return results.Invalidf("message %d: %v", i, err)
+		}
+		if message.Expect != nil {
+			if expectations[i], err = newResponseExpectation(message.Expect.Regex, message.Expect.Expr); err != nil {
+				return results.Invalidf("message %d: %v", i, err)
+			}
+		}
+	}
+
 	addr, port, err := extractAddrAndPort(c.Endpoint)
 	if err != nil {
 		return results.ErrorMessage(err)
 	}
+	host := addr
 
-	timeout := time.Millisecond * time.Duration(c.ThresholdMillis)
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(addr, port), timeout)
+	resolve, err := resolveFamily(ctx, c.Resolve, addr, family)
+	if err != nil {
+		return results.Failf("%v", err)
+	}
+	pinned, err := pinnedAddresses(resolve, addr)
+	if err != nil {
+		return results.Invalidf("invalid resolve: %v", err)
+	}
+	if len(pinned) > 1 {
+		return checkEachAddress(c.GetName(), pinned, func(name, ip string) pkg.Results {
+			check := c
+			check.Name = name
+			check.Resolve = pinToAddress(resolve, addr, ip)
+			return t.Check(ctx, check)
+		})
+	} else if len(pinned) == 1 {
+		addr = pinned[0]
+	}
+
+	dialCtx := gocontext.Context(ctx)
+	if c.ThresholdMillis > 0 {
+		var cancel gocontext.CancelFunc
+		dialCtx, cancel = gocontext.WithTimeout(ctx, time.Millisecond*time.Duration(c.ThresholdMillis))
+		defer cancel()
+	}
+	var conn net.Conn
+	if protocol == "udp" {
+		conn, err = (&net.Dialer{}).DialContext(dialCtx, familyNetwork("udp", family), net.JoinHostPort(addr, port))
+	} else {
+		proxy, proxyErr := getProxy(ctx, c.Proxy)
+		if proxyErr != nil {
+			return results.Invalidf("invalid proxy: %v", proxyErr)
+		}
+		conn, err = proxy.DialContext(dialCtx, familyNetwork("tcp", family), net.JoinHostPort(addr, port))
+	}
 	if err != nil {
 		return results.Failf("Connection error: %s", err)
 	}
-	if conn != nil {
-		defer conn.Close()
+	defer conn.Close()
+
+	if c.TLSConfig.Enabled() {
+		tlsConfig, err := c.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("invalid tls config: %v", err)
+		}
+		tlsConfig.MinVersion = tls.VersionTLS12
+		tlsConfig.ServerName = host
+		handshakeTimeout, err := c.TLSConfig.HandshakeTimeout.GetDurationOr(10 * time.Second)
+		if err != nil {
+			return results.Invalidf("invalid handshake timeout %s", c.TLSConfig.HandshakeTimeout)
+		}
+		handshakeCtx, cancel := gocontext.WithTimeout(dialCtx, handshakeTimeout)
+		defer cancel()
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+			return results.Failf("tls handshake with %s failed: %v", c.Endpoint, err)
+		}
+		conn = tlsConn
+	}
+
+	if len(c.Messages) == 0 {
+		return results
+	}
+	data := TCPCheckResult{Messages: []TCPMessageResult{}}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+
+	buf := make([]byte, 64*1024)
+	for i, payload := range payloads {
+		var messageResult TCPMessageResult
+		sent := time.Now()
+		if payload != nil {
+			_ = conn.SetWriteDeadline(sent.Add(timeout))
+			if _, err := conn.Write(payload); err != nil {
+				return results.Failf("failed to send message %d: %v", i, err)
+			}
+		}
+		if expectations[i] != nil {
+			_ = conn.SetReadDeadline(sent.Add(timeout))
+			// a tcp response is matched every time more of it is received, expressions may fail
+			// on a partial response
+			var response []byte
+			var exprErr error
+			for {
+				n, err := conn.Read(buf)
+				if n > 0 {
+					if protocol == "udp" {
+						response = append(response[:0], buf[:n]...)
+					} else {
+						response = append(response, buf[:n]...)
+					}
+					matched, matchErr := expectations[i].match(response, map[string]any{
+						"response": string(response),
+						"hex":      hex.EncodeToString(response),
+					})
+					if matchErr != nil {
+						exprErr = matchErr
+					} else if matched {
+						messageResult.Response = string(response)
+						if !utf8.Valid(response) {
+							messageResult.Hex = hex.EncodeToString(response)
+						}
+						break
+					}
+					if protocol == "udp" {
+						messageResult.Skipped++
+					} else if len(response) > maxTCPResponse {
+						data.Messages = append(data.Messages, messageResult)
+						return results.Failf("response to message %d exceeds %d bytes without matching %s", i, maxTCPResponse, expectations[i])
+					}
+				}
+				if err != nil {
+					data.Messages = append(data.Messages, messageResult)
+					var netErr net.Error
+					switch {
+					case errors.As(err, &netErr) && netErr.Timeout() && exprErr != nil:
+						return results.Failf("no response to message %d matching %s within %s: %v", i, expectations[i], timeout, exprErr)
+					case errors.As(err, &netErr) && netErr.Timeout():
+						return results.Failf("no response to message %d matching %s within %s, received %q", i, expectations[i], timeout, truncateResponse(response))
+					case errors.Is(err, io.EOF):
+						return results.Failf("connection closed before a response to message %d matching %s, received %q", i, expectations[i], truncateResponse(response))
+					}
+					return results.Failf("failed to receive a response to message %d: %v", i, err)
+				}
+			}
+			messageResult.RoundTripMillis = time.Since(sent).Milliseconds()
+		}
+		data.Messages = append(data.Messages, messageResult)
 	}
 	return results
 }
 
+// tcpPayload returns the bytes to send for the message, or nil if it only waits for a response
+func tcpPayload(message v1.TCPMessage) ([]byte, error) {
+	switch {
+	case message.Text != "" && message.Hex != "":
+		return nil, errors.New("only one of text or hex can be sent")
+	case message.Hex != "":
+		payload, err := hex.DecodeString(strings.Join(strings.Fields(message.Hex), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex: %w", err)
+		}
+		return payload, nil
+	case message.Text != "":
+		return []byte(message.Text), nil
+	}
+	if message.Expect == nil {
+		return nil, errors.New("text, hex or expect is required")
+	}
+	return nil, nil
+}
+
+// truncateResponse shortens a response that did not match for the error message
+func truncateResponse(response []byte) string {
+	if len(response) > 256 {
+		return string(response[:256]) + "..."
+	}
+	return string(response)
+}
+
 func extractAddrAndPort(e string) (string, string, error) {
-	s := strings.Split(e, ":")
-	if len(s) != 2 {
+	addr, port, err := net.SplitHostPort(e)
+	if err != nil || port == "" {
 		return "", "", errors.New(formatErrorMsg(e))
 	}
-	return s[0], s[1], nil
+	return addr, port, nil
 }
 
 func formatErrorMsg(f string) string {
-	return fmt.Sprintf("Incorrect endpoint format: %s should be ADDRESS:PORT", f)
+	return fmt.Sprintf("Incorrect endpoint format: %s should be ADDRESS:PORT or [IPv6]:PORT", f)
 }
 
 // Type returns the type
@@ -0,0 +1,151 @@
+package checks
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// newTestLineServer replies to PING with +PONG, and to stats with the stats in separate writes
+func newTestLineServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte("READY\r\n"))
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.TrimSpace(line) {
+					case "PING":
+						_, _ = conn.Write([]byte("+PONG\r\n"))
+					case "stats":
+						_, _ = conn.Write([]byte("STAT uptime 10\r\n"))
+						time.Sleep(10 * time.Millisecond)
+						_, _ = conn.Write([]byte("STAT curr_connections 1\r\nEND\r\n"))
+					case "binary":
+						_, _ = conn.Write([]byte{0xff, 0x00, 0x01})
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestTCPCheckerMessages(t *testing.T) {
+	check := v1.TCPCheck{
+		Description:     v1.Description{Name: "tcp"},
+		Endpoint:        newTestLineServer(t),
+		ResponseTimeout: "1s",
+		Messages: []v1.TCPMessage{
+			{Expect: &v1.TCPExpect{Regex: "^READY"}},
+			{Text: "PING\r\n", Expect: &v1.TCPExpect{Regex: `^\+PONG`}},
+			{Text: "stats\r\n", Expect: &v1.TCPExpect{Expr: `response.endsWith("END\r\n") && response.contains("curr_connections 1")`}},
+			{Hex: "62 69 6e 61 72 79 0a", Expect: &v1.TCPExpect{Expr: `hex == "ff0001"`}},
+		},
+	}
+	results := NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	messages := results[0].Data["messages"].([]any)
+	if len(messages) != 4 || messages[2].(map[string]any)["response"] != "STAT uptime 10\r\nSTAT curr_connections 1\r\nEND\r\n" {
+		t.Errorf("expected the stats split over writes to be one response, got %v", messages)
+	}
+	if messages[3].(map[string]any)["hex"] != "ff0001" {
+		t.Errorf("expected the binary response to be hex encoded, got %v", messages[3])
+	}
+
+	check.ResponseTimeout = "100ms"
+	check.Messages = []v1.TCPMessage{{Text: "PING\r\n", Expect: &v1.TCPExpect{Regex: "^-ERR"}}}
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != `no response to message 0 matching /^-ERR/ within 100ms, received "READY\r\n+PONG\r\n"` {
+		t.Errorf("expected the response to not match, got %s", results[0].Error)
+	}
+
+	check.Messages = []v1.TCPMessage{{Text: "PING", Hex: "0a"}}
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if !results[0].Invalid || results[0].Error != "message 0: only one of text or hex can be sent" {
+		t.Errorf("expected text and hex to be invalid, got %s", results[0].Error)
+	}
+}
+
+func TestTCPCheckerTLS(t *testing.T) {
+	server, ca := newTLSTestServer(t)
+	check := v1.TCPCheck{
+		Description: v1.Description{Name: "tls"},
+		Endpoint:    server.Listener.Addr().String(),
+		TLSConfig:   &v1.SwitchableTLSConfig{TLSConfig: v1.TLSConfig{CA: ca}},
+		Messages: []v1.TCPMessage{
+			{Text: "GET / HTTP/1.0\r\n\r\n", Expect: &v1.TCPExpect{Regex: `^HTTP/1\.[01] 200`}},
+		},
+	}
+	results := NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+
+	check.TLSConfig = &v1.SwitchableTLSConfig{Enable: true}
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if results[0].Pass || !strings.Contains(results[0].Error, "tls handshake with") {
+		t.Errorf("expected the untrusted certificate to fail, got %s", results[0].Error)
+	}
+}
+
+func TestTCPCheckerUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		request := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			// a datagram that does not match is skipped
+			_, _ = conn.WriteTo([]byte("noise"), addr)
+			_, _ = conn.WriteTo(append([]byte("echo "), request[:n]...), addr)
+		}
+	}()
+
+	check := v1.TCPCheck{
+		Description:     v1.Description{Name: "udp"},
+		Endpoint:        conn.LocalAddr().String(),
+		Protocol:        "udp",
+		ResponseTimeout: "1s",
+		Messages:        []v1.TCPMessage{{Text: "hello", Expect: &v1.TCPExpect{Regex: "^echo hello$"}}},
+	}
+	results := NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	if message := results[0].Data["messages"].([]any)[0].(map[string]any); message["skipped"] != float64(1) {
+		t.Errorf("expected the noise to be skipped, got %v", message)
+	}
+
+	check.Messages = nil
+	results = NewTCPChecker().Check(newRetryTestContext(nil), check)
+	if !results[0].Invalid || results[0].Error != "udp checks require messages" {
+		t.Errorf("expected udp without messages to be invalid, got %s", results[0].Error)
+	}
+}
@@ -0,0 +1,428 @@
+package checks
+
+import (
+	"bufio"
+	gocontext "context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+var tlsCertificateExpiry = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "canary_check_tls_certificate_expiry",
+		Help: "The number of days until the first certificate in the chain expires",
+	},
+	[]string{"endpoint", "canary_name", "canary_namespace", "name"},
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func init() {
+	prometheus.MustRegister(tlsCertificateExpiry)
+}
+
+type TLSChecker struct{}
+
+// Type: returns checker type
+func (c *TLSChecker) Type() string {
+	return "tls"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *TLSChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.TLS {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+type TLSCertificate struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SANs               []string  `json:"sans,omitempty"`
+	SerialNumber       string    `json:"serialNumber"`
+	KeyType            string    `json:"keyType"`
+	KeySize            int       `json:"keySize"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	IsCA               bool      `json:"isCA"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	ExpiresInDays      int       `json:"expiresInDays"`
+}
+
+type TLSOCSPStatus struct {
+	Stapled    bool       `json:"stapled"`
+	Status     string     `json:"status,omitempty"`
+	ProducedAt *time.Time `json:"producedAt,omitempty"`
+	NextUpdate *time.Time `json:"nextUpdate,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type TLSCheckResult struct {
+	Protocol      string           `json:"protocol"`
+	CipherSuite   string           `json:"cipherSuite"`
+	ServerName    string           `json:"serverName"`
+	Chain         []TLSCertificate `json:"chain"`
+	OCSP          TLSOCSPStatus    `json:"ocsp"`
+	ExpiresInDays int              `json:"expiresInDays"`
+	Verified      bool             `json:"verified"`
+}
+
+func (c *TLSChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.TLSCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	host, _, err := net.SplitHostPort(check.Endpoint)
+	if err != nil {
+		return results.Invalidf("invalid endpoint %s, expected host:port: %v", check.Endpoint, err)
+	}
+	pinned, err := pinnedAddresses(check.Resolve, host)
+	if err != nil {
+		return results.Invalidf("invalid resolve: %v", err)
+	}
+	if len(pinned) > 1 {
+		return checkEachAddress(check.GetName(), pinned, func(name, addr string) pkg.Results {
+			pinnedCheck := check
+			pinnedCheck.Name = name
+			pinnedCheck.Resolve = pinToAddress(check.Resolve, host, addr)
+			return c.Check(ctx, pinnedCheck)
+		})
+	}
+	serverName := check.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	var minVersion uint16
+	if check.MinVersion != "" {
+		var ok bool
+		if minVersion, ok = tlsVersions[check.MinVersion]; !ok {
+			return results.Invalidf("invalid minVersion %s, expected one of 1.0, 1.1, 1.2 or 1.3", check.MinVersion)
+		}
+	}
+
+	tlsConfig, err := check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace())
+	if err != nil {
+		return results.Invalidf("invalid tls config: %v", err)
+	}
+	// the chain is verified after the handshake, so that its details are reported even when it is not trusted
+	verify := !tlsConfig.InsecureSkipVerify
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.ServerName = serverName
+	tlsConfig.MinVersion = tls.VersionTLS10
+
+	handshakeTimeout, err := check.TLSConfig.HandshakeTimeout.GetDurationOr(10 * time.Second)
+	if err != nil {
+		return results.Invalidf("invalid handshakeTimeout: %v", err)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	dialCtx, cancel := gocontext.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := proxy.DialContext(dialCtx, "tcp", pinHostPort(check.Resolve, check.Endpoint))
+	if err != nil {
+		return results.Failf("connection error: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := dialCtx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if check.StartTLS != "" {
+		if err := startTLS(conn, check.StartTLS); err != nil {
+			return results.Failf("%s starttls failed: %v", check.StartTLS, err)
+		}
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+		return results.Failf("tls handshake failed: %v", err)
+	}
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return results.Failf("no certificates presented by %s", check.Endpoint)
+	}
+	leaf := state.PeerCertificates[0]
+
+	data := TLSCheckResult{
+		Protocol:    tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  serverName,
+		OCSP:        getOCSPStatus(state),
+	}
+
+	firstExpiry := leaf.NotAfter
+	for _, cert := range state.PeerCertificates {
+		data.Chain = append(data.Chain, toTLSCertificate(cert))
+		if cert.NotAfter.Before(firstExpiry) {
+			firstExpiry = cert.NotAfter
+		}
+	}
+	data.ExpiresInDays = daysUntil(firstExpiry)
+	tlsCertificateExpiry.WithLabelValues(check.Endpoint, ctx.Canary.Name, ctx.Canary.Namespace, check.GetName()).Set(time.Until(firstExpiry).Hours() / 24)
+
+	var verifyErr error
+	if verify {
+		verifyErr = verifyChain(state.PeerCertificates, tlsConfig.RootCAs)
+		data.Verified = verifyErr == nil
+	}
+	result.AddDataStruct(data)
+
+	// expiry is checked first, as an expired certificate would otherwise only be reported as an untrusted chain
+	for _, cert := range state.PeerCertificates {
+		if time.Now().After(cert.NotAfter) {
+			return results.Failf("certificate %s expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+	if check.ExpiryDays > 0 && data.ExpiresInDays < check.ExpiryDays {
+		return results.Failf("certificate expires in %d days, less than %d days", data.ExpiresInDays, check.ExpiryDays)
+	}
+	if verifyErr != nil {
+		return results.Failf("untrusted certificate chain: %v", verifyErr)
+	}
+	if verify {
+		if err := leaf.VerifyHostname(serverName); err != nil {
+			return results.Failf("hostname mismatch: %v", err)
+		}
+	}
+	if minVersion > 0 && state.Version < minVersion {
+		return results.Failf("negotiated %s, expected at least TLS %s", data.Protocol, check.MinVersion)
+	}
+	if data.OCSP.Status == "revoked" {
+		return results.Failf("certificate %s has been revoked", leaf.Subject)
+	}
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+
+	return results
+}
+
+func verifyChain(certs []*x509.Certificate, roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func getOCSPStatus(state tls.ConnectionState) TLSOCSPStatus {
+	if len(state.OCSPResponse) == 0 {
+		return TLSOCSPStatus{}
+	}
+
+	status := TLSOCSPStatus{Stapled: true}
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	response, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	switch response.Status {
+	case ocsp.Good:
+		status.Status = "good"
+	case ocsp.Revoked:
+		status.Status = "revoked"
+	default:
+		status.Status = "unknown"
+	}
+	status.ProducedAt = &response.ProducedAt
+	if !response.NextUpdate.IsZero() {
+		status.NextUpdate = &response.NextUpdate
+	}
+	return status
+}
+
+func toTLSCertificate(cert *x509.Certificate) TLSCertificate {
+	out := TLSCertificate{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		ExpiresInDays:      daysUntil(cert.NotAfter),
+		KeyType:            cert.PublicKeyAlgorithm.String(),
+	}
+
+	out.SANs = append(out.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		out.SANs = append(out.SANs, ip.String())
+	}
+	out.SANs = append(out.SANs, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		out.SANs = append(out.SANs, uri.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		out.KeySize = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		out.KeySize = key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		out.KeySize = len(key) * 8
+	}
+	return out
+}
+
+func daysUntil(t time.Time) int {
+	return int(time.Until(t).Hours() / 24)
+}
+
+// startTLS negotiates the upgrade of a plaintext connection to TLS for the given protocol
+func startTLS(conn net.Conn, protocol string) error {
+	switch strings.ToLower(protocol) {
+	case "smtp":
+		return startTLSSMTP(conn)
+	case "imap":
+		return startTLSIMAP(conn)
+	case "postgres":
+		return startTLSPostgres(conn)
+	case "ldap":
+		return startTLSLDAP(conn)
+	default:
+		return fmt.Errorf("unsupported protocol %s, expected one of smtp, imap, postgres or ldap", protocol)
+	}
+}
+
+func startTLSSMTP(conn net.Conn) error {
+	text := textproto.NewConn(conn)
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return err
+	}
+	if _, err := text.Cmd("EHLO canary-checker"); err != nil {
+		return err
+	}
+	if _, _, err := text.ReadResponse(250); err != nil {
+		return err
+	}
+	if _, err := text.Cmd("STARTTLS"); err != nil {
+		return err
+	}
+	_, _, err := text.ReadResponse(220)
+	return err
+}
+
+func startTLSIMAP(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	greeting, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+	}
+	if _, err := io.WriteString(conn, "a1 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if !strings.HasPrefix(line, "a1 OK") {
+				return fmt.Errorf("unexpected response: %s", strings.TrimSpace(line))
+			}
+			return nil
+		}
+	}
+}
+
+// postgresSSLRequestCode is the protocol version sent in an SSLRequest message
+const postgresSSLRequestCode = 80877103
+
+func startTLSPostgres(conn net.Conn) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return err
+	}
+	if response[0] != 'S' {
+		return fmt.Errorf("server does not support ssl")
+	}
+	return nil
+}
+
+// ldapStartTLSOID is the name of the StartTLS extended operation (RFC 4511)
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+func startTLSLDAP(conn net.Conn) error {
+	request := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Request")
+	request.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(1), "MessageID"))
+	extendedRequest := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 23, nil, "Extended Request")
+	extendedRequest.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, ldapStartTLSOID, "Request Name"))
+	request.AppendChild(extendedRequest)
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return err
+	}
+
+	response, err := ber.ReadPacket(conn)
+	if err != nil {
+		return err
+	}
+	// LDAPMessage ::= SEQUENCE { messageID, ExtendedResponse ::= [APPLICATION 24] SEQUENCE { resultCode, ... } }
+	if len(response.Children) < 2 || len(response.Children[1].Children) < 3 {
+		return fmt.Errorf("invalid extended response")
+	}
+	extendedResponse := response.Children[1]
+	resultCode, ok := extendedResponse.Children[0].Value.(int64)
+	if !ok {
+		return fmt.Errorf("invalid result code in extended response")
+	}
+	if resultCode != 0 {
+		return fmt.Errorf("result code %d: %v", resultCode, extendedResponse.Children[2].Value)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package checks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+func newTLSTestServer(t *testing.T) (*httptest.Server, types.EnvVar) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return server, types.EnvVar{ValueStatic: string(ca)}
+}
+
+// newExpiredTLSTestServer serves a self-signed certificate for expired.example.com that expired a day ago
+func newExpiredTLSTestServer(t *testing.T) (string, types.EnvVar) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "expired.example.com"},
+		DNSNames:     []string{"expired.example.com"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return listener.Addr().String(), types.EnvVar{ValueStatic: string(ca)}
+}
+
+func TestTLSCheckerTrustedChain(t *testing.T) {
+	server, ca := newTLSTestServer(t)
+
+	check := v1.TLSCheck{
+		Description: v1.Description{Name: "tls"},
+		Endpoint:    server.Listener.Addr().String(),
+		ServerName:  "example.com",
+		MinVersion:  "1.2",
+		TLSConfig:   v1.TLSConfig{CA: ca},
+	}
+
+	results := (&TLSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected check to pass, got error: %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["verified"] != true {
+		t.Fatalf("expected the chain to be verified, got %v", data["verified"])
+	}
+	if !strings.HasPrefix(data["protocol"].(string), "TLS 1.") {
+		t.Fatalf("unexpected protocol %v", data["protocol"])
+	}
+	if chain, ok := data["chain"].([]any); !ok || len(chain) != 1 {
+		t.Fatalf("expected a chain of one certificate, got %v", data["chain"])
+	}
+}
+
+func TestTLSCheckerFailures(t *testing.T) {
+	server, ca := newTLSTestServer(t)
+	endpoint := server.Listener.Addr().String()
+	expiredEndpoint, expiredCA := newExpiredTLSTestServer(t)
+
+	tests := []struct {
+		name  string
+		check v1.TLSCheck
+		error string
+	}{
+		{
+			name:  "untrusted chain",
+			check: v1.TLSCheck{Endpoint: endpoint},
+			error: "untrusted certificate chain",
+		},
+		{
+			name:  "hostname mismatch",
+			check: v1.TLSCheck{Endpoint: endpoint, ServerName: "canary.local", TLSConfig: v1.TLSConfig{CA: ca}},
+			error: "hostname mismatch",
+		},
+		{
+			name:  "expired certificate",
+			check: v1.TLSCheck{Endpoint: expiredEndpoint, ServerName: "expired.example.com", TLSConfig: v1.TLSConfig{CA: expiredCA}},
+			error: "certificate CN=expired.example.com expired on",
+		},
+		{
+			name:  "expiry threshold",
+			check: v1.TLSCheck{Endpoint: endpoint, ExpiryDays: 365 * 1000, TLSConfig: v1.TLSConfig{InsecureSkipVerify: true}},
+			error: "certificate expires in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.Name = tt.name
+			results := (&TLSChecker{}).Check(newRetryTestContext(nil), tt.check)
+			if results[0].Pass {
+				t.Fatalf("expected check to fail")
+			}
+			if !strings.Contains(results[0].Error, tt.error) {
+				t.Fatalf("expected error to contain %q, got %q", tt.error, results[0].Error)
+			}
+		})
+	}
+}
+
+func TestTLSCheckerPostgresStartTLS(t *testing.T) {
+	server, _ := newTLSTestServer(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(conn, request); err != nil || binary.BigEndian.Uint32(request[4:]) != postgresSSLRequestCode {
+			return
+		}
+		_, _ = conn.Write([]byte("S"))
+		_ = tls.Server(conn, server.TLS).Handshake()
+	}()
+
+	check := v1.TLSCheck{
+		Description: v1.Description{Name: "postgres"},
+		Endpoint:    listener.Addr().String(),
+		StartTLS:    "postgres",
+		TLSConfig:   v1.TLSConfig{InsecureSkipVerify: true},
+	}
+
+	results := (&TLSChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected check to pass, got error: %s", results[0].Error)
+	}
+}
@@ -3,6 +3,7 @@ package checks
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/flanksource/canary-checker/api/external"
 	"github.com/flanksource/canary-checker/pkg"
@@ -60,3 +61,8 @@ func mb(bytes int64) string {
 	}
 	return fmt.Sprintf("%dB", bytes)
 }
+
+// millis returns the duration in milliseconds with a microsecond precision
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,233 @@
+package checks
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/flanksource/canary-checker/api/context"
+	"github.com/flanksource/canary-checker/api/external"
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"github.com/flanksource/canary-checker/pkg"
+	"github.com/flanksource/canary-checker/pkg/utils"
+)
+
+var (
+	websocketHandshakeSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_websocket_handshake_seconds",
+			Help: "The number of seconds to connect and complete the upgrade handshake",
+		},
+		[]string{"url"},
+	)
+	websocketRoundTripSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_check_websocket_round_trip_seconds",
+			Help: "The number of seconds between sending a message and receiving the expected response",
+		},
+		[]string{"url", "message"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(websocketHandshakeSeconds, websocketRoundTripSeconds)
+}
+
+type WebsocketChecker struct{}
+
+// Type: returns checker type
+func (c *WebsocketChecker) Type() string {
+	return "websocket"
+}
+
+// Run: Check every entry from config according to Checker interface
+// Returns check result and metrics
+func (c *WebsocketChecker) Run(ctx *context.Context) pkg.Results {
+	var results pkg.Results
+	for _, conf := range ctx.Canary.Spec.Websocket {
+		results = append(results, c.Check(ctx, conf)...)
+	}
+	return results
+}
+
+// WebsocketCheckResult is the data of a websocket check
+type WebsocketCheckResult struct {
+	// StatusCode of the upgrade response, 101 when the upgrade succeeds
+	StatusCode      int                      `json:"statusCode,omitempty"`
+	Subprotocol     string                   `json:"subprotocol,omitempty"`
+	HandshakeMillis int64                    `json:"handshakeMillis"`
+	Messages        []WebsocketMessageResult `json:"messages"`
+}
+
+type WebsocketMessageResult struct {
+	// Response is the response that matched the expectation
+	Response        string `json:"response,omitempty"`
+	RoundTripMillis int64  `json:"roundTripMillis,omitempty"`
+	// Skipped is the number of responses that did not match before the expected one
+	Skipped int `json:"skipped,omitempty"`
+}
+
+func (c *WebsocketChecker) Check(ctx *context.Context, extConfig external.Check) pkg.Results {
+	check := extConfig.(v1.WebsocketCheck)
+	result := pkg.Success(check, ctx.Canary)
+	var results pkg.Results
+	results = append(results, result)
+
+	connection, err := ctx.GetConnection(check.Connection)
+	if err != nil {
+		return results.Failf("error getting connection: %v", err)
+	}
+	if u, err := url.Parse(connection.URL); err != nil || u.Host == "" || (u.Scheme != "ws" && u.Scheme != "wss") {
+		return results.Invalidf("invalid url %s, expected ws://host[:port]/path or wss://host[:port]/path", v1.SanitizeEndpoints(connection.URL))
+	}
+	timeout, err := check.ResponseTimeout.GetDurationOr(30 * time.Second)
+	if err != nil || timeout <= 0 {
+		return results.Invalidf("invalid response timeout %s", check.ResponseTimeout)
+	}
+	proxy, err := getProxy(ctx, check.Proxy)
+	if err != nil {
+		return results.Invalidf("invalid proxy: %v", err)
+	}
+	dialer := &websocket.Dialer{
+		Proxy:            proxy.ProxyFunc,
+		HandshakeTimeout: timeout,
+		Subprotocols:     check.Subprotocols,
+	}
+	if check.TLSConfig != nil {
+		if dialer.TLSClientConfig, err = check.TLSConfig.ToTLSConfig(ctx, ctx.GetNamespace()); err != nil {
+			return results.Invalidf("invalid tls config: %v", err)
+		}
+		dialer.TLSClientConfig.MinVersion = tls.VersionTLS12
+	}
+
+	header := http.Header{}
+	if connection.Username != "" || connection.Password != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(connection.Username+":"+connection.Password)))
+	}
+	for _, h := range check.Headers {
+		value, err := ctx.GetEnvValueFromCache(h, ctx.GetNamespace())
+		if err != nil {
+			return results.Invalidf("failed to get header %s: %v", h.Name, err)
+		}
+		header.Set(h.Name, value)
+	}
+
+	payloads := make([][]byte, len(check.Messages))
+	expectations := make([]*responseExpectation, len(check.Messages))
+	for i, message := range check.Messages {
+		if payloads[i], err = websocketPayload(message); err != nil {
+			return results.Invalidf("message %d: %v", i, err)
+		}
+		if expectations[i], err = newWebsocketExpectation(message.Expect); err != nil {
+			return results.Invalidf("message %d: %v", i, err)
+		}
+	}
+
+	data := WebsocketCheckResult{Messages: []WebsocketMessageResult{}}
+	defer func() {
+		result.AddDataStruct(data)
+	}()
+	endpoint := v1.SanitizeEndpoints(connection.URL)
+
+	start := time.Now()
+	conn, response, err := dialer.DialContext(ctx, connection.URL, header)
+	if response != nil {
+		data.StatusCode = response.StatusCode
+	}
+	if errors.Is(err, websocket.ErrBadHandshake) && response != nil {
+		return results.Failf("upgrade of %s failed with %s", endpoint, response.Status)
+	} else if err != nil {
+		return results.Failf("failed to connect to %s: %v", endpoint, err)
+	}
+	defer conn.Close()
+	handshake := time.Since(start)
+	data.HandshakeMillis = handshake.Milliseconds()
+	data.Subprotocol = conn.Subprotocol()
+	websocketHandshakeSeconds.WithLabelValues(endpoint).Set(handshake.Seconds())
+
+	for i, payload := range payloads {
+		var messageResult WebsocketMessageResult
+		sent := time.Now()
+		if payload != nil {
+			_ = conn.SetWriteDeadline(sent.Add(timeout))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return results.Failf("failed to send message %d: %v", i, err)
+			}
+		}
+		if expectations[i] != nil {
+			_ = conn.SetReadDeadline(sent.Add(timeout))
+			// responses the expression fails on, e.g. heartbeats that are not JSON, are skipped
+			var exprErr error
+			for {
+				_, received, err := conn.ReadMessage()
+				if err != nil {
+					data.Messages = append(data.Messages, messageResult)
+					var netErr interface{ Timeout() bool }
+					if !errors.As(err, &netErr) || !netErr.Timeout() {
+						return results.Failf("failed to receive a response to message %d: %v", i, err)
+					} else if exprErr != nil {
+						return results.Failf("no response to message %d matching %s within %s: %v", i, expectations[i], timeout, exprErr)
+					}
+					return results.Failf("no response to message %d matching %s within %s", i, expectations[i], timeout)
+				}
+				matched, err := expectations[i].match(received, map[string]any{"message": string(received)})
+				if err != nil {
+					exprErr = err
+				} else if matched {
+					messageResult.Response = string(received)
+					break
+				}
+				messageResult.Skipped++
+			}
+			roundTrip := time.Since(sent)
+			messageResult.RoundTripMillis = roundTrip.Milliseconds()
+			websocketRoundTripSeconds.WithLabelValues(endpoint, strconv.Itoa(i)).Set(roundTrip.Seconds())
+		}
+		data.Messages = append(data.Messages, messageResult)
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	elapsed := time.Since(start)
+	result.Duration = elapsed.Milliseconds()
+	if check.ThresholdMillis > 0 && check.ThresholdMillis < int(elapsed.Milliseconds()) {
+		return results.Failf("threshold exceeded %s > %d", utils.Age(elapsed), check.ThresholdMillis)
+	}
+	return results
+}
+
+// websocketPayload returns the frame to send for the message, or nil if it only waits for a response
+func websocketPayload(message v1.WebsocketMessage) ([]byte, error) {
+	switch {
+	case message.Text != "" && message.JSON != "":
+		return nil, errors.New("only one of text or json can be sent")
+	case message.JSON != "":
+		var payload bytes.Buffer
+		if err := json.Compact(&payload, []byte(message.JSON)); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return payload.Bytes(), nil
+	case message.Text != "":
+		return []byte(message.Text), nil
+	}
+	if message.Expect == nil {
+		return nil, errors.New("text, json or expect is required")
+	}
+	return nil, nil
+}
+
+func newWebsocketExpectation(expect *v1.WebsocketExpect) (*responseExpectation, error) {
+	if expect == nil {
+		return nil, nil
+	}
+	return newResponseExpectation(expect.Regex, expect.Expr)
+}
@@ -0,0 +1,112 @@
+package checks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/duty/types"
+	"github.com/gorilla/websocket"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+)
+
+// newTestWebsocketServer requires user:pass, sends a ping heartbeat before acknowledging a subscription and
+// echoes any other message
+func newTestWebsocketServer(t *testing.T) string {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"v1"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" || r.Header.Get("X-Client") != "canary" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var reply []string
+			if string(message) == `{"type":"subscribe","channel":"prices"}` {
+				reply = []string{"ping", `{"type":"subscribed","channel":"prices"}`}
+			} else {
+				reply = []string{"echo: " + string(message)}
+			}
+			for _, r := range reply {
+				if conn.WriteMessage(websocket.TextMessage, []byte(r)) != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return strings.Replace(server.URL, "http://", "ws://", 1)
+}
+
+func newTestWebsocketCheck(url string) v1.WebsocketCheck {
+	return v1.WebsocketCheck{
+		Description: v1.Description{Name: "websocket"},
+		Connection: v1.Connection{URL: url, Authentication: types.Authentication{
+			Username: types.EnvVar{ValueStatic: "user"},
+			Password: types.EnvVar{ValueStatic: "pass"},
+		}},
+		Headers:         []types.EnvVar{{Name: "X-Client", ValueStatic: "canary"}},
+		Subprotocols:    []string{"v2", "v1"},
+		ResponseTimeout: "1s",
+	}
+}
+
+func TestWebsocketChecker(t *testing.T) {
+	check := newTestWebsocketCheck(newTestWebsocketServer(t))
+	check.Messages = []v1.WebsocketMessage{
+		{
+			JSON:   `{ "type": "subscribe", "channel": "prices" }`,
+			Expect: &v1.WebsocketExpect{Expr: `json.type == "subscribed" && json.channel == "prices"`},
+		},
+		{Text: "hello", Expect: &v1.WebsocketExpect{Regex: "^echo: hello$"}},
+	}
+	results := (&WebsocketChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Pass {
+		t.Fatalf("expected the check to pass, got %s", results[0].Error)
+	}
+	data := results[0].Data
+	if data["statusCode"] != float64(101) || data["subprotocol"] != "v1" {
+		t.Errorf("expected an upgrade to v1, got %v", data)
+	}
+	messages := data["messages"].([]any)
+	if subscribed := messages[0].(map[string]any); subscribed["skipped"] != float64(1) || subscribed["response"] != `{"type":"subscribed","channel":"prices"}` {
+		t.Errorf("expected the heartbeat to be skipped before the subscription, got %v", subscribed)
+	}
+	if echo := messages[1].(map[string]any); echo["response"] != "echo: hello" {
+		t.Errorf("expected the echo, got %v", echo)
+	}
+}
+
+func TestWebsocketCheckerFailures(t *testing.T) {
+	url := newTestWebsocketServer(t)
+	check := newTestWebsocketCheck(url)
+	check.Headers = nil
+	results := (&WebsocketChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "upgrade of "+url+" failed with 401 Unauthorized" {
+		t.Errorf("expected the upgrade to be unauthorized, got %s", results[0].Error)
+	}
+
+	check = newTestWebsocketCheck(url)
+	check.ResponseTimeout = "100ms"
+	check.Messages = []v1.WebsocketMessage{{Text: "hello", Expect: &v1.WebsocketExpect{Regex: "^pong$"}}}
+	results = (&WebsocketChecker{}).Check(newRetryTestContext(nil), check)
+	if results[0].Pass || results[0].Error != "no response to message 0 matching /^pong$/ within 100ms" {
+		t.Errorf("expected no matching response, got %s", results[0].Error)
+	}
+
+	check.Messages = []v1.WebsocketMessage{{Text: "hello", JSON: `{}`}}
+	results = (&WebsocketChecker{}).Check(newRetryTestContext(nil), check)
+	if !results[0].Invalid {
+		t.Errorf("expected both text and json to be invalid, got %s", results[0].Error)
+	}
+}
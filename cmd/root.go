@@ -77,6 +77,7 @@ var Root = &cobra.Command{
 			runner.Prometheus, _ = prometheus.NewPrometheusAPI(
 				context.New(),
 				connection.PrometheusConnection{HTTPConnection: connection.HTTPConnection{URL: prometheus.PrometheusURL}},
+				nil,
 			)
 		}
 
@@ -372,6 +372,8 @@ type CheckResult struct {
 	ErrorObject error          `json:"-"`
 
 	InternalError bool `json:"-"`
+	// TimedOut is set when the check was cancelled for exceeding its timeout
+	TimedOut bool `json:"-"`
 
 	CanaryResult []TransformedCanaryResult
 }
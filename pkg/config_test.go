@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "github.com/flanksource/canary-checker/api/v1"
+	"gopkg.in/flanksource/yaml.v3"
+)
+
+func TestUnmarshalAllChecks(t *testing.T) {
+	for _, check := range v1.AllChecks {
+		t.Run(check.GetType(), func(t *testing.T) {
+			value := reflect.New(reflect.TypeOf(check))
+			if err := unmarshalCheck([]byte("name: test\ncheckTimeout: 10s\n"), value.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			timeout, err := value.Elem().Interface().(interface {
+				GetCheckTimeout() (time.Duration, error)
+			}).GetCheckTimeout()
+			if err != nil || timeout != 10*time.Second {
+				t.Errorf("expected a check timeout of 10s, got %s, %v", timeout, err)
+			}
+		})
+	}
+}
+
+// unmarshalCheck recovers the panic yaml raises for fields sharing a key with the description
+func unmarshalCheck(data []byte, out any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return yaml.Unmarshal(data, out)
+}
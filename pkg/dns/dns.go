@@ -64,12 +64,13 @@ func Lookup(recordType, hostname string) ([]net.IP, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "lookup of %s failed", host)
 	}
-	var ipv4 []net.IP
+	// AAAA returns the IPv6 addresses, any other record type the IPv4 addresses
+	var matching []net.IP
 	for _, ip := range ips {
-		if ip.To4() != nil {
-			ipv4 = append(ipv4, ip)
+		if (ip.To4() == nil) == (recordType == "AAAA") {
+			matching = append(matching, ip)
 		}
 	}
-	logger.Debugf("%s => %v", host, ipv4)
-	return ipv4, nil
+	logger.Debugf("%s %s => %v", host, recordType, matching)
+	return matching, nil
 }
@@ -97,7 +97,15 @@ func SetupMetrics() {
 		checkLabels,
 	)
 
-	prometheus.MustRegister(Gauge, CanaryCheckInfo, OpsCount, OpsSuccessCount, OpsInvalidCount, OpsErrorCount, OpsFailedCount, RequestLatency)
+	OpsTimeoutCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "canary_check_timeout_count",
+			Help: "The total number of checks that were cancelled for exceeding their timeout",
+		},
+		checkLabels,
+	)
+
+	prometheus.MustRegister(Gauge, CanaryCheckInfo, OpsCount, OpsSuccessCount, OpsInvalidCount, OpsErrorCount, OpsFailedCount, OpsTimeoutCount, RequestLatency)
 }
 
 var (
@@ -119,6 +127,7 @@ var (
 	OpsFailedCount  *prometheus.CounterVec
 	OpsSuccessCount *prometheus.CounterVec
 	OpsErrorCount   *prometheus.CounterVec
+	OpsTimeoutCount *prometheus.CounterVec
 	RequestLatency  *prometheus.HistogramVec
 )
 
@@ -292,6 +301,10 @@ func Record(
 		default:
 			fail.Append(1)
 			OpsFailedCount.WithLabelValues(checkMetricLabels...).Inc()
+			// timeouts are failures of the monitored target, additionally counted on their own
+			if result.TimedOut {
+				OpsTimeoutCount.WithLabelValues(checkMetricLabels...).Inc()
+			}
 		}
 	}
 
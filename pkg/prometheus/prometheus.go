@@ -3,6 +3,8 @@ package prometheus
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/flanksource/duty/connection"
@@ -51,14 +53,26 @@ func (p PrometheusClient) GetUptime(checkKey, duration string) (float64, error)
 	return val, nil
 }
 
-func NewPrometheusAPI(ctx dutyContext.Context, conn connection.PrometheusConnection) (*PrometheusClient, error) {
+// NewPrometheusAPI returns a client of the prometheus at the url of the connection, connecting
+// through proxyURL when it is not nil
+func NewPrometheusAPI(ctx dutyContext.Context, conn connection.PrometheusConnection, proxyURL *url.URL) (*PrometheusClient, error) {
 	if conn.URL == "" {
 		return nil, nil
 	}
 
-	roundTripper, err := conn.HTTPConnection.TransportWithContext(ctx)
-	if err != nil {
-		return nil, err
+	var roundTripper http.RoundTripper
+	if proxyURL != nil {
+		// the proxy is set on the client the transport is created from
+		client, err := connection.CreateHTTPClient(ctx, conn.HTTPConnection)
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = client.Proxy(proxyURL.String())
+	} else {
+		var err error
+		if roundTripper, err = conn.HTTPConnection.TransportWithContext(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	if !conn.Username.IsEmpty() {